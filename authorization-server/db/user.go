@@ -12,8 +12,9 @@ import (
 )
 
 var (
-	insertUser      = `INSERT INTO "user" (id, email, password_hash) VALUES ($1, $2, $3)`
-	findUserByEmail = `SELECT id, email, password_hash FROM "user" WHERE email = $1`
+	insertUser = `INSERT INTO "user" (id, email, password_hash) VALUES ($1, $2, $3)`
+	// emails are unique regardless of case, so registration rejects case variants of existing email
+	findUserByEmail = `SELECT id, email, password_hash FROM "user" WHERE lower(email) = lower($1)`
 )
 
 var ErrUserNotFound = fmt.Errorf("user not found")
@@ -79,3 +79,22 @@ func (s *UserDbSuite) TestFindUserNotFound() {
 	s.Require().Equal(ErrUserNotFound, err)
 	s.Require().Empty(user)
 }
+
+func (s *UserDbSuite) TestFindUserIgnoresEmailCase() {
+	//given
+	savedUser, err := s.userDb.Save(model.User{Username: "Case@example.com", PasswordHash: "hash"})
+	s.Require().NoError(err)
+
+	//when
+	foundUser, err := s.userDb.Find("case@EXAMPLE.com")
+
+	//then
+	s.Require().NoError(err)
+	s.Require().Equal(savedUser.ID, foundUser.ID)
+
+	//and when saving case variant of the email
+	_, err = s.userDb.Save(model.User{Username: "CASE@example.com", PasswordHash: "hash"})
+
+	//then
+	s.Require().Error(err)
+}
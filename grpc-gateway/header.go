@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc/metadata"
+	"net/http"
+)
+
+// conditionalRequestMetadata forwards If-Match header of conditional updates to the server.
+func conditionalRequestMetadata(_ context.Context, r *http.Request) metadata.MD {
+	if ifMatch := r.Header.Get("If-Match"); ifMatch != "" {
+		return metadata.Pairs("if-match", ifMatch)
+	}
+	return nil
+}
+
+// idempotencyKeyMetadata forwards Idempotency-Key header of create requests to the server.
+func idempotencyKeyMetadata(_ context.Context, r *http.Request) metadata.MD {
+	if key := r.Header.Get("Idempotency-Key"); key != "" {
+		return metadata.Pairs("idempotency-key", key)
+	}
+	return nil
+}
+
+// outgoingHeaderMatcher returns etag of the server as ETag header, other headers are prefixed as by default.
+func outgoingHeaderMatcher(key string) (string, bool) {
+	if key == "etag" {
+		return "ETag", true
+	}
+	return fmt.Sprintf("%s%s", runtime.MetadataHeaderPrefix, key), true
+}
@@ -30,8 +30,16 @@ func main() {
 
 	mux := runtime.NewServeMux(
 		runtime.WithErrorHandler(UnaryErrorHandler),
-		runtime.WithMarshalerOption(runtime.MIMEWildcard, &runtime.JSONPb{
-			MarshalOptions: protojson.MarshalOptions{EmitUnpopulated: false},
+		//etag of workout is exchanged in ETag and If-Match headers
+		runtime.WithMetadata(conditionalRequestMetadata),
+		runtime.WithOutgoingHeaderMatcher(outgoingHeaderMatcher),
+		//retried create requests are deduplicated by Idempotency-Key header
+		runtime.WithMetadata(idempotencyKeyMetadata),
+		//HTTPBodyMarshaler passes google.api.HttpBody responses (e.g. CSV exports) as raw body
+		runtime.WithMarshalerOption(runtime.MIMEWildcard, &runtime.HTTPBodyMarshaler{
+			Marshaler: &runtime.JSONPb{
+				MarshalOptions: protojson.MarshalOptions{EmitUnpopulated: false},
+			},
 		}),
 	)
 	opts := []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
@@ -51,6 +59,50 @@ func main() {
 	if err != nil {
 		log.Fatalf("error registering workout schedule service handler: %v", err)
 	}
+	err = workout.RegisterReportServiceHandlerFromEndpoint(context.Background(), mux, workoutSrcAddr, opts)
+	if err != nil {
+		log.Fatalf("error registering report service handler: %v", err)
+	}
+	err = workout.RegisterProfileServiceHandlerFromEndpoint(context.Background(), mux, workoutSrcAddr, opts)
+	if err != nil {
+		log.Fatalf("error registering profile service handler: %v", err)
+	}
+	err = workout.RegisterCalendarServiceHandlerFromEndpoint(context.Background(), mux, workoutSrcAddr, opts)
+	if err != nil {
+		log.Fatalf("error registering calendar service handler: %v", err)
+	}
+	err = workout.RegisterProgramServiceHandlerFromEndpoint(context.Background(), mux, workoutSrcAddr, opts)
+	if err != nil {
+		log.Fatalf("error registering program service handler: %v", err)
+	}
+	err = workout.RegisterWorkoutTemplateServiceHandlerFromEndpoint(context.Background(), mux, workoutSrcAddr, opts)
+	if err != nil {
+		log.Fatalf("error registering workout template service handler: %v", err)
+	}
+	err = workout.RegisterWorkoutShareServiceHandlerFromEndpoint(context.Background(), mux, workoutSrcAddr, opts)
+	if err != nil {
+		log.Fatalf("error registering workout share service handler: %v", err)
+	}
+	err = workout.RegisterCoachingServiceHandlerFromEndpoint(context.Background(), mux, workoutSrcAddr, opts)
+	if err != nil {
+		log.Fatalf("error registering coaching service handler: %v", err)
+	}
+	err = workout.RegisterCommentServiceHandlerFromEndpoint(context.Background(), mux, workoutSrcAddr, opts)
+	if err != nil {
+		log.Fatalf("error registering comment service handler: %v", err)
+	}
+	err = workout.RegisterTagServiceHandlerFromEndpoint(context.Background(), mux, workoutSrcAddr, opts)
+	if err != nil {
+		log.Fatalf("error registering tag service handler: %v", err)
+	}
+	err = workout.RegisterFolderServiceHandlerFromEndpoint(context.Background(), mux, workoutSrcAddr, opts)
+	if err != nil {
+		log.Fatalf("error registering folder service handler: %v", err)
+	}
+	err = workout.RegisterSearchServiceHandlerFromEndpoint(context.Background(), mux, workoutSrcAddr, opts)
+	if err != nil {
+		log.Fatalf("error registering search service handler: %v", err)
+	}
 	// Start HTTP server (and proxy calls to gRPC server endpoint)
 	err = http.ListenAndServe(listenAddr, mux)
 	if err != nil {
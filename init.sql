@@ -1,30 +1,113 @@
+-- trigram similarity makes search tolerant to typos
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
 CREATE TABLE "user"
 (
     id            uuid PRIMARY KEY,
     email         VARCHAR(255) NOT NULL,
     password_hash VARCHAR(255) NOT NULL,
-    created_at TIMESTAMP DEFAULT (now() AT TIME ZONE 'UTC')
+    created_at TIMESTAMPTZ DEFAULT now()
 );
 
+-- emails identify recipients of shares and coaching invites, case variants of registered email are rejected
+CREATE UNIQUE INDEX user_email_index ON "user" (lower(email));
+
 CREATE TABLE exercise
 (
     id           uuid PRIMARY KEY,
     name         VARCHAR(255) NOT NULL,
     description  TEXT         NOT NULL,
     category     VARCHAR(255) NOT NULL,
-    muscle_group VARCHAR(255) NOT NULL
+    muscle_group VARCHAR(255) NOT NULL,
+    -- custom exercises are owned by users, global exercises have no owner
+    "owner"      uuid,
+    search       tsvector GENERATED ALWAYS AS (setweight(to_tsvector('english', name), 'A') ||
+                                               setweight(to_tsvector('english', description), 'B')) STORED,
+    UNIQUE ("owner", name)
+);
+
+CREATE INDEX exercise_search_index ON exercise USING GIN (search);
+CREATE INDEX exercise_name_trgm_index ON exercise USING GIN (name gin_trgm_ops);
+
+-- folders organize user's workouts, workout is in at most one folder
+CREATE TABLE folder
+(
+    id         uuid PRIMARY KEY,
+    "owner"    uuid         NOT NULL,
+    name       VARCHAR(255) NOT NULL,
+    created_at TIMESTAMPTZ  NOT NULL DEFAULT now(),
+    UNIQUE ("owner", name)
 );
 
+-- templates are workouts instantiated into workouts of users, system templates are owned by nil uuid
 CREATE TABLE workout
 (
-    id      uuid PRIMARY KEY,
-    name    VARCHAR(255) NOT NULL,
-    "owner" uuid NOT NULL,
-    comment TEXT
+    id                 uuid PRIMARY KEY,
+    name               VARCHAR(255) NOT NULL,
+    "owner"            uuid         NOT NULL,
+    comment            TEXT,
+    template           BOOLEAN      NOT NULL DEFAULT FALSE,
+    source_template_id uuid REFERENCES workout (id) ON DELETE SET NULL,
+    -- current version, incremented with every update
+    version            INTEGER      NOT NULL DEFAULT 1,
+    -- set when workout is moved to trash, deleted workouts are purged after retention period
+    deleted_at         TIMESTAMPTZ,
+    -- set instead of purge when completed schedules reference the workout, purged workout can't be restored
+    purged_at          TIMESTAMPTZ,
+    folder_id          uuid REFERENCES folder (id) ON DELETE SET NULL,
+    search             tsvector GENERATED ALWAYS AS (setweight(to_tsvector('english', name), 'A') ||
+                                                     setweight(to_tsvector('english', coalesce(comment, '')), 'B')) STORED
 );
 
 CREATE INDEX workout_owner_index ON workout ("owner");
 
+CREATE INDEX workout_search_index ON workout USING GIN (search);
+CREATE INDEX workout_name_trgm_index ON workout USING GIN (name gin_trgm_ops);
+
+CREATE INDEX workout_deleted_index ON workout (deleted_at) WHERE deleted_at IS NOT NULL AND purged_at IS NULL;
+
+-- tags are user-defined labels of workouts, created with the first workout using them
+CREATE TABLE tag
+(
+    id      uuid PRIMARY KEY,
+    "owner" uuid        NOT NULL,
+    name    VARCHAR(64) NOT NULL,
+    UNIQUE ("owner", name)
+);
+
+CREATE TABLE workout_tag
+(
+    workout_id uuid NOT NULL REFERENCES workout (id) ON DELETE CASCADE,
+    tag_id     uuid NOT NULL REFERENCES tag (id) ON DELETE CASCADE,
+    PRIMARY KEY (workout_id, tag_id)
+);
+
+CREATE INDEX workout_tag_tag_index ON workout_tag (tag_id);
+
+-- snapshot of workout with its groups and exercises taken after every change, history is append-only
+CREATE TABLE workout_version
+(
+    workout_id uuid      NOT NULL REFERENCES workout (id) ON DELETE CASCADE,
+    version    INTEGER   NOT NULL,
+    snapshot   JSONB     NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (workout_id, version)
+);
+
+-- label is unique within workout, deferred so labels can be swapped within single update
+CREATE TABLE workout_exercise_group
+(
+    id                          uuid PRIMARY KEY,
+    workout_id                  uuid    NOT NULL REFERENCES workout (id) ON DELETE CASCADE,
+    label                       TEXT    NOT NULL,
+    type                        TEXT    NOT NULL,
+    rounds                      INTEGER NOT NULL DEFAULT 1,
+    rest_between_rounds_seconds INTEGER NOT NULL DEFAULT 0,
+    UNIQUE (workout_id, label) DEFERRABLE INITIALLY DEFERRED
+);
+
+CREATE INDEX workout_exercise_group_workout_id_index ON workout_exercise_group (workout_id);
+
 CREATE TABLE workout_exercise
 (
     workout_exercise_id uuid PRIMARY KEY,
@@ -34,22 +117,244 @@ CREATE TABLE workout_exercise
     repetitions         int  NOT NULL,
     sets                int  NOT NULL,
     weight  DECIMAL(5, 2),
-    comment TEXT
+    comment TEXT,
+    group_id uuid REFERENCES workout_exercise_group (id) ON DELETE SET NULL,
+    rest_seconds     INTEGER,
+    set_rest_seconds INTEGER[],
+    tempo            TEXT,
+    search           tsvector GENERATED ALWAYS AS (to_tsvector('english', coalesce(comment, ''))) STORED
 );
 
 CREATE INDEX workout_exercise_workout_id_index ON workout_exercise (workout_id);
 CREATE INDEX workout_exercise_exercise_id_index ON workout_exercise (exercise_id);
+CREATE INDEX workout_exercise_search_index ON workout_exercise USING GIN (search);
+
+CREATE TABLE program
+(
+    id          uuid PRIMARY KEY,
+    "owner"     uuid      NOT NULL,
+    name        TEXT      NOT NULL,
+    description TEXT,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX program_owner_index ON program ("owner");
+
+-- week numbers start at 1, progression of the week applies to all its days
+CREATE TABLE program_week
+(
+    program_id              uuid             NOT NULL REFERENCES program (id) ON DELETE CASCADE,
+    week                    INTEGER          NOT NULL,
+    weight_increase_percent DOUBLE PRECISION NOT NULL DEFAULT 0,
+    deload                  BOOLEAN          NOT NULL DEFAULT FALSE,
+    PRIMARY KEY (program_id, week)
+);
+
+CREATE TABLE program_day
+(
+    program_id uuid    NOT NULL,
+    week       INTEGER NOT NULL,
+    day        INTEGER NOT NULL,
+    -- workouts used by programs are never purged
+    workout    uuid    NOT NULL REFERENCES workout (id),
+    PRIMARY KEY (program_id, week, day),
+    FOREIGN KEY (program_id, week) REFERENCES program_week (program_id, week) ON DELETE CASCADE
+);
+
+CREATE INDEX program_day_workout_index ON program_day (workout);
+
+CREATE TABLE program_enrollment
+(
+    id         uuid PRIMARY KEY,
+    program_id uuid        NOT NULL REFERENCES program (id) ON DELETE CASCADE,
+    "owner"    uuid        NOT NULL,
+    start_date TIMESTAMPTZ NOT NULL,
+    timezone   TEXT        NOT NULL DEFAULT 'UTC',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE workout_schedule_series
+(
+    id             uuid PRIMARY KEY,
+    "owner"        uuid        NOT NULL,
+    workout        uuid        NOT NULL REFERENCES workout (id) ON DELETE CASCADE,
+    starts_at      TIMESTAMPTZ   NOT NULL,
+    -- IANA timezone, occurrences keep wall-clock time of starts_at in it across DST transitions
+    timezone       TEXT          NOT NULL DEFAULT 'UTC',
+    recurrence     TEXT          NOT NULL,
+    excluded_dates TIMESTAMPTZ[] NOT NULL DEFAULT '{}',
+    created_at     TIMESTAMPTZ   NOT NULL DEFAULT now()
+);
+
+CREATE INDEX workout_schedule_series_owner_index ON workout_schedule_series ("owner");
 
 CREATE TABLE workout_schedule
 (
     id           uuid PRIMARY KEY,
     "owner"      uuid      NOT NULL,
     workout      uuid      NOT NULL REFERENCES workout (id) On DELETE CASCADE,
-    scheduled_at TIMESTAMP NOT NULL,
-    crated_at TIMESTAMP NOT NULL DEFAULT (now() AT TIME ZONE 'UTC'),
-    completed    boolean   NOT NULL DEFAULT FALSE
+    scheduled_at TIMESTAMPTZ NOT NULL,
+    -- IANA timezone the workout was scheduled in
+    timezone     TEXT      NOT NULL DEFAULT 'UTC',
+    crated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    completed    boolean   NOT NULL DEFAULT FALSE,
+    completed_at TIMESTAMPTZ,
+    cancelled_at  TIMESTAMPTZ,
+    cancel_reason TEXT,
+    -- set by background job when schedule is not completed within grace period
+    missed_at     TIMESTAMPTZ,
+    -- set when schedule is a detached (edited or completed) occurrence of recurring series
+    series_id     uuid REFERENCES workout_schedule_series (id) ON DELETE SET NULL,
+    recurrence_id TIMESTAMPTZ,
+    -- set when schedule is a session of training program, kept as history when enrollment is deleted
+    program_enrollment_id uuid REFERENCES program_enrollment (id) ON DELETE SET NULL,
+    program_week          INTEGER,
+    program_day           INTEGER,
+    -- version of the workout the schedule was created from, repinned to version performed once completed
+    workout_version       INTEGER,
+    UNIQUE (series_id, recurrence_id)
 );
 
+CREATE INDEX workout_schedule_program_enrollment_index ON workout_schedule (program_enrollment_id);
+
+CREATE INDEX workout_schedule_overdue_index ON workout_schedule (scheduled_at) WHERE NOT completed AND cancelled_at IS NULL AND missed_at IS NULL;
+
+-- outbox of workout schedule state changes, e.g. transition to missed
+CREATE TABLE workout_schedule_event
+(
+    id          uuid PRIMARY KEY,
+    schedule_id uuid      NOT NULL REFERENCES workout_schedule (id) ON DELETE CASCADE,
+    "owner"     uuid      NOT NULL,
+    type        TEXT      NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- sets performed in completed workout schedules, history of next session plans
+CREATE TABLE exercise_set_result
+(
+    schedule_id uuid          NOT NULL REFERENCES workout_schedule (id) ON DELETE CASCADE,
+    "owner"     uuid          NOT NULL,
+    exercise_id uuid          NOT NULL REFERENCES exercise (id),
+    set_number  INTEGER       NOT NULL,
+    repetitions INTEGER       NOT NULL,
+    weight      DECIMAL(6, 2),
+    rpe         DECIMAL(3, 1),
+    PRIMARY KEY (schedule_id, exercise_id, set_number)
+);
+
+CREATE INDEX exercise_set_result_owner_exercise_index ON exercise_set_result ("owner", exercise_id);
+
+-- link shares have token hash, user shares have recipient email, deleting share revokes it
+CREATE TABLE workout_share
+(
+    id              uuid PRIMARY KEY,
+    workout_id      uuid      NOT NULL REFERENCES workout (id) ON DELETE CASCADE,
+    "owner"         uuid      NOT NULL,
+    token_hash      TEXT UNIQUE,
+    recipient_email TEXT,
+    -- recipient is resolved from email when sharing, so accounts registered later don't receive the share
+    recipient_id    uuid REFERENCES "user" (id) ON DELETE CASCADE,
+    permission      TEXT      NOT NULL,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CHECK ((token_hash IS NULL) <> (recipient_email IS NULL)),
+    CHECK ((recipient_email IS NULL) = (recipient_id IS NULL))
+);
+
+CREATE INDEX workout_share_workout_id_index ON workout_share (workout_id);
+CREATE INDEX workout_share_recipient_id_index ON workout_share (recipient_id);
+
+-- invite is pending until client accepts it, deleting relationship revokes coach's access immediately
+CREATE TABLE coaching
+(
+    id           uuid PRIMARY KEY,
+    coach        uuid      NOT NULL,
+    client_email TEXT      NOT NULL,
+    -- account registered with client_email when invited, invite is pending until accepted_at is set
+    client       uuid      NOT NULL REFERENCES "user" (id) ON DELETE CASCADE,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    accepted_at  TIMESTAMPTZ
+);
+
+CREATE UNIQUE INDEX coaching_coach_client_index ON coaching (coach, client);
+CREATE INDEX coaching_client_index ON coaching (client);
+
+-- comment targets exactly one of workout, workout exercise or session, replies share target of the thread
+CREATE TABLE comment
+(
+    id                  uuid PRIMARY KEY,
+    author              uuid      NOT NULL,
+    workout_id          uuid REFERENCES workout (id) ON DELETE CASCADE,
+    workout_exercise_id uuid REFERENCES workout_exercise (workout_exercise_id) ON DELETE CASCADE,
+    schedule_id         uuid REFERENCES workout_schedule (id) ON DELETE CASCADE,
+    parent_id           uuid REFERENCES comment (id) ON DELETE CASCADE,
+    body                TEXT      NOT NULL,
+    created_at          TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at          TIMESTAMPTZ,
+    CHECK (num_nonnulls(workout_id, workout_exercise_id, schedule_id) = 1)
+);
+
+CREATE INDEX comment_workout_id_index ON comment (workout_id, created_at, id);
+CREATE INDEX comment_workout_exercise_id_index ON comment (workout_exercise_id, created_at, id);
+CREATE INDEX comment_schedule_id_index ON comment (schedule_id, created_at, id);
+CREATE INDEX comment_parent_id_index ON comment (parent_id, created_at, id);
+
+CREATE TABLE notification
+(
+    id         uuid PRIMARY KEY,
+    "user"     uuid      NOT NULL,
+    type       TEXT      NOT NULL,
+    comment_id uuid      NOT NULL REFERENCES comment (id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    read_at    TIMESTAMPTZ
+);
+
+CREATE INDEX notification_user_index ON notification ("user", created_at DESC, id DESC);
+
+CREATE TABLE user_profile
+(
+    user_id                    uuid PRIMARY KEY,
+    reminder_lead_time_minutes INTEGER   NOT NULL DEFAULT 60,
+    reminder_channels          TEXT[]    NOT NULL DEFAULT '{}',
+    webhook_url                TEXT,
+    default_rest_seconds       INTEGER   NOT NULL DEFAULT 90,
+    warm_up_rest_seconds       INTEGER,
+    updated_at                 TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- one row per schedule and channel, claimed by single replica before sending
+CREATE TABLE reminder_delivery
+(
+    schedule_id uuid      NOT NULL REFERENCES workout_schedule (id) ON DELETE CASCADE,
+    channel     TEXT      NOT NULL,
+    claimed_at  TIMESTAMPTZ NOT NULL,
+    attempts    INTEGER   NOT NULL DEFAULT 1,
+    sent_at     TIMESTAMPTZ,
+    last_error  TEXT,
+    PRIMARY KEY (schedule_id, channel)
+);
+
+-- only hash of the token is stored, token itself is shown to the user once
+CREATE TABLE calendar_feed
+(
+    user_id    uuid PRIMARY KEY,
+    token_hash TEXT      NOT NULL UNIQUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- response of create request stored for replay of retries with the same key, response is null while request is processed
+CREATE TABLE idempotency_key
+(
+    user_id      uuid      NOT NULL,
+    method       TEXT      NOT NULL,
+    key          TEXT      NOT NULL,
+    request_hash BYTEA     NOT NULL,
+    response     BYTEA,
+    created_at   TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (user_id, method, key)
+);
+
+CREATE INDEX idempotency_key_created_at_index ON idempotency_key (created_at);
+
 -- Populating here since this is some predefined data
 INSERT INTO exercise (id, name, description, category, muscle_group)
 VALUES ('87df312d-36e0-40e8-915e-093ac3342ac8', 'Bench Press',
@@ -60,3 +365,21 @@ VALUES ('87df312d-36e0-40e8-915e-093ac3342ac8', 'Bench Press',
         'A pull-up is an upper-body strength exercise.', 'CARDIO', 'BACK'),
        ('66a27a50-191d-4338-a6b9-59366b9c423c', 'Push-up',
         'A push-up is a common calisthenics exercise beginning from the prone position.', 'STRENGTH', 'CHEST');
+
+INSERT INTO workout (id, name, "owner", comment, template)
+VALUES ('0b0f6d4e-3c1b-4d2a-9a55-1f1d3c1f7a01', 'Full Body Beginner', '00000000-0000-0000-0000-000000000000',
+        'Three times a week with a rest day between sessions.', TRUE),
+       ('0b0f6d4e-3c1b-4d2a-9a55-1f1d3c1f7a02', 'Upper Body Push', '00000000-0000-0000-0000-000000000000',
+        NULL, TRUE);
+
+INSERT INTO workout_exercise (workout_exercise_id, workout_id, exercise_id, "order", repetitions, sets, rest_seconds)
+VALUES ('5d1e2f3a-6b7c-4d8e-9f01-2a3b4c5d6e01', '0b0f6d4e-3c1b-4d2a-9a55-1f1d3c1f7a01',
+        'c3339fa8-f9d6-481d-b983-f9cdc24ca4d0', 1, 8, 3, 120),
+       ('5d1e2f3a-6b7c-4d8e-9f01-2a3b4c5d6e02', '0b0f6d4e-3c1b-4d2a-9a55-1f1d3c1f7a01',
+        '87df312d-36e0-40e8-915e-093ac3342ac8', 2, 8, 3, 120),
+       ('5d1e2f3a-6b7c-4d8e-9f01-2a3b4c5d6e03', '0b0f6d4e-3c1b-4d2a-9a55-1f1d3c1f7a01',
+        '94b4109b-25ba-4519-8aa7-6adef75c0d37', 3, 6, 3, 120),
+       ('5d1e2f3a-6b7c-4d8e-9f01-2a3b4c5d6e04', '0b0f6d4e-3c1b-4d2a-9a55-1f1d3c1f7a02',
+        '87df312d-36e0-40e8-915e-093ac3342ac8', 1, 5, 5, 180),
+       ('5d1e2f3a-6b7c-4d8e-9f01-2a3b4c5d6e05', '0b0f6d4e-3c1b-4d2a-9a55-1f1d3c1f7a02',
+        '66a27a50-191d-4338-a6b9-59366b9c423c', 2, 15, 3, 60);
@@ -0,0 +1,354 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"google.golang.org/genproto/googleapis/api/httpbody"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"log"
+	workout "proto/workout/v1/generated"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+	"workout-tracker-server/auth"
+	"workout-tracker-server/db"
+	"workout-tracker-server/model"
+)
+
+const (
+	calendarFeedSuffix = ".ics"
+	// calendarEventDuration is fixed length of calendar event. Duration estimates are recalibrated with every completed
+	// session, following them would make calendar apps rewrite all events of the feed on refresh.
+	calendarEventDuration = time.Hour
+	calendarUIDDomain     = "@workout-tracker"
+	icsTimeLayout         = "20060102T150405Z"
+	icsLocalTimeLayout    = "20060102T150405"
+	icsMaxLineLength      = 75
+)
+
+type CalendarAPI struct {
+	workout.UnimplementedCalendarServiceServer
+	db   db.CalendarDb
+	wsDb db.WorkoutScheduleDb
+	wDb  db.WorkoutDb
+}
+
+func NewCalendarAPI(db db.CalendarDb, wsDb db.WorkoutScheduleDb, wDb db.WorkoutDb) *CalendarAPI {
+	return &CalendarAPI{db: db, wsDb: wsDb, wDb: wDb}
+}
+
+func (c *CalendarAPI) CreateCalendarFeed(ctx context.Context, _ *emptypb.Empty) (*workout.CalendarFeed, error) {
+	userId, err := auth.GetUserId(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "user id not found in context")
+	}
+	token, err := newSecretToken()
+	if err != nil {
+		log.Printf("error generating calendar feed token: %v", err)
+		return nil, status.Error(codes.Internal, "error creating calendar feed")
+	}
+	if err := c.db.SaveCalendarFeed(userId, hashSecretToken(token)); err != nil {
+		log.Printf("error saving calendar feed: %v", err)
+		return nil, status.Error(codes.Internal, "error creating calendar feed")
+	}
+	return &workout.CalendarFeed{
+		Token: token,
+		Path:  "/v1/calendar/" + token + calendarFeedSuffix,
+	}, nil
+}
+
+func (c *CalendarAPI) DeleteCalendarFeed(ctx context.Context, _ *emptypb.Empty) (*emptypb.Empty, error) {
+	userId, err := auth.GetUserId(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "user id not found in context")
+	}
+	err = c.db.DeleteCalendarFeed(userId)
+	if errors.Is(err, db.ErrCalendarFeedNotFound) {
+		return nil, status.Error(codes.NotFound, "calendar feed not found")
+	}
+	if err != nil {
+		log.Printf("error deleting calendar feed: %v", err)
+		return nil, status.Error(codes.Internal, "error deleting calendar feed")
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// GetCalendarFeed renders all user's workout schedules as iCalendar, recurring series are rendered with RRULE
+// and their detached occurrences as overrides of the series event.
+func (c *CalendarAPI) GetCalendarFeed(_ context.Context, rq *workout.GetCalendarFeedRequest) (*httpbody.HttpBody, error) {
+	if err := rq.Validate(); err != nil {
+		return nil, validationError(func() error { return err.(workout.GetCalendarFeedRequestValidationError) })
+	}
+	userId, err := c.db.GetCalendarFeedOwner(hashSecretToken(strings.TrimSuffix(rq.Token, calendarFeedSuffix)))
+	if errors.Is(err, db.ErrCalendarFeedNotFound) {
+		return nil, status.Error(codes.NotFound, "calendar feed not found")
+	}
+	if err != nil {
+		log.Printf("error getting calendar feed: %v", err)
+		return nil, status.Error(codes.Internal, "error getting calendar feed")
+	}
+	now := time.Now().UTC()
+	schedules, err := c.wsDb.ListWorkoutSchedules(userId, model.WorkoutScheduleFilter{Now: now})
+	if err != nil {
+		log.Printf("error getting workout schedules: %v", err)
+		return nil, status.Error(codes.Internal, "error getting calendar feed")
+	}
+	seriesList, err := c.wsDb.GetWorkoutScheduleSeriesByOwner(userId)
+	if err != nil {
+		log.Printf("error getting workout schedule series: %v", err)
+		return nil, status.Error(codes.Internal, "error getting calendar feed")
+	}
+	var workoutIds []string
+	for _, ws := range schedules {
+		workoutIds = append(workoutIds, ws.WorkoutID)
+	}
+	for _, series := range seriesList {
+		workoutIds = append(workoutIds, series.WorkoutID)
+	}
+	summaries, err := c.wDb.GetWorkoutSummaries(workoutIds)
+	if err != nil {
+		log.Printf("error getting workout summaries: %v", err)
+		return nil, status.Error(codes.Internal, "error getting calendar feed")
+	}
+	ics, err := calendarFeedIcs(schedules, seriesList, summaries, now)
+	if err != nil {
+		log.Printf("error writing calendar feed: %v", err)
+		return nil, status.Error(codes.Internal, "error getting calendar feed")
+	}
+	return &httpbody.HttpBody{
+		ContentType: "text/calendar; charset=utf-8",
+		Data:        ics,
+	}, nil
+}
+
+// calendarFeedIcs writes iCalendar (RFC 5545) document. Event UIDs are derived from workout schedule and series ids,
+// so calendar apps update existing events on changes instead of duplicating them.
+func calendarFeedIcs(schedules []model.WorkoutSchedule, seriesList []model.WorkoutScheduleSeries, summaries map[string]model.WorkoutSummary, now time.Time) ([]byte, error) {
+	w := &icsWriter{}
+	w.line("BEGIN", "VCALENDAR")
+	w.line("VERSION", "2.0")
+	w.line("PRODID", "-//workout-tracker//calendar feed//EN")
+	w.line("CALSCALE", "GREGORIAN")
+	w.line("X-WR-CALNAME", "Workouts")
+	var timezones []*time.Location
+	seriesTimezones := make(map[string]*time.Location, len(seriesList))
+	for _, series := range seriesList {
+		loc, err := model.Location(series.Timezone)
+		if err != nil {
+			return nil, err
+		}
+		seriesTimezones[series.ID] = loc
+		timezones = append(timezones, loc)
+	}
+	scheduleTimezones := make([]*time.Location, len(schedules))
+	for i, ws := range schedules {
+		loc, err := model.Location(ws.Timezone)
+		if err != nil {
+			return nil, err
+		}
+		scheduleTimezones[i] = loc
+		timezones = append(timezones, loc)
+	}
+	written := map[string]bool{time.UTC.String(): true}
+	for _, loc := range timezones {
+		if !written[loc.String()] {
+			written[loc.String()] = true
+			w.timezone(loc, now.Year())
+		}
+	}
+	for _, series := range seriesList {
+		loc := seriesTimezones[series.ID]
+		w.line("BEGIN", "VEVENT")
+		w.line("UID", series.ID+calendarUIDDomain)
+		w.line("DTSTAMP", now.UTC().Format(icsTimeLayout))
+		w.timeLine("DTSTART", loc, series.StartsAt)
+		w.timeLine("DTEND", loc, series.StartsAt.Add(calendarEventDuration))
+		w.line("RRULE", series.Recurrence.String())
+		if len(series.ExcludedDates) > 0 {
+			w.timeLine("EXDATE", loc, series.ExcludedDates...)
+		}
+		w.workoutLines(summaries[series.WorkoutID])
+		w.line("STATUS", "CONFIRMED")
+		w.line("END", "VEVENT")
+	}
+	for i, ws := range schedules {
+		w.line("BEGIN", "VEVENT")
+		loc := scheduleTimezones[i]
+		var seriesLoc *time.Location
+		if ws.SeriesID != nil {
+			seriesLoc = seriesTimezones[*ws.SeriesID]
+		}
+		if seriesLoc != nil && ws.RecurrenceID != nil {
+			//recurrence id must have the same form as start of the series
+			w.line("UID", *ws.SeriesID+calendarUIDDomain)
+			w.timeLine("RECURRENCE-ID", seriesLoc, *ws.RecurrenceID)
+		} else {
+			w.line("UID", ws.ID+calendarUIDDomain)
+		}
+		w.line("DTSTAMP", now.UTC().Format(icsTimeLayout))
+		w.timeLine("DTSTART", loc, ws.ScheduledAt)
+		w.timeLine("DTEND", loc, ws.ScheduledAt.Add(calendarEventDuration))
+		w.workoutLines(summaries[ws.WorkoutID])
+		if ws.CancelledAt != nil {
+			w.line("STATUS", "CANCELLED")
+		} else {
+			w.line("STATUS", "CONFIRMED")
+		}
+		w.line("END", "VEVENT")
+	}
+	w.line("END", "VCALENDAR")
+	return w.buf.Bytes(), nil
+}
+
+type icsWriter struct {
+	buf bytes.Buffer
+}
+
+// timeLine writes UTC times in UTC form and times in other locations as local times with TZID parameter, so
+// recurring events keep wall-clock time across DST transitions.
+func (w *icsWriter) timeLine(name string, loc *time.Location, times ...time.Time) {
+	var values []string
+	for _, t := range times {
+		if loc == time.UTC {
+			values = append(values, t.UTC().Format(icsTimeLayout))
+		} else {
+			values = append(values, t.In(loc).Format(icsLocalTimeLayout))
+		}
+	}
+	if loc != time.UTC {
+		name += ";TZID=" + loc.String()
+	}
+	w.line(name, strings.Join(values, ","))
+}
+
+// timezone writes VTIMEZONE component with DST transitions of given year as yearly rules, timezone without
+// transitions is written with its standard offset only.
+func (w *icsWriter) timezone(loc *time.Location, year int) {
+	w.line("BEGIN", "VTIMEZONE")
+	w.line("TZID", loc.String())
+	transitions := zoneTransitions(loc, year)
+	if len(transitions) == 0 {
+		start := time.Date(year, time.January, 1, 0, 0, 0, 0, loc)
+		name, offset := start.Zone()
+		w.line("BEGIN", "STANDARD")
+		w.line("DTSTART", start.Format(icsLocalTimeLayout))
+		w.line("TZOFFSETFROM", icsUTCOffset(offset))
+		w.line("TZOFFSETTO", icsUTCOffset(offset))
+		w.line("TZNAME", name)
+		w.line("END", "STANDARD")
+	}
+	for _, transition := range transitions {
+		_, offsetFrom := transition.Add(-time.Second).Zone()
+		name, offsetTo := transition.Zone()
+		//transition is described by wall-clock time before it
+		local := transition.UTC().Add(time.Duration(offsetFrom) * time.Second)
+		component := "STANDARD"
+		if transition.IsDST() {
+			component = "DAYLIGHT"
+		}
+		week := (local.Day()-1)/7 + 1
+		if local.AddDate(0, 0, 7).Month() != local.Month() {
+			week = -1
+		}
+		w.line("BEGIN", component)
+		w.line("DTSTART", local.Format(icsLocalTimeLayout))
+		w.line("TZOFFSETFROM", icsUTCOffset(offsetFrom))
+		w.line("TZOFFSETTO", icsUTCOffset(offsetTo))
+		w.line("TZNAME", name)
+		w.line("RRULE", fmt.Sprintf("FREQ=YEARLY;BYMONTH=%d;BYDAY=%d%s", local.Month(), week, strings.ToUpper(local.Weekday().String()[:2])))
+		w.line("END", component)
+	}
+	w.line("END", "VTIMEZONE")
+}
+
+// zoneTransitions returns instants within given year when UTC offset of the location changes.
+func zoneTransitions(loc *time.Location, year int) []time.Time {
+	var transitions []time.Time
+	end := time.Date(year+1, time.January, 1, 0, 0, 0, 0, time.UTC)
+	for day := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC); day.Before(end); day = day.AddDate(0, 0, 1) {
+		from, to := day.In(loc), day.AddDate(0, 0, 1).In(loc)
+		_, offsetFrom := from.Zone()
+		if _, offsetTo := to.Zone(); offsetTo == offsetFrom {
+			continue
+		}
+		//binary search for the first second with new offset
+		for to.Sub(from) > time.Second {
+			middle := from.Add(to.Sub(from) / 2)
+			if _, offset := middle.Zone(); offset == offsetFrom {
+				from = middle
+			} else {
+				to = middle
+			}
+		}
+		transitions = append(transitions, to)
+	}
+	return transitions
+}
+
+func icsUTCOffset(seconds int) string {
+	sign := "+"
+	if seconds < 0 {
+		sign, seconds = "-", -seconds
+	}
+	return fmt.Sprintf("%s%02d%02d", sign, seconds/3600, seconds%3600/60)
+}
+
+func (w *icsWriter) workoutLines(summary model.WorkoutSummary) {
+	w.line("SUMMARY", escapeIcsText(summary.Name))
+	if len(summary.Exercises) == 0 {
+		return
+	}
+	var exercises []string
+	for _, ex := range summary.Exercises {
+		line := fmt.Sprintf("%s: %d x %d", ex.Name, ex.Sets, ex.Repetitions)
+		if ex.Weight != nil {
+			line += fmt.Sprintf(" @ %d", *ex.Weight)
+		}
+		if ex.Rest.Tempo != nil {
+			line += ", tempo " + *ex.Rest.Tempo
+		}
+		if rests := exerciseRests(ex, summary.RestDefaults); rests != "" {
+			line += ", rest " + rests
+		}
+		exercises = append(exercises, line)
+	}
+	w.line("DESCRIPTION", escapeIcsText(strings.Join(exercises, "\n")))
+}
+
+// exerciseRests describes rests between sets of the exercise, e.g. "90s" or "60s/90s/90s" when rests differ.
+func exerciseRests(ex model.ExerciseSummary, defaults model.RestDefaults) string {
+	var rests []string
+	for set := 1; set < int(ex.Sets); set++ {
+		rests = append(rests, ex.Rest.AfterSet(set, defaults).String())
+	}
+	if len(rests) > 0 && !slices.ContainsFunc(rests, func(rest string) bool { return rest != rests[0] }) {
+		return rests[0]
+	}
+	return strings.Join(rests, "/")
+}
+
+// line writes content line folded to 75 octets, continuation lines start with a space which counts to the limit.
+func (w *icsWriter) line(name, value string) {
+	content := name + ":" + value
+	limit := icsMaxLineLength
+	for len(content) > limit {
+		cut := limit
+		//don't split multi-byte characters
+		for !utf8.RuneStart(content[cut]) {
+			cut--
+		}
+		w.buf.WriteString(content[:cut] + "\r\n ")
+		content = content[cut:]
+		limit = icsMaxLineLength - 1
+	}
+	w.buf.WriteString(content + "\r\n")
+}
+
+func escapeIcsText(value string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(value)
+}
@@ -0,0 +1,213 @@
+package api
+
+import (
+	"context"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"net"
+	workout "proto/workout/v1/generated"
+	"strings"
+	"testing"
+	"time"
+	"workout-tracker-server/db"
+	"workout-tracker-server/mocks"
+	"workout-tracker-server/model"
+	"workout-tracker-server/rrule"
+)
+
+const testCalendarToken = "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA"
+
+type CalendarAPISuite struct {
+	suite.Suite
+	cDbMock        *mocks.CalendarDb
+	wsDbMock       *mocks.WorkoutScheduleDb
+	wDbMock        *mocks.WorkoutDb
+	calendarClient workout.CalendarServiceClient
+	cleanup        func()
+}
+
+func TestCalendarAPISuite(t *testing.T) {
+	suite.Run(t, new(CalendarAPISuite))
+}
+
+func (s *CalendarAPISuite) SetupSuite() {
+	s.cDbMock = mocks.NewCalendarDb(s.T())
+	s.wsDbMock = mocks.NewWorkoutScheduleDb(s.T())
+	s.wDbMock = mocks.NewWorkoutDb(s.T())
+	lis := bufconn.Listen(1024 * 1024)
+
+	server := grpc.NewServer()
+	workout.RegisterCalendarServiceServer(server, NewCalendarAPI(s.cDbMock, s.wsDbMock, s.wDbMock))
+	go func() {
+		if err := server.Serve(lis); err != nil {
+			s.T().Errorf("Server exited with error: %v", err)
+		}
+	}()
+	client, err := grpc.NewClient("passthrough://",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return lis.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	s.Require().NoError(err)
+	s.calendarClient = workout.NewCalendarServiceClient(client)
+
+	s.cleanup = func() {
+		client.Close()
+		server.Stop()
+	}
+}
+
+func (s *CalendarAPISuite) TearDownSuite() {
+	s.cleanup()
+}
+
+func (s *CalendarAPISuite) TestGetCalendarFeedInvalidToken() {
+	resp, err := s.calendarClient.GetCalendarFeed(context.Background(), &workout.GetCalendarFeedRequest{Token: "token"})
+
+	s.Require().Nil(resp)
+	s.Require().Equal(codes.InvalidArgument, status.Code(err))
+}
+
+func (s *CalendarAPISuite) TestGetCalendarFeedNotFound() {
+	s.cDbMock.EXPECT().GetCalendarFeedOwner(hashSecretToken(testCalendarToken)).Return("", db.ErrCalendarFeedNotFound).Once()
+
+	resp, err := s.calendarClient.GetCalendarFeed(context.Background(), &workout.GetCalendarFeedRequest{Token: testCalendarToken + ".ics"})
+
+	s.Require().Nil(resp)
+	s.Require().Equal(codes.NotFound, status.Code(err))
+	s.Require().Equal("calendar feed not found", status.Convert(err).Message())
+}
+
+func (s *CalendarAPISuite) TestGetCalendarFeed() {
+	//given
+	userId := "4f97a755-aeee-4d23-a518-0f8c82680014"
+	ws := model.WorkoutSchedule{ID: "schedule", WorkoutID: "workout", ScheduledAt: time.Date(2026, 1, 2, 18, 0, 0, 0, time.UTC)}
+	s.cDbMock.EXPECT().GetCalendarFeedOwner(hashSecretToken(testCalendarToken)).Return(userId, nil).Once()
+	s.wsDbMock.EXPECT().ListWorkoutSchedules(userId, mock.Anything).Return([]model.WorkoutSchedule{ws}, nil).Once()
+	s.wsDbMock.EXPECT().GetWorkoutScheduleSeriesByOwner(userId).Return(nil, nil).Once()
+	s.wDbMock.EXPECT().GetWorkoutSummaries([]string{"workout"}).Return(map[string]model.WorkoutSummary{"workout": {Name: "Push day"}}, nil).Once()
+
+	//when
+	resp, err := s.calendarClient.GetCalendarFeed(context.Background(), &workout.GetCalendarFeedRequest{Token: testCalendarToken + ".ics"})
+
+	//then
+	s.Require().NoError(err)
+	s.Require().Equal("text/calendar; charset=utf-8", resp.ContentType)
+	s.Require().Contains(string(resp.Data), "UID:schedule@workout-tracker\r\n")
+	s.Require().Contains(string(resp.Data), "SUMMARY:Push day\r\n")
+}
+
+func TestCalendarFeedIcs(t *testing.T) {
+	//given series with excluded date, its moved occurrence and cancelled standalone schedule
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	start := time.Date(2026, 1, 5, 7, 0, 0, 0, time.UTC)
+	seriesId := "series"
+	recurrenceId := start.AddDate(0, 0, 7)
+	cancelledAt := now
+	weight := int32(50)
+	series := model.WorkoutScheduleSeries{
+		ID:            seriesId,
+		WorkoutID:     "workout",
+		StartsAt:      start,
+		Recurrence:    rrule.Rule{Freq: rrule.Weekly, Interval: 1, ByDay: []time.Weekday{time.Monday}},
+		ExcludedDates: []time.Time{start.AddDate(0, 0, 14)},
+	}
+	schedules := []model.WorkoutSchedule{
+		{ID: "moved", WorkoutID: "workout", ScheduledAt: recurrenceId.Add(time.Hour), SeriesID: &seriesId, RecurrenceID: &recurrenceId},
+		{ID: "cancelled", WorkoutID: "workout", ScheduledAt: start.Add(2 * time.Hour), CancelledAt: &cancelledAt},
+	}
+	tempo := "3-1-1-0"
+	summaries := map[string]model.WorkoutSummary{"workout": {
+		Name: "Push, pull",
+		Exercises: []model.ExerciseSummary{
+			{Name: "Bench Press", Sets: 3, Repetitions: 10, Weight: &weight, Rest: model.Rest{SetSeconds: []int32{60}, Tempo: &tempo}},
+			{Name: "Push-up", Sets: 2, Repetitions: 20},
+		},
+		RestDefaults: model.RestDefaults{Rest: model.DefaultRest},
+	}}
+
+	//when
+	feed, err := calendarFeedIcs(schedules, []model.WorkoutScheduleSeries{series}, summaries, now)
+	ics := string(feed)
+
+	//then
+	require.NoError(t, err)
+	events := strings.Split(ics, "BEGIN:VEVENT\r\n")[1:]
+	require.Len(t, events, 3)
+	require.Contains(t, events[0], "UID:series@workout-tracker\r\n")
+	require.Contains(t, events[0], "DTSTART:20260105T070000Z\r\n")
+	require.Contains(t, events[0], "RRULE:FREQ=WEEKLY;BYDAY=MO\r\n")
+	require.Contains(t, events[0], "EXDATE:20260119T070000Z\r\n")
+	require.Contains(t, events[0], "SUMMARY:Push\\, pull\r\n")
+	require.Contains(t, strings.ReplaceAll(events[0], "\r\n ", ""),
+		"DESCRIPTION:Bench Press: 3 x 10 @ 50\\, tempo 3-1-1-0\\, rest 1m0s/1m30s\\nPush-up: 2 x 20\\, rest 1m30s\r\n",
+	)
+	require.Contains(t, events[1], "UID:series@workout-tracker\r\n")
+	require.Contains(t, events[1], "RECURRENCE-ID:20260112T070000Z\r\n")
+	require.Contains(t, events[1], "DTSTART:20260112T080000Z\r\n")
+	require.Contains(t, events[2], "UID:cancelled@workout-tracker\r\n")
+	require.Contains(t, events[2], "STATUS:CANCELLED\r\n")
+	require.True(t, strings.HasPrefix(ics, "BEGIN:VCALENDAR\r\n"))
+	require.True(t, strings.HasSuffix(ics, "END:VCALENDAR\r\n"))
+}
+
+func TestCalendarFeedIcsWithTimezone(t *testing.T) {
+	//given series in Warsaw with its moved occurrence
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	seriesId := "series"
+	start := time.Date(2026, 1, 5, 6, 0, 0, 0, time.UTC)
+	recurrenceId := start.AddDate(0, 0, 7)
+	series := model.WorkoutScheduleSeries{
+		ID:         seriesId,
+		WorkoutID:  "workout",
+		StartsAt:   start,
+		Timezone:   "Europe/Warsaw",
+		Recurrence: rrule.Rule{Freq: rrule.Weekly, Interval: 1, ByDay: []time.Weekday{time.Monday}},
+	}
+	schedules := []model.WorkoutSchedule{
+		{ID: "moved", WorkoutID: "workout", ScheduledAt: recurrenceId.Add(time.Hour), Timezone: "Europe/Warsaw", SeriesID: &seriesId, RecurrenceID: &recurrenceId},
+	}
+
+	//when
+	feed, err := calendarFeedIcs(schedules, []model.WorkoutScheduleSeries{series}, nil, now)
+	ics := string(feed)
+
+	//then times are local with timezone definition of both DST transitions
+	require.NoError(t, err)
+	require.Equal(t, 1, strings.Count(ics, "BEGIN:VTIMEZONE\r\n"))
+	require.Contains(t, ics, "TZID:Europe/Warsaw\r\n")
+	require.Contains(t, ics, "BEGIN:DAYLIGHT\r\nDTSTART:20260329T020000\r\nTZOFFSETFROM:+0100\r\nTZOFFSETTO:+0200\r\n")
+	require.Contains(t, ics, "RRULE:FREQ=YEARLY;BYMONTH=3;BYDAY=-1SU\r\n")
+	require.Contains(t, ics, "RRULE:FREQ=YEARLY;BYMONTH=10;BYDAY=-1SU\r\n")
+	events := strings.Split(ics, "BEGIN:VEVENT\r\n")[1:]
+	require.Len(t, events, 2)
+	require.Contains(t, events[0], "DTSTART;TZID=Europe/Warsaw:20260105T070000\r\n")
+	require.Contains(t, events[1], "RECURRENCE-ID;TZID=Europe/Warsaw:20260112T070000\r\n")
+	require.Contains(t, events[1], "DTSTART;TZID=Europe/Warsaw:20260112T080000\r\n")
+}
+
+func TestIcsLineFolding(t *testing.T) {
+	//given
+	w := &icsWriter{}
+
+	//when
+	w.line("DESCRIPTION", strings.Repeat("ą", 100))
+
+	//then
+	lines := strings.Split(strings.TrimSuffix(w.buf.String(), "\r\n"), "\r\n")
+	require.Greater(t, len(lines), 1)
+	unfolded := lines[0]
+	for i, line := range lines {
+		require.LessOrEqual(t, len(line), icsMaxLineLength)
+		if i > 0 {
+			require.True(t, strings.HasPrefix(line, " "))
+			unfolded += line[1:]
+		}
+	}
+	require.Equal(t, "DESCRIPTION:"+strings.Repeat("ą", 100), unfolded)
+}
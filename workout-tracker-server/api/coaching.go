@@ -0,0 +1,159 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"log"
+	workout "proto/workout/v1/generated"
+	"workout-tracker-server/auth"
+	"workout-tracker-server/db"
+)
+
+type CoachingAPI struct {
+	workout.UnimplementedCoachingServiceServer
+	db db.CoachingDb
+}
+
+func NewCoachingAPI(db db.CoachingDb) *CoachingAPI {
+	return &CoachingAPI{db: db}
+}
+
+// InviteClient creates pending invite, coach gets access to client's account once the client accepts it.
+func (c *CoachingAPI) InviteClient(ctx context.Context, rq *workout.InviteClientRequest) (*workout.CoachingRelationship, error) {
+	if err := rq.Validate(); err != nil {
+		return nil, validationError(func() error { return err.(workout.InviteClientRequestValidationError) })
+	}
+	userId, err := auth.GetUserId(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "user id not found in context")
+	}
+	relationship, err := c.db.SaveCoachingInvite(userId, rq.ClientEmail)
+	if errors.Is(err, db.ErrCoachingRelationshipExists) {
+		return nil, status.Error(codes.AlreadyExists, "client already invited")
+	}
+	if errors.Is(err, db.ErrCoachingClientNotFound) {
+		return nil, status.Error(codes.NotFound, "client not found")
+	}
+	if errors.Is(err, db.ErrCoachingSelfInvite) {
+		return nil, status.Error(codes.InvalidArgument, "coach can't invite themselves")
+	}
+	if err != nil {
+		log.Printf("error saving coaching invite: %v", err)
+		return nil, status.Error(codes.Internal, "error inviting client")
+	}
+	return relationship.ToProto(), nil
+}
+
+func (c *CoachingAPI) ListCoachingRelationships(ctx context.Context, _ *emptypb.Empty) (*workout.ListCoachingRelationshipsResponse, error) {
+	userId, err := auth.GetUserId(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "user id not found in context")
+	}
+	relationships, err := c.db.GetCoachingRelationships(userId)
+	if err != nil {
+		log.Printf("error listing coaching relationships: %v", err)
+		return nil, status.Error(codes.Internal, "error listing coaching relationships")
+	}
+	var resp workout.ListCoachingRelationshipsResponse
+	for _, relationship := range relationships {
+		resp.Relationships = append(resp.Relationships, relationship.ToProto())
+	}
+	return &resp, nil
+}
+
+// AcceptCoachingInvite activates invite addressed to account of the user.
+func (c *CoachingAPI) AcceptCoachingInvite(ctx context.Context, rq *workout.AcceptCoachingInviteRequest) (*emptypb.Empty, error) {
+	if err := rq.Validate(); err != nil {
+		return nil, validationError(func() error { return err.(workout.AcceptCoachingInviteRequestValidationError) })
+	}
+	userId, err := auth.GetUserId(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "user id not found in context")
+	}
+	if err := c.db.AcceptCoachingInvite(rq.Id, userId); err != nil {
+		if errors.Is(err, db.ErrCoachingRelationshipNotFound) {
+			return nil, status.Error(codes.NotFound, "coaching invite not found")
+		}
+		log.Printf("error accepting coaching invite: %v", err)
+		return nil, status.Error(codes.Internal, "error accepting coaching invite")
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// DeleteCoachingRelationship lets coach withdraw the relationship and client revoke it, coach loses access
+// immediately.
+func (c *CoachingAPI) DeleteCoachingRelationship(ctx context.Context, rq *workout.DeleteCoachingRelationshipRequest) (*emptypb.Empty, error) {
+	if err := rq.Validate(); err != nil {
+		return nil, validationError(func() error { return err.(workout.DeleteCoachingRelationshipRequestValidationError) })
+	}
+	userId, err := auth.GetUserId(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "user id not found in context")
+	}
+	relationship, err := c.db.GetCoachingRelationship(rq.Id)
+	if err != nil && !errors.Is(err, db.ErrCoachingRelationshipNotFound) {
+		log.Printf("error getting coaching relationship: %v", err)
+		return nil, status.Error(codes.Internal, "error deleting coaching relationship")
+	}
+	if err != nil || !relationship.IsParticipant(userId) {
+		return nil, status.Error(codes.NotFound, "coaching relationship not found")
+	}
+	if err := c.db.DeleteCoachingRelationship(rq.Id); err != nil && !errors.Is(err, db.ErrCoachingRelationshipNotFound) {
+		log.Printf("error deleting coaching relationship: %v", err)
+		return nil, status.Error(codes.Internal, "error deleting coaching relationship")
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// getValidatedWorkoutOwnerId returns owner of the workout when the user is the owner or active coach of the owner.
+func getValidatedWorkoutOwnerId(ctx context.Context, wDb db.WorkoutDb, cDb db.CoachingDb, workoutId string) (string, error) {
+	userId, err := auth.GetUserId(ctx)
+	if err != nil {
+		return "", status.Error(codes.Internal, "user id not found in context")
+	}
+	owner, err := wDb.GetWorkoutOwner(workoutId)
+	if errors.Is(err, db.ErrWorkoutNotFound) {
+		return "", status.Error(codes.NotFound, "workout not found")
+	}
+	if err != nil {
+		log.Printf("error getting workout data: %v", err)
+		return "", status.Error(codes.Internal, "error getting workout data")
+	}
+	if owner == userId {
+		return owner, nil
+	}
+	if err := validateCoachOf(cDb, userId, owner); err != nil {
+		return "", err
+	}
+	return owner, nil
+}
+
+// getAccountId returns id of the user, or id of the client when given and the user is their active coach.
+func getAccountId(ctx context.Context, cDb db.CoachingDb, clientId *string) (string, error) {
+	userId, err := auth.GetUserId(ctx)
+	if err != nil {
+		return "", status.Error(codes.Internal, "user id not found in context")
+	}
+	if clientId == nil || *clientId == userId {
+		return userId, nil
+	}
+	if err := validateCoachOf(cDb, userId, *clientId); err != nil {
+		return "", err
+	}
+	return *clientId, nil
+}
+
+func validateCoachOf(cDb db.CoachingDb, coachId, clientId string) error {
+	isCoach, err := cDb.IsCoachOf(coachId, clientId)
+	if err != nil {
+		log.Printf("error getting coaching relationship: %v", err)
+		return status.Error(codes.Internal, "error getting coaching relationship")
+	}
+	if !isCoach {
+		return status.Error(codes.PermissionDenied, "access forbidden")
+	}
+	return nil
+}
@@ -0,0 +1,243 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"log"
+	workout "proto/workout/v1/generated"
+	"workout-tracker-server/auth"
+	"workout-tracker-server/db"
+	"workout-tracker-server/model"
+)
+
+type CommentAPI struct {
+	workout.UnimplementedCommentServiceServer
+	db  db.CommentDb
+	cDb db.CoachingDb
+}
+
+func NewCommentAPI(db db.CommentDb, cDb db.CoachingDb) *CommentAPI {
+	return &CommentAPI{db: db, cDb: cDb}
+}
+
+// CreateComment starts new thread on the target or replies to existing comment, reply inherits target of the parent.
+func (c *CommentAPI) CreateComment(ctx context.Context, rq *workout.CreateCommentRequest) (*workout.Comment, error) {
+	if err := rq.Validate(); err != nil {
+		return nil, validationError(func() error { return err.(workout.CreateCommentRequestValidationError) })
+	}
+	comment := model.Comment{Body: rq.Body}
+	if replyTo, ok := rq.Thread.(*workout.CreateCommentRequest_ReplyTo); ok {
+		parent, err := c.getComment(replyTo.ReplyTo)
+		if err != nil {
+			return nil, err
+		}
+		comment.Target = parent.Target
+		comment.ParentID = &parent.ID
+	} else {
+		comment.Target = model.CommentTargetFromProto(rq.GetTarget())
+	}
+	userId, err := c.validateCommentAccess(ctx, comment.Target)
+	if err != nil {
+		return nil, err
+	}
+	comment.AuthorID = userId
+	comment, err = c.db.SaveComment(comment)
+	if err != nil {
+		log.Printf("error saving comment: %v", err)
+		return nil, status.Error(codes.Internal, "error creating comment")
+	}
+	return comment.ToProto(), nil
+}
+
+func (c *CommentAPI) ListComments(ctx context.Context, rq *workout.ListCommentsRequest) (*workout.ListCommentsResponse, error) {
+	if err := rq.Validate(); err != nil {
+		return nil, validationError(func() error { return err.(workout.ListCommentsRequestValidationError) })
+	}
+	var after *model.CreationCursor
+	if rq.PageToken != "" {
+		createdAt, id, err := decodeCursorToken(rq.PageToken)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, "invalid page token")
+		}
+		after = &model.CreationCursor{CreatedAt: createdAt, ID: id}
+	}
+	pageSize := int(rq.PageSize)
+	if pageSize == 0 {
+		pageSize = defaultPageSize
+	}
+	//one more than page size is fetched to find out whether there is next page
+	filter := model.CommentFilter{After: after, Limit: pageSize + 1}
+	var target model.CommentTarget
+	if parentId, ok := rq.Thread.(*workout.ListCommentsRequest_ParentId); ok {
+		parent, err := c.getComment(parentId.ParentId)
+		if err != nil {
+			return nil, err
+		}
+		target = parent.Target
+		filter.ParentID = &parent.ID
+	} else {
+		target = model.CommentTargetFromProto(rq.GetTarget())
+		filter.Target = &target
+	}
+	if _, err := c.validateCommentAccess(ctx, target); err != nil {
+		return nil, err
+	}
+	comments, err := c.db.ListComments(filter)
+	if err != nil {
+		log.Printf("error listing comments: %v", err)
+		return nil, status.Error(codes.Internal, "error listing comments")
+	}
+	var resp workout.ListCommentsResponse
+	if len(comments) > pageSize {
+		comments = comments[:pageSize]
+		cursor := comments[pageSize-1].Cursor()
+		resp.NextPageToken = encodeCursorToken(cursor.CreatedAt, cursor.ID)
+	}
+	for _, comment := range comments {
+		resp.Comments = append(resp.Comments, comment.ToProto())
+	}
+	return &resp, nil
+}
+
+func (c *CommentAPI) UpdateComment(ctx context.Context, rq *workout.UpdateCommentRequest) (*workout.Comment, error) {
+	if err := rq.Validate(); err != nil {
+		return nil, validationError(func() error { return err.(workout.UpdateCommentRequestValidationError) })
+	}
+	if err := c.validateCommentAuthor(ctx, rq.Id); err != nil {
+		return nil, err
+	}
+	comment, err := c.db.UpdateComment(rq.Id, rq.Body)
+	if errors.Is(err, db.ErrCommentNotFound) {
+		return nil, status.Error(codes.NotFound, "comment not found")
+	}
+	if err != nil {
+		log.Printf("error updating comment: %v", err)
+		return nil, status.Error(codes.Internal, "error updating comment")
+	}
+	return comment.ToProto(), nil
+}
+
+// DeleteComment deletes the comment together with all replies.
+func (c *CommentAPI) DeleteComment(ctx context.Context, rq *workout.DeleteCommentRequest) (*emptypb.Empty, error) {
+	if err := rq.Validate(); err != nil {
+		return nil, validationError(func() error { return err.(workout.DeleteCommentRequestValidationError) })
+	}
+	if err := c.validateCommentAuthor(ctx, rq.Id); err != nil {
+		return nil, err
+	}
+	if err := c.db.DeleteComment(rq.Id); err != nil && !errors.Is(err, db.ErrCommentNotFound) {
+		log.Printf("error deleting comment: %v", err)
+		return nil, status.Error(codes.Internal, "error deleting comment")
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (c *CommentAPI) ListNotifications(ctx context.Context, rq *workout.ListNotificationsRequest) (*workout.ListNotificationsResponse, error) {
+	if err := rq.Validate(); err != nil {
+		return nil, validationError(func() error { return err.(workout.ListNotificationsRequestValidationError) })
+	}
+	var before *model.CreationCursor
+	if rq.PageToken != "" {
+		createdAt, id, err := decodeCursorToken(rq.PageToken)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, "invalid page token")
+		}
+		before = &model.CreationCursor{CreatedAt: createdAt, ID: id}
+	}
+	userId, err := auth.GetUserId(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "user id not found in context")
+	}
+	pageSize := int(rq.PageSize)
+	if pageSize == 0 {
+		pageSize = defaultPageSize
+	}
+	notifications, err := c.db.ListNotifications(userId, model.NotificationFilter{UnreadOnly: rq.UnreadOnly, Before: before, Limit: pageSize + 1})
+	if err != nil {
+		log.Printf("error listing notifications: %v", err)
+		return nil, status.Error(codes.Internal, "error listing notifications")
+	}
+	var resp workout.ListNotificationsResponse
+	if len(notifications) > pageSize {
+		notifications = notifications[:pageSize]
+		cursor := notifications[pageSize-1].Cursor()
+		resp.NextPageToken = encodeCursorToken(cursor.CreatedAt, cursor.ID)
+	}
+	for _, notification := range notifications {
+		resp.Notifications = append(resp.Notifications, notification.ToProto())
+	}
+	return &resp, nil
+}
+
+func (c *CommentAPI) MarkNotificationRead(ctx context.Context, rq *workout.MarkNotificationReadRequest) (*emptypb.Empty, error) {
+	if err := rq.Validate(); err != nil {
+		return nil, validationError(func() error { return err.(workout.MarkNotificationReadRequestValidationError) })
+	}
+	userId, err := auth.GetUserId(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "user id not found in context")
+	}
+	if err := c.db.MarkNotificationRead(rq.Id, userId); err != nil {
+		if errors.Is(err, db.ErrNotificationNotFound) {
+			return nil, status.Error(codes.NotFound, "notification not found")
+		}
+		log.Printf("error marking notification read: %v", err)
+		return nil, status.Error(codes.Internal, "error marking notification read")
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (c *CommentAPI) getComment(id string) (model.Comment, error) {
+	comment, err := c.db.GetComment(id)
+	if errors.Is(err, db.ErrCommentNotFound) {
+		return model.Comment{}, status.Error(codes.NotFound, "comment not found")
+	}
+	if err != nil {
+		log.Printf("error getting comment: %v", err)
+		return model.Comment{}, status.Error(codes.Internal, "error getting comment")
+	}
+	return comment, nil
+}
+
+// validateCommentAccess allows owner of the target, active coach of the owner and recipients of the workout shared
+// by email to read and write comments. Returns id of the user.
+func (c *CommentAPI) validateCommentAccess(ctx context.Context, target model.CommentTarget) (string, error) {
+	userId, err := auth.GetUserId(ctx)
+	if err != nil {
+		return "", status.Error(codes.Internal, "user id not found in context")
+	}
+	access, err := c.db.GetCommentTargetAccess(target, userId)
+	if errors.Is(err, db.ErrCommentTargetNotFound) {
+		return "", status.Error(codes.NotFound, "comment target not found")
+	}
+	if err != nil {
+		log.Printf("error getting comment target: %v", err)
+		return "", status.Error(codes.Internal, "error getting comment target")
+	}
+	if access.OwnerID == userId || access.SharedWithUser {
+		return userId, nil
+	}
+	if err := validateCoachOf(c.cDb, userId, access.OwnerID); err != nil {
+		return "", err
+	}
+	return userId, nil
+}
+
+// validateCommentAuthor allows only author to edit and delete the comment.
+func (c *CommentAPI) validateCommentAuthor(ctx context.Context, id string) error {
+	userId, err := auth.GetUserId(ctx)
+	if err != nil {
+		return status.Error(codes.Internal, "user id not found in context")
+	}
+	comment, err := c.getComment(id)
+	if err != nil {
+		return err
+	}
+	if comment.AuthorID != userId {
+		return status.Error(codes.PermissionDenied, "access forbidden")
+	}
+	return nil
+}
@@ -2,11 +2,16 @@ package api
 
 import (
 	"context"
+	"errors"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
 	"log"
 	workout "proto/workout/v1/generated"
+	"strings"
+	"workout-tracker-server/auth"
 	"workout-tracker-server/db"
+	"workout-tracker-server/model"
 )
 
 type ExerciseAPI struct {
@@ -18,8 +23,15 @@ func NewExerciseAPI(db db.ExerciseDb) *ExerciseAPI {
 	return &ExerciseAPI{db: db}
 }
 
+// GetExercises is public, anonymous callers get only global exercises.
 func (e *ExerciseAPI) GetExercises(ctx context.Context, rq *workout.GetExercisesRequest) (*workout.GetExercisesResponse, error) {
-	exercises, err := e.db.GetExercises(rq.GetMuscleGroupFilter(), rq.GetCategoryFilter())
+	var exercises []model.Exercise
+	var err error
+	if userId, authErr := auth.GetUserId(ctx); authErr == nil {
+		exercises, err = e.db.GetUserExercises(userId, rq.GetMuscleGroupFilter(), rq.GetCategoryFilter())
+	} else {
+		exercises, err = e.db.GetExercises(rq.GetMuscleGroupFilter(), rq.GetCategoryFilter())
+	}
 	if err != nil {
 		log.Printf("error getting exercises: %v", err)
 		return nil, status.Error(codes.Internal, "error getting exercises")
@@ -30,3 +42,103 @@ func (e *ExerciseAPI) GetExercises(ctx context.Context, rq *workout.GetExercises
 	}
 	return &workout.GetExercisesResponse{Exercises: exercisesProto}, nil
 }
+
+func (e *ExerciseAPI) CreateExercise(ctx context.Context, rq *workout.CreateExerciseRequest) (*workout.Exercise, error) {
+	if err := rq.Validate(); err != nil {
+		return nil, validationError(func() error { return err.(workout.CreateExerciseRequestValidationError) })
+	}
+	userId, err := auth.GetUserId(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "user id not found in context")
+	}
+	exercise, err := customExercise(rq.Name, rq.Description, rq.MuscleGroup, rq.Category)
+	if err != nil {
+		return nil, err
+	}
+	exercise.OwnerID = &userId
+	exercise.ID, err = e.db.SaveExercise(exercise)
+	if errors.Is(err, db.ErrExerciseExists) {
+		return nil, status.Error(codes.AlreadyExists, "exercise with the name already exists")
+	}
+	if err != nil {
+		log.Printf("error saving exercise: %v", err)
+		return nil, status.Error(codes.Internal, "error saving exercise")
+	}
+	return exercise.ToProto(), nil
+}
+
+func (e *ExerciseAPI) UpdateExercise(ctx context.Context, rq *workout.UpdateExerciseRequest) (*workout.Exercise, error) {
+	if err := rq.Validate(); err != nil {
+		return nil, validationError(func() error { return err.(workout.UpdateExerciseRequestValidationError) })
+	}
+	exercise, err := customExercise(rq.Name, rq.Description, rq.MuscleGroup, rq.Category)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := e.getOwnedExercise(ctx, rq.Id); err != nil {
+		return nil, err
+	}
+	exercise.ID = rq.Id
+	updated, err := e.db.UpdateExercise(exercise)
+	if errors.Is(err, db.ErrExerciseExists) {
+		return nil, status.Error(codes.AlreadyExists, "exercise with the name already exists")
+	}
+	if err != nil {
+		log.Printf("error updating exercise: %v", err)
+		return nil, status.Error(codes.Internal, "error updating exercise")
+	}
+	return updated.ToProto(), nil
+}
+
+func (e *ExerciseAPI) DeleteExercise(ctx context.Context, rq *workout.DeleteExerciseRequest) (*emptypb.Empty, error) {
+	if err := rq.Validate(); err != nil {
+		return nil, validationError(func() error { return err.(workout.DeleteExerciseRequestValidationError) })
+	}
+	if _, err := e.getOwnedExercise(ctx, rq.Id); err != nil {
+		return nil, err
+	}
+	err := e.db.DeleteExercise(rq.Id)
+	if errors.Is(err, db.ErrExerciseInUse) {
+		return nil, status.Error(codes.FailedPrecondition, "exercise is used by workouts or workout history")
+	}
+	if err != nil {
+		log.Printf("error deleting exercise: %v", err)
+		return nil, status.Error(codes.Internal, "error deleting exercise")
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// getOwnedExercise returns custom exercise of the caller, global exercises can't be modified and custom exercises of
+// other users are reported as not found.
+func (e *ExerciseAPI) getOwnedExercise(ctx context.Context, id string) (model.Exercise, error) {
+	userId, err := auth.GetUserId(ctx)
+	if err != nil {
+		return model.Exercise{}, status.Error(codes.Internal, "user id not found in context")
+	}
+	exercise, err := e.db.GetExercise(id)
+	if errors.Is(err, db.ErrExerciseNotFound) || (err == nil && exercise.OwnerID != nil && *exercise.OwnerID != userId) {
+		return model.Exercise{}, status.Error(codes.NotFound, "exercise not found")
+	}
+	if err != nil {
+		log.Printf("error getting exercise: %v", err)
+		return model.Exercise{}, status.Error(codes.Internal, "error getting exercise")
+	}
+	if exercise.OwnerID == nil {
+		return model.Exercise{}, status.Error(codes.PermissionDenied, "global exercises can't be modified")
+	}
+	return exercise, nil
+}
+
+// customExercise trims the name and uppercases muscle group and category, as they are filtered uppercase.
+func customExercise(name, description, muscleGroup, category string) (model.Exercise, error) {
+	exercise := model.Exercise{
+		Name:        strings.TrimSpace(name),
+		Description: strings.TrimSpace(description),
+		MuscleGroup: strings.ToUpper(strings.TrimSpace(muscleGroup)),
+		Category:    strings.ToUpper(strings.TrimSpace(category)),
+	}
+	if exercise.Name == "" || exercise.MuscleGroup == "" || exercise.Category == "" {
+		return model.Exercise{}, status.Error(codes.InvalidArgument, "exercise name, muscle group and category must not be blank")
+	}
+	return exercise, nil
+}
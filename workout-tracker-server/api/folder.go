@@ -0,0 +1,129 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"log"
+	workout "proto/workout/v1/generated"
+	"strings"
+	"workout-tracker-server/auth"
+	"workout-tracker-server/db"
+	"workout-tracker-server/model"
+)
+
+type FolderAPI struct {
+	workout.UnimplementedFolderServiceServer
+	db db.FolderDb
+}
+
+func NewFolderAPI(db db.FolderDb) *FolderAPI {
+	return &FolderAPI{db: db}
+}
+
+func (f *FolderAPI) CreateFolder(ctx context.Context, rq *workout.CreateFolderRequest) (*workout.Folder, error) {
+	if err := rq.Validate(); err != nil {
+		return nil, validationError(func() error { return err.(workout.CreateFolderRequestValidationError) })
+	}
+	userId, err := auth.GetUserId(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "user id not found in context")
+	}
+	name, err := folderName(rq.Name)
+	if err != nil {
+		return nil, err
+	}
+	id, err := f.db.SaveFolder(model.Folder{OwnerID: userId, Name: name})
+	if errors.Is(err, db.ErrFolderExists) {
+		return nil, status.Error(codes.AlreadyExists, "folder with the name already exists")
+	}
+	if err != nil {
+		log.Printf("error saving folder: %v", err)
+		return nil, status.Error(codes.Internal, "error saving folder")
+	}
+	folder, err := f.db.GetFolder(id)
+	if err != nil {
+		log.Printf("error getting folder: %v", err)
+		return nil, status.Error(codes.Internal, "error getting folder")
+	}
+	return folder.ToProto(), nil
+}
+
+func (f *FolderAPI) ListFolders(ctx context.Context, _ *emptypb.Empty) (*workout.ListFoldersResponse, error) {
+	userId, err := auth.GetUserId(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "user id not found in context")
+	}
+	folders, err := f.db.GetFolders(userId)
+	if err != nil {
+		log.Printf("error listing folders: %v", err)
+		return nil, status.Error(codes.Internal, "error listing folders")
+	}
+	var resp workout.ListFoldersResponse
+	for _, folder := range folders {
+		resp.Folders = append(resp.Folders, folder.ToProto())
+	}
+	return &resp, nil
+}
+
+func (f *FolderAPI) RenameFolder(ctx context.Context, rq *workout.RenameFolderRequest) (*workout.Folder, error) {
+	if err := rq.Validate(); err != nil {
+		return nil, validationError(func() error { return err.(workout.RenameFolderRequestValidationError) })
+	}
+	name, err := folderName(rq.Name)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.validateFolderOwner(ctx, rq.Id); err != nil {
+		return nil, err
+	}
+	folder, err := f.db.RenameFolder(rq.Id, name)
+	if errors.Is(err, db.ErrFolderExists) {
+		return nil, status.Error(codes.AlreadyExists, "folder with the name already exists")
+	}
+	if err != nil {
+		log.Printf("error renaming folder: %v", err)
+		return nil, status.Error(codes.Internal, "error renaming folder")
+	}
+	return folder.ToProto(), nil
+}
+
+func (f *FolderAPI) DeleteFolder(ctx context.Context, rq *workout.DeleteFolderRequest) (*emptypb.Empty, error) {
+	if err := rq.Validate(); err != nil {
+		return nil, validationError(func() error { return err.(workout.DeleteFolderRequestValidationError) })
+	}
+	if err := f.validateFolderOwner(ctx, rq.Id); err != nil {
+		return nil, err
+	}
+	if err := f.db.DeleteFolder(rq.Id); err != nil {
+		log.Printf("error deleting folder: %v", err)
+		return nil, status.Error(codes.Internal, "error deleting folder")
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// validateFolderOwner allows access to folder of the caller, folders of other users are reported as not found.
+func (f *FolderAPI) validateFolderOwner(ctx context.Context, id string) error {
+	userId, err := auth.GetUserId(ctx)
+	if err != nil {
+		return status.Error(codes.Internal, "user id not found in context")
+	}
+	folder, err := f.db.GetFolder(id)
+	if errors.Is(err, db.ErrFolderNotFound) || (err == nil && folder.OwnerID != userId) {
+		return status.Error(codes.NotFound, "folder not found")
+	}
+	if err != nil {
+		log.Printf("error getting folder: %v", err)
+		return status.Error(codes.Internal, "error getting folder")
+	}
+	return nil
+}
+
+func folderName(name string) (string, error) {
+	if name = strings.TrimSpace(name); name == "" {
+		return "", status.Error(codes.InvalidArgument, "folder name must not be blank")
+	}
+	return name, nil
+}
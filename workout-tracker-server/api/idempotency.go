@@ -0,0 +1,128 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"log"
+	"time"
+	"workout-tracker-server/auth"
+	"workout-tracker-server/db"
+	"workout-tracker-server/model"
+)
+
+const (
+	// idempotencyKeyHeader is request header with idempotency key forwarded by gateway from Idempotency-Key HTTP header.
+	idempotencyKeyHeader = "idempotency-key"
+	// requestIdField is field of create requests with idempotency key, it's not part of the request hash.
+	requestIdField          = "request_id"
+	maxIdempotencyKeyLength = 255
+)
+
+// runIdempotent runs create once per idempotency key of the caller, key is taken from request id or from metadata.
+// Retry with the same key and request replays stored response unmarshalled into given empty response, retry with
+// different request fails. Failed create releases the key, so the request can be retried, key of request which
+// neither failed nor stored response within the lease is taken over by retry. Without key create just runs.
+func runIdempotent[T proto.Message](ctx context.Context, iDb db.IdempotencyDb, rq proto.Message, requestId *string, response T, create func() (T, error)) (T, error) {
+	var empty T
+	key, err := idempotencyKey(ctx, requestId)
+	if err != nil {
+		return empty, err
+	}
+	if key == "" {
+		return create()
+	}
+	userId, err := auth.GetUserId(ctx)
+	if err != nil {
+		return empty, status.Error(codes.Internal, "user id not found in context")
+	}
+	method, _ := grpc.Method(ctx)
+	hash, err := requestHash(rq)
+	if err != nil {
+		log.Printf("error hashing request: %v", err)
+		return empty, status.Error(codes.Internal, "error processing idempotency key")
+	}
+	//stored timestamps have microsecond precision, key is identified by its creation time
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	ik := model.IdempotencyKey{UserID: userId, Method: method, Key: key, RequestHash: hash, CreatedAt: now}
+	reserved, err := iDb.ReserveIdempotencyKey(ik, now.Add(-model.IdempotencyKeyTTL), now.Add(-model.IdempotencyKeyLease))
+	if err != nil {
+		log.Printf("error reserving idempotency key: %v", err)
+		return empty, status.Error(codes.Internal, "error processing idempotency key")
+	}
+	if !reserved {
+		return replayResponse(ik, iDb, response)
+	}
+	resp, err := create()
+	if err != nil {
+		if err := iDb.DeleteIdempotencyKey(ik); err != nil {
+			log.Printf("error deleting idempotency key: %v", err)
+		}
+		return empty, err
+	}
+	//the request was processed, failure to store its response is only logged
+	if stored, err := proto.Marshal(resp); err != nil {
+		log.Printf("error marshalling idempotent response: %v", err)
+	} else if err := iDb.SaveIdempotentResponse(ik, stored); err != nil {
+		log.Printf("error saving idempotent response: %v", err)
+	}
+	return resp, nil
+}
+
+func replayResponse[T proto.Message](ik model.IdempotencyKey, iDb db.IdempotencyDb, response T) (T, error) {
+	var empty T
+	existing, err := iDb.GetIdempotencyKey(ik.UserID, ik.Method, ik.Key)
+	if errors.Is(err, db.ErrIdempotencyKeyNotFound) {
+		return empty, status.Error(codes.Aborted, "request with the same idempotency key failed, retry")
+	}
+	if err != nil {
+		log.Printf("error getting idempotency key: %v", err)
+		return empty, status.Error(codes.Internal, "error processing idempotency key")
+	}
+	if !bytes.Equal(existing.RequestHash, ik.RequestHash) {
+		return empty, status.Error(codes.FailedPrecondition, "idempotency key was already used with different request")
+	}
+	if existing.Response == nil {
+		return empty, status.Error(codes.Aborted, "request with the same idempotency key is in progress")
+	}
+	if err := proto.Unmarshal(existing.Response, response); err != nil {
+		log.Printf("error unmarshalling idempotent response: %v", err)
+		return empty, status.Error(codes.Internal, "error processing idempotency key")
+	}
+	return response, nil
+}
+
+// idempotencyKey returns request id, or key from metadata when request id is not set.
+func idempotencyKey(ctx context.Context, requestId *string) (string, error) {
+	if requestId != nil {
+		return *requestId, nil
+	}
+	values := metadata.ValueFromIncomingContext(ctx, idempotencyKeyHeader)
+	if len(values) == 0 {
+		return "", nil
+	}
+	if len(values[0]) == 0 || len(values[0]) > maxIdempotencyKeyLength {
+		return "", status.Errorf(codes.InvalidArgument, "idempotency key must be 1-%d characters long", maxIdempotencyKeyLength)
+	}
+	return values[0], nil
+}
+
+// requestHash returns hash of deterministically marshalled request without its request id.
+func requestHash(rq proto.Message) ([]byte, error) {
+	rq = proto.Clone(rq)
+	if field := rq.ProtoReflect().Descriptor().Fields().ByName(requestIdField); field != nil {
+		rq.ProtoReflect().Clear(field)
+	}
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(rq)
+	if err != nil {
+		return nil, err
+	}
+	hash := sha256.Sum256(data)
+	return hash[:], nil
+}
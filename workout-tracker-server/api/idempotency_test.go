@@ -0,0 +1,157 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	workout "proto/workout/v1/generated"
+	"strings"
+	"testing"
+	"workout-tracker-server/auth"
+	"workout-tracker-server/mocks"
+	"workout-tracker-server/model"
+)
+
+func TestRequestHashIgnoresRequestId(t *testing.T) {
+	//given same requests with different keys
+	rq := &workout.CreateWorkoutRequest{Workout: &workout.Workout{Name: "Back Day"}, RequestId: proto.String("key-1")}
+	retry := &workout.CreateWorkoutRequest{Workout: &workout.Workout{Name: "Back Day"}, RequestId: proto.String("key-2")}
+	changed := &workout.CreateWorkoutRequest{Workout: &workout.Workout{Name: "Leg Day"}, RequestId: proto.String("key-1")}
+
+	//when
+	rqHash, err := requestHash(rq)
+	require.NoError(t, err)
+	retryHash, err := requestHash(retry)
+	require.NoError(t, err)
+	changedHash, err := requestHash(changed)
+	require.NoError(t, err)
+
+	//then
+	require.Equal(t, rqHash, retryHash)
+	require.NotEqual(t, rqHash, changedHash)
+	require.Equal(t, "key-1", rq.GetRequestId(), "request must not be modified")
+}
+
+func TestIdempotencyKey(t *testing.T) {
+	withHeader := func(key string) context.Context {
+		return metadata.NewIncomingContext(context.Background(), metadata.Pairs(idempotencyKeyHeader, key))
+	}
+	testData := []struct {
+		name        string
+		ctx         context.Context
+		requestId   *string
+		expectedKey string
+		expectedErr codes.Code
+	}{
+		{name: "RequestId", ctx: withHeader("header"), requestId: proto.String("field"), expectedKey: "field"},
+		{name: "Header", ctx: withHeader("header"), expectedKey: "header"},
+		{name: "None", ctx: context.Background(), expectedKey: ""},
+		{name: "TooLongHeader", ctx: withHeader(strings.Repeat("k", maxIdempotencyKeyLength+1)), expectedErr: codes.InvalidArgument},
+	}
+	for _, tCase := range testData {
+		t.Run(tCase.name, func(t *testing.T) {
+			//when
+			key, err := idempotencyKey(tCase.ctx, tCase.requestId)
+
+			//then
+			if tCase.expectedErr != codes.OK {
+				require.Equal(t, tCase.expectedErr, status.Code(err))
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tCase.expectedKey, key)
+		})
+	}
+}
+
+func TestRunIdempotentReplaysStoredResponse(t *testing.T) {
+	//given key with stored response of the same request
+	iDb := mocks.NewIdempotencyDb(t)
+	rq := &workout.CreateWorkoutRequest{Workout: &workout.Workout{Name: "Back Day"}, RequestId: proto.String("key-1")}
+	hash, err := requestHash(rq)
+	require.NoError(t, err)
+	stored, err := proto.Marshal(&workout.CreateWorkoutResponse{Id: clientWorkoutId})
+	require.NoError(t, err)
+	iDb.EXPECT().ReserveIdempotencyKey(mock.Anything, mock.Anything, mock.Anything).Return(false, nil).Once()
+	iDb.EXPECT().GetIdempotencyKey(clientTestUserId, "", "key-1").
+		Return(model.IdempotencyKey{UserID: clientTestUserId, Key: "key-1", RequestHash: hash, Response: stored}, nil).Once()
+
+	//when
+	resp, err := runIdempotent(idempotencyTestContext(), iDb, rq, rq.RequestId, &workout.CreateWorkoutResponse{}, func() (*workout.CreateWorkoutResponse, error) {
+		t.Fatal("create must not run for replayed request")
+		return nil, nil
+	})
+
+	//then
+	require.NoError(t, err)
+	require.Equal(t, clientWorkoutId, resp.Id)
+}
+
+func TestRunIdempotentRejectsDifferentRequest(t *testing.T) {
+	//given key used with other request
+	iDb := mocks.NewIdempotencyDb(t)
+	rq := &workout.CreateWorkoutRequest{Workout: &workout.Workout{Name: "Back Day"}, RequestId: proto.String("key-1")}
+	iDb.EXPECT().ReserveIdempotencyKey(mock.Anything, mock.Anything, mock.Anything).Return(false, nil).Once()
+	iDb.EXPECT().GetIdempotencyKey(clientTestUserId, "", "key-1").
+		Return(model.IdempotencyKey{UserID: clientTestUserId, Key: "key-1", RequestHash: []byte("other")}, nil).Once()
+
+	//when
+	resp, err := runIdempotent(idempotencyTestContext(), iDb, rq, rq.RequestId, &workout.CreateWorkoutResponse{}, func() (*workout.CreateWorkoutResponse, error) {
+		t.Fatal("create must not run for different request")
+		return nil, nil
+	})
+
+	//then
+	require.Nil(t, resp)
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+}
+
+func TestRunIdempotentReleasesKeyOnError(t *testing.T) {
+	//given
+	iDb := mocks.NewIdempotencyDb(t)
+	rq := &workout.CreateWorkoutRequest{Workout: &workout.Workout{Name: "Back Day"}, RequestId: proto.String("key-1")}
+	isKey := mock.MatchedBy(func(ik model.IdempotencyKey) bool {
+		return ik.UserID == clientTestUserId && ik.Key == "key-1"
+	})
+	iDb.EXPECT().ReserveIdempotencyKey(isKey, mock.Anything, mock.Anything).Return(true, nil).Once()
+	iDb.EXPECT().DeleteIdempotencyKey(isKey).Return(nil).Once()
+	createErr := status.Error(codes.Internal, "error creating workout")
+
+	//when
+	resp, err := runIdempotent(idempotencyTestContext(), iDb, rq, rq.RequestId, &workout.CreateWorkoutResponse{}, func() (*workout.CreateWorkoutResponse, error) {
+		return nil, createErr
+	})
+
+	//then
+	require.Nil(t, resp)
+	require.ErrorIs(t, err, createErr)
+}
+
+func TestRunIdempotentStoresResponse(t *testing.T) {
+	//given
+	iDb := mocks.NewIdempotencyDb(t)
+	rq := &workout.CreateWorkoutRequest{Workout: &workout.Workout{Name: "Back Day"}, RequestId: proto.String("key-1")}
+	iDb.EXPECT().ReserveIdempotencyKey(mock.Anything, mock.Anything, mock.Anything).Return(true, nil).Once()
+	iDb.EXPECT().SaveIdempotentResponse(mock.Anything, mock.MatchedBy(func(stored []byte) bool {
+		var resp workout.CreateWorkoutResponse
+		return proto.Unmarshal(stored, &resp) == nil && resp.Id == clientWorkoutId
+	})).Return(fmt.Errorf("connection lost")).Once()
+
+	//when
+	resp, err := runIdempotent(idempotencyTestContext(), iDb, rq, rq.RequestId, &workout.CreateWorkoutResponse{}, func() (*workout.CreateWorkoutResponse, error) {
+		return &workout.CreateWorkoutResponse{Id: clientWorkoutId}, nil
+	})
+
+	//then failure to store the response doesn't fail processed request
+	require.NoError(t, err)
+	require.Equal(t, clientWorkoutId, resp.Id)
+}
+
+func idempotencyTestContext() context.Context {
+	return auth.WithUserId(context.Background(), clientTestUserId)
+}
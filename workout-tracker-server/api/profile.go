@@ -0,0 +1,98 @@
+package api
+
+import (
+	"context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"log"
+	workout "proto/workout/v1/generated"
+	"slices"
+	"workout-tracker-server/auth"
+	"workout-tracker-server/db"
+	"workout-tracker-server/model"
+)
+
+type ProfileAPI struct {
+	workout.UnimplementedProfileServiceServer
+	db db.ProfileDb
+}
+
+func NewProfileAPI(db db.ProfileDb) *ProfileAPI {
+	return &ProfileAPI{db: db}
+}
+
+func (p *ProfileAPI) GetProfile(ctx context.Context, _ *emptypb.Empty) (*workout.Profile, error) {
+	userId, err := auth.GetUserId(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "user id not found in context")
+	}
+	profile, err := p.db.GetProfile(userId)
+	if err != nil {
+		log.Printf("error getting profile: %v", err)
+		return nil, status.Error(codes.Internal, "error getting profile")
+	}
+	return profile.ToProto(), nil
+}
+
+func (p *ProfileAPI) UpdateProfile(ctx context.Context, rq *workout.UpdateProfileRequest) (*emptypb.Empty, error) {
+	if err := rq.Validate(); err != nil {
+		return nil, validationError(func() error { return err.(workout.UpdateProfileRequestValidationError).Cause() })
+	}
+	userId, err := auth.GetUserId(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "user id not found in context")
+	}
+	profile, err := p.db.GetProfile(userId)
+	if err != nil {
+		log.Printf("error getting profile: %v", err)
+		return nil, status.Error(codes.Internal, "error getting profile")
+	}
+	if err := applyProfileUpdate(ctx, &profile, rq.GetProfile(), rq.GetUpdateMask().GetPaths()); err != nil {
+		return nil, err
+	}
+	if err := p.db.SaveProfile(profile); err != nil {
+		log.Printf("error saving profile: %v", err)
+		return nil, status.Error(codes.Internal, "error saving profile")
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// applyProfileUpdate copies fields listed in paths from update into profile and validates the result.
+func applyProfileUpdate(ctx context.Context, profile *model.Profile, update *workout.Profile, paths []string) error {
+	for _, path := range paths {
+		switch path {
+		case "reminder_lead_time":
+			profile.ReminderLeadTime = update.GetReminderLeadTime().AsDuration()
+		case "reminder_channels":
+			profile.ReminderChannels = nil
+			for _, channel := range update.GetReminderChannels() {
+				profile.ReminderChannels = append(profile.ReminderChannels, model.ReminderChannelFromProto(channel))
+			}
+		case "webhook_url":
+			if update.WebhookUrl != nil {
+				if err := model.ValidateWebhookURL(ctx, *update.WebhookUrl); err != nil {
+					return status.Error(codes.InvalidArgument, err.Error())
+				}
+			}
+			profile.WebhookURL = update.WebhookUrl
+		case "default_rest":
+			profile.RestDefaults.Rest = model.DefaultRest
+			if update.DefaultRest != nil {
+				profile.RestDefaults.Rest = update.DefaultRest.AsDuration()
+			}
+		case "warm_up_rest":
+			profile.RestDefaults.WarmUpRest = nil
+			if update.WarmUpRest != nil {
+				warmUpRest := update.WarmUpRest.AsDuration()
+				profile.RestDefaults.WarmUpRest = &warmUpRest
+			}
+		default:
+			return status.Errorf(codes.InvalidArgument, "unsupported update mask path: %s", path)
+		}
+	}
+	if slices.Contains(profile.ReminderChannels, model.ReminderChannelWebhook) && profile.WebhookURL == nil {
+		return status.Error(codes.InvalidArgument, "webhook_url is required for webhook reminder channel")
+	}
+	return nil
+}
@@ -0,0 +1,269 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"log"
+	workout "proto/workout/v1/generated"
+	"time"
+	"workout-tracker-server/auth"
+	"workout-tracker-server/db"
+	"workout-tracker-server/model"
+)
+
+type ProgramAPI struct {
+	workout.UnimplementedProgramServiceServer
+	db  db.ProgramDb
+	wDb db.WorkoutDb
+}
+
+func NewProgramAPI(db db.ProgramDb, wDb db.WorkoutDb) *ProgramAPI {
+	return &ProgramAPI{db: db, wDb: wDb}
+}
+
+func (p *ProgramAPI) CreateProgram(ctx context.Context, rq *workout.CreateProgramRequest) (*workout.CreateProgramResponse, error) {
+	if err := rq.Validate(); err != nil {
+		return nil, validationError(func() error { return err.(workout.CreateProgramRequestValidationError).Cause() })
+	}
+	program := model.FromProgramProto(rq.Program)
+	if err := validateProgramDays(program); err != nil {
+		return nil, err
+	}
+	userId, err := auth.GetUserId(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "user id not found in context")
+	}
+	program.OwnerID = userId
+	for _, workoutId := range program.WorkoutIDs() {
+		if err := p.validateWorkoutOwner(userId, workoutId); err != nil {
+			return nil, err
+		}
+	}
+	id, err := p.db.SaveProgram(program)
+	if errors.Is(err, db.ErrWorkoutNotFound) {
+		return nil, status.Error(codes.NotFound, "workout not found")
+	}
+	if err != nil {
+		log.Printf("error saving program: %v", err)
+		return nil, status.Error(codes.Internal, "error saving program")
+	}
+	return &workout.CreateProgramResponse{
+		Id: id,
+	}, nil
+}
+
+// validateProgramDays checks that each day is used at most once within a week.
+func validateProgramDays(program model.Program) error {
+	for i, week := range program.Weeks {
+		days := make(map[int32]bool, len(week.Days))
+		for _, day := range week.Days {
+			if days[day.Day] {
+				return status.Errorf(codes.InvalidArgument, "duplicate day %d in week %d", day.Day, i+1)
+			}
+			days[day.Day] = true
+		}
+	}
+	return nil
+}
+
+func (p *ProgramAPI) validateWorkoutOwner(userId, workoutId string) error {
+	isOwner, err := p.wDb.IsWorkoutOwner(workoutId, userId)
+	if errors.Is(err, db.ErrWorkoutNotFound) {
+		return status.Error(codes.NotFound, "workout not found")
+	}
+	if err != nil {
+		log.Printf("error getting workout data: %v", err)
+		return status.Error(codes.Internal, "error getting workout data")
+	}
+	if !isOwner {
+		return status.Error(codes.PermissionDenied, "access forbidden")
+	}
+	return nil
+}
+
+func (p *ProgramAPI) GetProgram(ctx context.Context, rq *workout.GetProgramRequest) (*workout.Program, error) {
+	if err := rq.Validate(); err != nil {
+		return nil, validationError(func() error { return err.(workout.GetProgramRequestValidationError) })
+	}
+	program, err := p.getValidatedProgram(ctx, rq.Id)
+	if err != nil {
+		return nil, err
+	}
+	return program.ToProto(), nil
+}
+
+func (p *ProgramAPI) ListPrograms(ctx context.Context, _ *emptypb.Empty) (*workout.ListProgramsResponse, error) {
+	userId, err := auth.GetUserId(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "user id not found in context")
+	}
+	programs, err := p.db.GetPrograms(userId)
+	if err != nil {
+		log.Printf("error listing programs: %v", err)
+		return nil, status.Error(codes.Internal, "error listing programs")
+	}
+	var resp workout.ListProgramsResponse
+	for _, program := range programs {
+		resp.Programs = append(resp.Programs, program.ToProto())
+	}
+	return &resp, nil
+}
+
+func (p *ProgramAPI) DeleteProgram(ctx context.Context, rq *workout.DeleteProgramRequest) (*emptypb.Empty, error) {
+	if err := rq.Validate(); err != nil {
+		return nil, validationError(func() error { return err.(workout.DeleteProgramRequestValidationError) })
+	}
+	if _, err := p.getValidatedProgram(ctx, rq.Id); err != nil {
+		return nil, err
+	}
+	if err := p.db.DeleteProgram(rq.Id); err != nil {
+		if errors.Is(err, db.ErrProgramNotFound) {
+			return nil, status.Error(codes.NotFound, "program not found")
+		}
+		log.Printf("error deleting program: %v", err)
+		return nil, status.Error(codes.Internal, "error deleting program")
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// EnrollInProgram schedules all program sessions from the start date as workout schedules.
+func (p *ProgramAPI) EnrollInProgram(ctx context.Context, rq *workout.EnrollInProgramRequest) (*workout.EnrollInProgramResponse, error) {
+	if err := rq.Validate(); err != nil {
+		return nil, validationError(func() error { return err.(workout.EnrollInProgramRequestValidationError) })
+	}
+	loc, err := loadLocation(rq.GetTimezone())
+	if err != nil {
+		return nil, err
+	}
+	program, err := p.getValidatedProgram(ctx, rq.ProgramId)
+	if err != nil {
+		return nil, err
+	}
+	enrollment := model.ProgramEnrollment{
+		ProgramID: program.ID,
+		OwnerID:   program.OwnerID,
+		StartDate: rq.StartDate.AsTime(),
+		Timezone:  loc.String(),
+		Sessions:  program.Sessions(program.OwnerID, rq.StartDate.AsTime(), loc),
+	}
+	id, err := p.db.SaveProgramEnrollment(enrollment)
+	if errors.Is(err, db.ErrProgramWorkoutDeleted) {
+		return nil, status.Error(codes.FailedPrecondition, "program references deleted workout")
+	}
+	if err != nil {
+		log.Printf("error saving program enrollment: %v", err)
+		return nil, status.Error(codes.Internal, "error enrolling in program")
+	}
+	return &workout.EnrollInProgramResponse{
+		Id: id,
+	}, nil
+}
+
+func (p *ProgramAPI) GetProgramEnrollment(ctx context.Context, rq *workout.GetProgramEnrollmentRequest) (*workout.ProgramEnrollment, error) {
+	if err := rq.Validate(); err != nil {
+		return nil, validationError(func() error { return err.(workout.GetProgramEnrollmentRequestValidationError) })
+	}
+	enrollment, err := p.getValidatedEnrollment(ctx, rq.Id)
+	if err != nil {
+		return nil, err
+	}
+	return p.programEnrollmentToProto(enrollment)
+}
+
+// AdjustProgramEnrollment handles missed sessions of the enrollment, either by skipping them or by moving
+// the rest of the program.
+func (p *ProgramAPI) AdjustProgramEnrollment(ctx context.Context, rq *workout.AdjustProgramEnrollmentRequest) (*workout.ProgramEnrollment, error) {
+	if err := rq.Validate(); err != nil {
+		return nil, validationError(func() error { return err.(workout.AdjustProgramEnrollmentRequestValidationError) })
+	}
+	if rq.Policy == workout.MissedSessionPolicy_MISSED_SESSION_POLICY_RESCHEDULE && rq.ResumeAt == nil {
+		return nil, status.Error(codes.InvalidArgument, "resume_at is required for reschedule policy")
+	}
+	enrollment, err := p.getValidatedEnrollment(ctx, rq.Id)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	if rq.Policy == workout.MissedSessionPolicy_MISSED_SESSION_POLICY_RESCHEDULE {
+		var moved []model.WorkoutSchedule
+		moved, err = enrollment.Reschedule(rq.ResumeAt.AsTime(), now)
+		if err == nil {
+			err = p.db.RescheduleProgramSessions(moved)
+		}
+	} else {
+		var ids []string
+		for _, session := range enrollment.MissedSessions(now) {
+			ids = append(ids, session.Schedule.ID)
+		}
+		err = p.db.SkipProgramSessions(ids)
+	}
+	if err != nil {
+		log.Printf("error adjusting program enrollment: %v", err)
+		return nil, status.Error(codes.Internal, "error adjusting program enrollment")
+	}
+	if enrollment, err = p.db.GetProgramEnrollment(rq.Id); err != nil {
+		log.Printf("error getting program enrollment: %v", err)
+		return nil, status.Error(codes.Internal, "error getting program enrollment")
+	}
+	return p.programEnrollmentToProto(enrollment)
+}
+
+// programEnrollmentToProto converts enrollment with exercise targets of its sessions scaled by program progression.
+func (p *ProgramAPI) programEnrollmentToProto(enrollment model.ProgramEnrollment) (*workout.ProgramEnrollment, error) {
+	program, err := p.db.GetProgram(enrollment.ProgramID)
+	if err != nil {
+		log.Printf("error getting program: %v", err)
+		return nil, status.Error(codes.Internal, "error getting program enrollment")
+	}
+	var workoutIds []string
+	for _, session := range enrollment.Sessions {
+		workoutIds = append(workoutIds, session.Schedule.WorkoutID)
+	}
+	summaries, err := p.wDb.GetWorkoutSummaries(workoutIds)
+	if err != nil {
+		log.Printf("error getting workout summaries: %v", err)
+		return nil, status.Error(codes.Internal, "error getting program enrollment")
+	}
+	return enrollment.ToProto(time.Now().UTC(), program, summaries), nil
+}
+
+func (p *ProgramAPI) getValidatedProgram(ctx context.Context, programId string) (model.Program, error) {
+	userId, err := auth.GetUserId(ctx)
+	if err != nil {
+		return model.Program{}, status.Error(codes.Internal, "user id not found in context")
+	}
+	program, err := p.db.GetProgram(programId)
+	if errors.Is(err, db.ErrProgramNotFound) {
+		return model.Program{}, status.Error(codes.NotFound, "program not found")
+	}
+	if err != nil {
+		log.Printf("error getting program: %v", err)
+		return model.Program{}, status.Error(codes.Internal, "error getting program")
+	}
+	if program.OwnerID != userId {
+		return model.Program{}, status.Error(codes.PermissionDenied, "access forbidden")
+	}
+	return program, nil
+}
+
+func (p *ProgramAPI) getValidatedEnrollment(ctx context.Context, enrollmentId string) (model.ProgramEnrollment, error) {
+	userId, err := auth.GetUserId(ctx)
+	if err != nil {
+		return model.ProgramEnrollment{}, status.Error(codes.Internal, "user id not found in context")
+	}
+	enrollment, err := p.db.GetProgramEnrollment(enrollmentId)
+	if errors.Is(err, db.ErrProgramEnrollmentNotFound) {
+		return model.ProgramEnrollment{}, status.Error(codes.NotFound, "program enrollment not found")
+	}
+	if err != nil {
+		log.Printf("error getting program enrollment: %v", err)
+		return model.ProgramEnrollment{}, status.Error(codes.Internal, "error getting program enrollment")
+	}
+	if enrollment.OwnerID != userId {
+		return model.ProgramEnrollment{}, status.Error(codes.PermissionDenied, "access forbidden")
+	}
+	return enrollment, nil
+}
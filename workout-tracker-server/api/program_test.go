@@ -0,0 +1,90 @@
+package api
+
+import (
+	"context"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"net"
+	workout "proto/workout/v1/generated"
+	"testing"
+	"workout-tracker-server/mocks"
+)
+
+type ProgramAPISuite struct {
+	suite.Suite
+	pDbMock       *mocks.ProgramDb
+	wDbMock       *mocks.WorkoutDb
+	programClient workout.ProgramServiceClient
+	cleanup       func()
+}
+
+func TestProgramAPISuite(t *testing.T) {
+	suite.Run(t, new(ProgramAPISuite))
+}
+
+func (s *ProgramAPISuite) SetupSuite() {
+	s.pDbMock = mocks.NewProgramDb(s.T())
+	s.wDbMock = mocks.NewWorkoutDb(s.T())
+	lis := bufconn.Listen(1024 * 1024)
+
+	server := grpc.NewServer()
+	workout.RegisterProgramServiceServer(server, NewProgramAPI(s.pDbMock, s.wDbMock))
+	go func() {
+		if err := server.Serve(lis); err != nil {
+			s.T().Errorf("Server exited with error: %v", err)
+		}
+	}()
+	client, err := grpc.NewClient("passthrough://",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return lis.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	s.Require().NoError(err)
+	s.programClient = workout.NewProgramServiceClient(client)
+
+	s.cleanup = func() {
+		client.Close()
+		server.Stop()
+	}
+}
+
+func (s *ProgramAPISuite) TearDownSuite() {
+	s.cleanup()
+}
+
+func (s *ProgramAPISuite) TestCreateProgramWithoutWeeks() {
+	resp, err := s.programClient.CreateProgram(context.Background(), &workout.CreateProgramRequest{
+		Program: &workout.Program{Name: "PRG"},
+	})
+
+	s.Require().Nil(resp)
+	s.Require().Equal(codes.InvalidArgument, status.Code(err))
+}
+
+func (s *ProgramAPISuite) TestCreateProgramDuplicateDay() {
+	workoutId := "4f97a755-aeee-4d23-a518-0f8c82680014"
+	resp, err := s.programClient.CreateProgram(context.Background(), &workout.CreateProgramRequest{
+		Program: &workout.Program{Name: "PRG", Weeks: []*workout.ProgramWeek{
+			{Days: []*workout.ProgramDay{{WorkoutId: workoutId, Day: 1}}},
+			{Days: []*workout.ProgramDay{{WorkoutId: workoutId, Day: 2}, {WorkoutId: workoutId, Day: 2}}},
+		}},
+	})
+
+	s.Require().Nil(resp)
+	s.Require().Equal(codes.InvalidArgument, status.Code(err))
+	s.Require().Equal("duplicate day 2 in week 2", status.Convert(err).Message())
+}
+
+func (s *ProgramAPISuite) TestAdjustProgramEnrollmentRescheduleWithoutResumeAt() {
+	resp, err := s.programClient.AdjustProgramEnrollment(context.Background(), &workout.AdjustProgramEnrollmentRequest{
+		Id:     "4f97a755-aeee-4d23-a518-0f8c82680014",
+		Policy: workout.MissedSessionPolicy_MISSED_SESSION_POLICY_RESCHEDULE,
+	})
+
+	s.Require().Nil(resp)
+	s.Require().Equal(codes.InvalidArgument, status.Code(err))
+	s.Require().Equal("resume_at is required for reschedule policy", status.Convert(err).Message())
+}
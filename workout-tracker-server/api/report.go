@@ -0,0 +1,126 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"google.golang.org/genproto/googleapis/api/httpbody"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"log"
+	workout "proto/workout/v1/generated"
+	"slices"
+	"strconv"
+	"time"
+	"workout-tracker-server/db"
+	"workout-tracker-server/model"
+)
+
+type ReportAPI struct {
+	workout.UnimplementedReportServiceServer
+	db  db.ReportDb
+	cDb db.CoachingDb
+}
+
+func NewReportAPI(db db.ReportDb, cDb db.CoachingDb) *ReportAPI {
+	return &ReportAPI{db: db, cDb: cDb}
+}
+
+func (r *ReportAPI) GetProgressReport(ctx context.Context, rq *workout.GetProgressReportRequest) (*workout.GetProgressReportResponse, error) {
+	entries, err := r.getProgressReport(ctx, rq)
+	if err != nil {
+		return nil, err
+	}
+	var resp workout.GetProgressReportResponse
+	for _, entry := range entries {
+		resp.Entries = append(resp.Entries, entry.ToProto())
+	}
+	return &resp, nil
+}
+
+func (r *ReportAPI) ExportProgressReport(ctx context.Context, rq *workout.GetProgressReportRequest) (*httpbody.HttpBody, error) {
+	entries, err := r.getProgressReport(ctx, rq)
+	if err != nil {
+		return nil, err
+	}
+	loc, _ := loadLocation(rq.GetTimezone())
+	data, err := progressReportCsv(entries, loc)
+	if err != nil {
+		log.Printf("error writing progress report csv: %v", err)
+		return nil, status.Error(codes.Internal, "error exporting progress report")
+	}
+	return &httpbody.HttpBody{
+		ContentType: "text/csv",
+		Data:        data,
+	}, nil
+}
+
+func (r *ReportAPI) getProgressReport(ctx context.Context, rq *workout.GetProgressReportRequest) ([]model.ProgressReportEntry, error) {
+	if err := rq.Validate(); err != nil {
+		return nil, validationError(func() error { return err.(workout.GetProgressReportRequestValidationError) })
+	}
+	loc, err := loadLocation(rq.GetTimezone())
+	if err != nil {
+		return nil, err
+	}
+	userId, err := getAccountId(ctx, r.cDb, rq.ClientId)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := r.db.GetProgressReport(userId, rq.StartDate.AsTime(), rq.EndDate.AsTime(), model.ReportBucketFromProto(rq.Bucket), loc.String())
+	if err != nil {
+		log.Printf("error getting progress report: %v", err)
+		return nil, status.Error(codes.Internal, "error getting progress report")
+	}
+	return entries, nil
+}
+
+// loadLocation resolves IANA timezone name, empty name resolves to UTC. Server's local timezone is rejected
+// as it differs between deployments.
+func loadLocation(timezone string) (*time.Location, error) {
+	loc, err := time.LoadLocation(timezone)
+	if err != nil || loc == time.Local {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid timezone: %s", timezone)
+	}
+	return loc, nil
+}
+
+// progressReportCsv writes one row per period, muscle groups are spread into separate sets_<group> columns.
+func progressReportCsv(entries []model.ProgressReportEntry, loc *time.Location) ([]byte, error) {
+	var muscleGroups []string
+	for _, entry := range entries {
+		for group := range entry.SetsPerMuscleGroup {
+			if !slices.Contains(muscleGroups, group) {
+				muscleGroups = append(muscleGroups, group)
+			}
+		}
+	}
+	slices.Sort(muscleGroups)
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	header := []string{"period_start", "session_count", "scheduled_count", "completion_rate", "total_volume"}
+	for _, group := range muscleGroups {
+		header = append(header, "sets_"+group)
+	}
+	if err := w.Write(header); err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		record := []string{
+			entry.PeriodStart.In(loc).Format(time.RFC3339),
+			strconv.Itoa(int(entry.SessionCount)),
+			strconv.Itoa(int(entry.ScheduledCount)),
+			strconv.FormatFloat(entry.CompletionRate(), 'f', 2, 64),
+			strconv.FormatFloat(entry.TotalVolume, 'f', 2, 64),
+		}
+		for _, group := range muscleGroups {
+			record = append(record, strconv.Itoa(int(entry.SetsPerMuscleGroup[group])))
+		}
+		if err := w.Write(record); err != nil {
+			return nil, err
+		}
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
@@ -2,20 +2,34 @@ package api
 
 import (
 	"context"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
 	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
 	"net"
 	workout "proto/workout/v1/generated"
 	"testing"
+	"time"
+	"workout-tracker-server/auth"
+	"workout-tracker-server/db"
 	"workout-tracker-server/mocks"
+	"workout-tracker-server/model"
 )
 
+const clientScheduleId = "8d4a6f5e-7b9c-4d1e-8f3a-4b5c6d7e8f9a"
+
 type ScheduleAPISuite struct {
 	suite.Suite
 	wsDbMock *mocks.WorkoutScheduleDb
 	wDbMock  *mocks.WorkoutDb
+	cDbMock  *mocks.CoachingDb
 	wsClient workout.WorkoutScheduleServiceClient
 	cleanup  func()
 }
@@ -27,13 +41,15 @@ func TestScheduleAPISuite(t *testing.T) {
 func (s *ScheduleAPISuite) SetupSuite() {
 	wsDbMock := mocks.NewWorkoutScheduleDb(s.T())
 	wDbMock := mocks.NewWorkoutDb(s.T())
+	cDbMock := mocks.NewCoachingDb(s.T())
 	lis := bufconn.Listen(1024 * 1024)
 
-	closeSrv := setupWorkoutScheduleTestServer(s.T(), lis, wDbMock, wsDbMock)
+	closeSrv := setupWorkoutScheduleTestServer(s.T(), lis, wDbMock, wsDbMock, cDbMock)
 	client, closeCl := setupWorkoutScheduleTestClient(s.T(), lis)
 
 	s.wsDbMock = wsDbMock
 	s.wDbMock = wDbMock
+	s.cDbMock = cDbMock
 	s.wsClient = client
 
 	s.cleanup = func() {
@@ -48,10 +64,14 @@ func (s *ScheduleAPISuite) TearDownSuite() {
 
 func setupWorkoutScheduleTestServer(
 	t *testing.T, listener *bufconn.Listener,
-	wDbMock *mocks.WorkoutDb, wsDbMock *mocks.WorkoutScheduleDb,
+	wDbMock *mocks.WorkoutDb, wsDbMock *mocks.WorkoutScheduleDb, cDbMock *mocks.CoachingDb,
 ) func() {
-	server := grpc.NewServer()
-	workout.RegisterWorkoutScheduleServiceServer(server, NewWorkoutScheduleAPI(wsDbMock, wDbMock))
+	server := grpc.NewServer(grpc.UnaryInterceptor(
+		func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			return handler(auth.WithUserId(ctx, coachTestUserId), req)
+		},
+	))
+	workout.RegisterWorkoutScheduleServiceServer(server, NewWorkoutScheduleAPI(wsDbMock, wDbMock, cDbMock, mocks.NewIdempotencyDb(t)))
 	go func() {
 		if err := server.Serve(listener); err != nil {
 			t.Fatalf("Server exited with error: %v", err)
@@ -72,3 +92,167 @@ func setupWorkoutScheduleTestClient(t *testing.T, listener *bufconn.Listener) (w
 	}
 	return workout.NewWorkoutScheduleServiceClient(client), func() { client.Close() }
 }
+
+func (s *ScheduleAPISuite) TestUpdateWorkoutScheduleUnsupportedPath() {
+	resp, err := s.wsClient.UpdateWorkoutSchedule(context.Background(), &workout.UpdateWorkoutScheduleRequest{
+		Id:              "4f97a755-aeee-4d23-a518-0f8c82680014",
+		WorkoutSchedule: &workout.WorkoutSchedule{WorkoutId: "4f97a755-aeee-4d23-a518-0f8c82680014"},
+		UpdateMask:      &fieldmaskpb.FieldMask{Paths: []string{"workout_id"}},
+	})
+
+	s.Require().Nil(resp)
+	s.assertStatusError(codes.InvalidArgument, "unsupported update mask path: workout_id", err)
+}
+
+func (s *ScheduleAPISuite) TestUpdateWorkoutScheduleInPast() {
+	resp, err := s.wsClient.UpdateWorkoutSchedule(context.Background(), &workout.UpdateWorkoutScheduleRequest{
+		Id:              "4f97a755-aeee-4d23-a518-0f8c82680014",
+		WorkoutSchedule: &workout.WorkoutSchedule{ScheduleAt: timestamppb.New(time.Now().Add(-time.Hour))},
+		UpdateMask:      &fieldmaskpb.FieldMask{Paths: []string{"schedule_at"}},
+	})
+
+	s.Require().Nil(resp)
+	s.assertStatusError(codes.InvalidArgument, "schedule_at must be in the future", err)
+}
+
+func (s *ScheduleAPISuite) TestMarkWorkoutCompleteDuplicateSet() {
+	exerciseId := "4f97a755-aeee-4d23-a518-0f8c82680014"
+	resp, err := s.wsClient.MarkWorkoutComplete(context.Background(), &workout.MarkWorkoutCompleteRequest{
+		Id: "4f97a755-aeee-4d23-a518-0f8c82680014",
+		Results: []*workout.ExerciseSetResult{
+			{ExerciseId: exerciseId, Set: 1, Repetitions: 8},
+			{ExerciseId: exerciseId, Set: 1, Repetitions: 7},
+		},
+	})
+
+	s.Require().Nil(resp)
+	s.assertStatusError(codes.InvalidArgument, "duplicate set 1 of exercise "+exerciseId, err)
+}
+
+func (s *ScheduleAPISuite) TestMarkWorkoutCompleteUnknownExercise() {
+	exerciseId := "9e5b7a6f-8c0d-4e2f-9a4b-5c6d7e8f9a0b"
+	s.wsDbMock.EXPECT().GetWorkoutScheduleOwner(clientScheduleId).Return(coachTestUserId, nil).Once()
+	s.wsDbMock.EXPECT().CompleteWorkoutSchedule(clientScheduleId, coachTestUserId, mock.Anything).Return(db.ErrExerciseNotInWorkout).Once()
+
+	resp, err := s.wsClient.MarkWorkoutComplete(context.Background(), &workout.MarkWorkoutCompleteRequest{
+		Id:      clientScheduleId,
+		Results: []*workout.ExerciseSetResult{{ExerciseId: exerciseId, Set: 1, Repetitions: 8}},
+	})
+
+	s.Require().Nil(resp)
+	s.assertStatusError(codes.InvalidArgument, "results reference exercise which is not part of the workout", err)
+}
+
+func (s *ScheduleAPISuite) TestCoachMarksClientWorkoutComplete() {
+	exerciseId := "9e5b7a6f-8c0d-4e2f-9a4b-5c6d7e8f9a0b"
+	s.wsDbMock.EXPECT().GetWorkoutScheduleOwner(clientScheduleId).Return(clientTestUserId, nil).Once()
+	s.cDbMock.EXPECT().IsCoachOf(coachTestUserId, clientTestUserId).Return(true, nil).Once()
+	s.wsDbMock.EXPECT().CompleteWorkoutSchedule(clientScheduleId, clientTestUserId, mock.Anything).Return(nil).Once()
+
+	resp, err := s.wsClient.MarkWorkoutComplete(context.Background(), &workout.MarkWorkoutCompleteRequest{
+		Id:      clientScheduleId,
+		Results: []*workout.ExerciseSetResult{{ExerciseId: exerciseId, Set: 1, Repetitions: 8}},
+	})
+
+	s.Require().NoError(err)
+	s.Require().NotNil(resp)
+}
+
+func (s *ScheduleAPISuite) TestListWorkoutSchedulesInvalidPageToken() {
+	resp, err := s.wsClient.ListWorkoutSchedules(context.Background(), &workout.ListWorkoutSchedulesRequest{PageToken: "invalid"})
+
+	s.Require().Nil(resp)
+	s.assertStatusError(codes.InvalidArgument, "invalid page token", err)
+}
+
+func (s *ScheduleAPISuite) TestGetWorkoutScheduleReportWithoutRange() {
+	resp, err := s.wsClient.GetWorkoutScheduleReport(context.Background(), &workout.GetWorkoutScheduleReportRequest{})
+
+	s.Require().Nil(resp)
+	s.assertStatusError(codes.InvalidArgument, "start_date and end_date or local_date_range is required", err)
+}
+
+func (s *ScheduleAPISuite) TestGetWorkoutScheduleReportInvalidTimezone() {
+	resp, err := s.wsClient.GetWorkoutScheduleReport(context.Background(), &workout.GetWorkoutScheduleReportRequest{
+		LocalDateRange: &workout.LocalDateRange{StartDate: "2026-03-01", EndDate: "2026-03-31", Timezone: proto.String("Mars/Olympus")},
+	})
+
+	s.Require().Nil(resp)
+	s.assertStatusError(codes.InvalidArgument, "invalid timezone: Mars/Olympus", err)
+}
+
+func (s *ScheduleAPISuite) TestCoachCancelsClientSchedule() {
+	s.wsDbMock.EXPECT().GetWorkoutScheduleOwner(clientScheduleId).Return(clientTestUserId, nil).Once()
+	s.cDbMock.EXPECT().IsCoachOf(coachTestUserId, clientTestUserId).Return(true, nil).Once()
+	s.wsDbMock.EXPECT().CancelWorkoutSchedule(clientScheduleId, (*string)(nil)).Return(nil).Once()
+
+	resp, err := s.wsClient.CancelWorkoutSchedule(context.Background(), &workout.CancelWorkoutScheduleRequest{Id: clientScheduleId})
+
+	s.Require().NoError(err)
+	s.Require().NotNil(resp)
+}
+
+func (s *ScheduleAPISuite) TestDeleteScheduleOfOtherUser() {
+	s.wsDbMock.EXPECT().GetWorkoutScheduleOwner(clientScheduleId).Return(clientTestUserId, nil).Once()
+	s.cDbMock.EXPECT().IsCoachOf(coachTestUserId, clientTestUserId).Return(false, nil).Once()
+
+	resp, err := s.wsClient.DeleteWorkoutSchedule(context.Background(), &workout.DeleteWorkoutScheduleRequest{Id: clientScheduleId})
+
+	s.Require().Nil(resp)
+	s.assertStatusError(codes.PermissionDenied, "access forbidden", err)
+}
+
+func (s *ScheduleAPISuite) TestListWorkoutSchedulesOfNotCoachedClient() {
+	s.cDbMock.EXPECT().IsCoachOf(coachTestUserId, clientTestUserId).Return(false, nil).Once()
+
+	resp, err := s.wsClient.ListWorkoutSchedules(context.Background(), &workout.ListWorkoutSchedulesRequest{ClientId: proto.String(clientTestUserId)})
+
+	s.Require().Nil(resp)
+	s.assertStatusError(codes.PermissionDenied, "access forbidden", err)
+}
+
+func TestReportTimeRangeFromLocalDates(t *testing.T) {
+	//given
+	rq := &workout.GetWorkoutScheduleReportRequest{
+		LocalDateRange: &workout.LocalDateRange{StartDate: "2026-03-29", EndDate: "2026-03-29", Timezone: proto.String("Europe/Warsaw")},
+	}
+
+	//when
+	from, to, err := reportTimeRange(rq)
+
+	//then day of DST transition is 23 hours long
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2026, 3, 28, 23, 0, 0, 0, time.UTC), from)
+	require.Equal(t, time.Date(2026, 3, 29, 22, 0, 0, 0, time.UTC).Add(-time.Nanosecond), to)
+}
+
+func (s *ScheduleAPISuite) assertStatusError(code codes.Code, message string, err error) {
+	s.Require().NotNil(err, "Error is nil")
+	st, ok := status.FromError(err)
+	s.Require().True(ok, "Error is not a status error")
+	s.Require().Equal(code, st.Code(), "Error code is not as expected - got: %v, expected: %v", st.Code(), code.String())
+	s.Require().Equal(message, st.Message(), "Error message is incorrect")
+}
+
+func (s *ScheduleAPISuite) TestScheduleRecurringWorkoutReturnsSeriesId() {
+	seriesId := "9e5b7a6f-8c0d-4e2f-9a4b-5c6d7e8f9a0b"
+	s.wDbMock.EXPECT().GetWorkoutOwner(clientWorkoutId).Return(clientTestUserId, nil).Once()
+	s.cDbMock.EXPECT().IsCoachOf(coachTestUserId, clientTestUserId).Return(true, nil).Once()
+	s.wsDbMock.EXPECT().GetCompletedWorkoutSchedules(clientTestUserId, mock.Anything).Return(nil, nil).Once()
+	s.wsDbMock.EXPECT().GetWorkoutSchedulesBetweenDates(clientTestUserId, mock.Anything, mock.Anything).Return(nil, nil).Once()
+	s.wsDbMock.EXPECT().GetWorkoutScheduleSeriesStartedBefore(clientTestUserId, mock.Anything).Return(nil, nil).Once()
+	s.wDbMock.EXPECT().GetWorkoutSummaries(mock.Anything).Return(map[string]model.WorkoutSummary{}, nil).Once()
+	s.wsDbMock.EXPECT().SaveWorkoutScheduleSeries(mock.Anything).Return(seriesId, nil).Once()
+
+	resp, err := s.wsClient.ScheduleWorkout(context.Background(), &workout.ScheduleWorkoutRequest{
+		WorkoutSchedule: &workout.WorkoutSchedule{
+			WorkoutId:  clientWorkoutId,
+			ScheduleAt: timestamppb.New(time.Now().Add(24 * time.Hour)),
+			Recurrence: proto.String("FREQ=WEEKLY;BYDAY=MO"),
+		},
+	})
+
+	s.Require().NoError(err)
+	s.Require().Equal(seriesId, resp.SeriesId)
+	s.Require().Empty(resp.Id)
+}
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"log"
+	workout "proto/workout/v1/generated"
+	"strings"
+	"workout-tracker-server/auth"
+	"workout-tracker-server/db"
+	"workout-tracker-server/model"
+)
+
+type SearchAPI struct {
+	workout.UnimplementedSearchServiceServer
+	db db.SearchDb
+}
+
+func NewSearchAPI(db db.SearchDb) *SearchAPI {
+	return &SearchAPI{db: db}
+}
+
+func (s *SearchAPI) Search(ctx context.Context, rq *workout.SearchRequest) (*workout.SearchResponse, error) {
+	if err := rq.Validate(); err != nil {
+		return nil, validationError(func() error { return err.(workout.SearchRequestValidationError) })
+	}
+	query := strings.TrimSpace(rq.Query)
+	if query == "" {
+		return nil, status.Error(codes.InvalidArgument, "search query must not be blank")
+	}
+	userId, err := auth.GetUserId(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "user id not found in context")
+	}
+	pageSize := int(rq.PageSize)
+	if pageSize == 0 {
+		pageSize = defaultPageSize
+	}
+	filter := model.SearchFilter{Query: query, Limit: pageSize}
+	for _, t := range rq.Types {
+		filter.Types = append(filter.Types, model.SearchResultTypeFromProto(t))
+	}
+	results, err := s.db.Search(userId, filter)
+	if err != nil {
+		log.Printf("error searching: %v", err)
+		return nil, status.Error(codes.Internal, "error searching")
+	}
+	var resp workout.SearchResponse
+	for _, result := range results {
+		resp.Results = append(resp.Results, result.ToProto())
+	}
+	return &resp, nil
+}
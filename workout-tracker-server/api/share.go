@@ -0,0 +1,228 @@
+package api
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"log"
+	workout "proto/workout/v1/generated"
+	"slices"
+	"workout-tracker-server/auth"
+	"workout-tracker-server/db"
+	"workout-tracker-server/model"
+)
+
+type WorkoutShareAPI struct {
+	workout.UnimplementedWorkoutShareServiceServer
+	db  db.WorkoutShareDb
+	wDb db.WorkoutDb
+}
+
+func NewWorkoutShareAPI(db db.WorkoutShareDb, wDb db.WorkoutDb) *WorkoutShareAPI {
+	return &WorkoutShareAPI{db: db, wDb: wDb}
+}
+
+// CreateWorkoutShare shares owner's workout with user of given email, or creates link share when email is omitted.
+// Token of link share is returned only here.
+func (w *WorkoutShareAPI) CreateWorkoutShare(ctx context.Context, rq *workout.CreateWorkoutShareRequest) (*workout.WorkoutShare, error) {
+	if err := rq.Validate(); err != nil {
+		return nil, validationError(func() error { return err.(workout.CreateWorkoutShareRequestValidationError) })
+	}
+	userId, err := w.getValidatedWorkoutOwnerId(ctx, rq.WorkoutId)
+	if err != nil {
+		return nil, err
+	}
+	share := model.WorkoutShare{
+		WorkoutID:      rq.WorkoutId,
+		OwnerID:        userId,
+		RecipientEmail: rq.RecipientEmail,
+		Permission:     model.SharePermissionFromProto(rq.Permission),
+	}
+	var token string
+	var tokenHash *string
+	if share.RecipientEmail == nil {
+		if token, err = newSecretToken(); err != nil {
+			log.Printf("error generating workout share token: %v", err)
+			return nil, status.Error(codes.Internal, "error creating workout share")
+		}
+		hash := hashSecretToken(token)
+		tokenHash = &hash
+	}
+	share, err = w.db.SaveWorkoutShare(share, tokenHash)
+	if errors.Is(err, db.ErrShareRecipientNotFound) {
+		return nil, status.Error(codes.NotFound, "recipient not found")
+	}
+	if err != nil {
+		log.Printf("error saving workout share: %v", err)
+		return nil, status.Error(codes.Internal, "error creating workout share")
+	}
+	resp := share.ToProto()
+	resp.Token = token
+	return resp, nil
+}
+
+func (w *WorkoutShareAPI) ListWorkoutShares(ctx context.Context, rq *workout.ListWorkoutSharesRequest) (*workout.ListWorkoutSharesResponse, error) {
+	if err := rq.Validate(); err != nil {
+		return nil, validationError(func() error { return err.(workout.ListWorkoutSharesRequestValidationError) })
+	}
+	if _, err := w.getValidatedWorkoutOwnerId(ctx, rq.WorkoutId); err != nil {
+		return nil, err
+	}
+	shares, err := w.db.GetWorkoutShares(rq.WorkoutId)
+	if err != nil {
+		log.Printf("error listing workout shares: %v", err)
+		return nil, status.Error(codes.Internal, "error listing workout shares")
+	}
+	return sharesToProto(shares), nil
+}
+
+// DeleteWorkoutShare revokes the share, recipients lose access immediately.
+func (w *WorkoutShareAPI) DeleteWorkoutShare(ctx context.Context, rq *workout.DeleteWorkoutShareRequest) (*emptypb.Empty, error) {
+	if err := rq.Validate(); err != nil {
+		return nil, validationError(func() error { return err.(workout.DeleteWorkoutShareRequestValidationError) })
+	}
+	userId, err := auth.GetUserId(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "user id not found in context")
+	}
+	share, err := w.db.GetWorkoutShare(rq.Id)
+	if err != nil && !errors.Is(err, db.ErrWorkoutShareNotFound) {
+		log.Printf("error getting workout share: %v", err)
+		return nil, status.Error(codes.Internal, "error deleting workout share")
+	}
+	if err != nil || share.OwnerID != userId {
+		return nil, status.Error(codes.NotFound, "workout share not found")
+	}
+	if err := w.db.DeleteWorkoutShare(rq.Id); err != nil && !errors.Is(err, db.ErrWorkoutShareNotFound) {
+		log.Printf("error deleting workout share: %v", err)
+		return nil, status.Error(codes.Internal, "error deleting workout share")
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// ListReceivedWorkoutShares returns workouts shared with the user.
+func (w *WorkoutShareAPI) ListReceivedWorkoutShares(ctx context.Context, _ *emptypb.Empty) (*workout.ListWorkoutSharesResponse, error) {
+	userId, err := auth.GetUserId(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "user id not found in context")
+	}
+	shares, err := w.db.GetReceivedWorkoutShares(userId)
+	if err != nil {
+		log.Printf("error listing received workout shares: %v", err)
+		return nil, status.Error(codes.Internal, "error listing workout shares")
+	}
+	return sharesToProto(shares), nil
+}
+
+func (w *WorkoutShareAPI) GetSharedWorkout(ctx context.Context, rq *workout.SharedWorkoutRequest) (*workout.Workout, error) {
+	if err := rq.Validate(); err != nil {
+		return nil, validationError(func() error { return err.(workout.SharedWorkoutRequestValidationError) })
+	}
+	share, err := w.getShare(ctx, rq)
+	if err != nil {
+		return nil, err
+	}
+	wrk, err := w.wDb.GetWorkout(share.WorkoutID)
+	if errors.Is(err, db.ErrWorkoutNotFound) {
+		return nil, status.Error(codes.NotFound, "workout share not found")
+	}
+	if err != nil {
+		log.Printf("error getting shared workout: %v", err)
+		return nil, status.Error(codes.Internal, "error getting shared workout")
+	}
+	wrk.Exercises = slices.SortedFunc(slices.Values(wrk.Exercises), func(e1, e2 model.WorkoutExercise) int {
+		return cmp.Compare(e1.Order, e2.Order)
+	})
+	return wrk.ToProto(), nil
+}
+
+// ImportSharedWorkout copies shared workout into user's workouts, share must allow copy.
+func (w *WorkoutShareAPI) ImportSharedWorkout(ctx context.Context, rq *workout.ImportSharedWorkoutRequest) (*workout.CreateWorkoutResponse, error) {
+	if err := rq.Validate(); err != nil {
+		return nil, validationError(func() error { return err.(workout.ImportSharedWorkoutRequestValidationError) })
+	}
+	share, err := w.getShare(ctx, rq.Share)
+	if err != nil {
+		return nil, err
+	}
+	if !share.AllowsCopy() {
+		return nil, status.Error(codes.PermissionDenied, "workout share doesn't allow copy")
+	}
+	userId, err := auth.GetUserId(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "user id not found in context")
+	}
+	name := share.WorkoutName
+	if rq.Name != nil {
+		name = *rq.Name
+	}
+	id, err := w.wDb.CopyWorkout(share.WorkoutID, model.WorkoutCopy{OwnerID: userId, Name: name})
+	if errors.Is(err, db.ErrWorkoutNotFound) {
+		return nil, status.Error(codes.NotFound, "workout share not found")
+	}
+	if errors.Is(err, db.ErrWorkoutCopyExercise) {
+		return nil, status.Error(codes.FailedPrecondition, "shared workout references custom exercise of other user")
+	}
+	if err != nil {
+		log.Printf("error importing shared workout: %v", err)
+		return nil, status.Error(codes.Internal, "error importing shared workout")
+	}
+	return &workout.CreateWorkoutResponse{
+		Id: id,
+	}, nil
+}
+
+// getShare resolves share by link token or by id of share addressed to the user. Shares are looked up on every
+// request, so revoked shares stop working immediately.
+func (w *WorkoutShareAPI) getShare(ctx context.Context, rq *workout.SharedWorkoutRequest) (model.WorkoutShare, error) {
+	var share model.WorkoutShare
+	var err error
+	switch key := rq.Key.(type) {
+	case *workout.SharedWorkoutRequest_Token:
+		share, err = w.db.GetWorkoutShareByToken(hashSecretToken(key.Token))
+	case *workout.SharedWorkoutRequest_ShareId:
+		userId, authErr := auth.GetUserId(ctx)
+		if authErr != nil {
+			return model.WorkoutShare{}, status.Error(codes.Internal, "user id not found in context")
+		}
+		share, err = w.db.GetReceivedWorkoutShare(key.ShareId, userId)
+	}
+	if errors.Is(err, db.ErrWorkoutShareNotFound) {
+		return model.WorkoutShare{}, status.Error(codes.NotFound, "workout share not found")
+	}
+	if err != nil {
+		log.Printf("error getting workout share: %v", err)
+		return model.WorkoutShare{}, status.Error(codes.Internal, "error getting workout share")
+	}
+	return share, nil
+}
+
+func (w *WorkoutShareAPI) getValidatedWorkoutOwnerId(ctx context.Context, workoutId string) (string, error) {
+	userId, err := auth.GetUserId(ctx)
+	if err != nil {
+		return "", status.Error(codes.Internal, "user id not found in context")
+	}
+	isOwner, err := w.wDb.IsWorkoutOwner(workoutId, userId)
+	if errors.Is(err, db.ErrWorkoutNotFound) {
+		return "", status.Error(codes.NotFound, "workout not found")
+	}
+	if err != nil {
+		log.Printf("error getting workout data: %v", err)
+		return "", status.Error(codes.Internal, "error getting workout data")
+	}
+	if !isOwner {
+		return "", status.Error(codes.PermissionDenied, "access forbidden")
+	}
+	return userId, nil
+}
+
+func sharesToProto(shares []model.WorkoutShare) *workout.ListWorkoutSharesResponse {
+	var resp workout.ListWorkoutSharesResponse
+	for _, share := range shares {
+		resp.Shares = append(resp.Shares, share.ToProto())
+	}
+	return &resp
+}
@@ -2,32 +2,59 @@ package api
 
 import (
 	"context"
+	"encoding/base64"
 	"errors"
+	"fmt"
+	"github.com/google/uuid"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
 	"google.golang.org/protobuf/types/known/emptypb"
-	"google.golang.org/protobuf/types/known/timestamppb"
 	"log"
 	workout "proto/workout/v1/generated"
+	"slices"
+	"strings"
+	"time"
 	"workout-tracker-server/auth"
 	"workout-tracker-server/db"
 	"workout-tracker-server/model"
+	"workout-tracker-server/rrule"
+)
+
+const (
+	defaultPageSize = 20
+	// calibrationSessions is number of recently completed sessions used to calibrate workout duration estimates.
+	calibrationSessions = 20
+	// scheduleConflictHorizon limits checked occurrences of recurring schedule.
+	scheduleConflictHorizon = 28 * 24 * time.Hour
 )
 
 type WorkoutScheduleAPI struct {
 	workout.UnimplementedWorkoutScheduleServiceServer
 	wsDb db.WorkoutScheduleDb
 	wDb  db.WorkoutDb
+	cDb  db.CoachingDb
+	iDb  db.IdempotencyDb
 }
 
-func NewWorkoutScheduleAPI(wsDb db.WorkoutScheduleDb, wDb db.WorkoutDb) *WorkoutScheduleAPI {
-	return &WorkoutScheduleAPI{wsDb: wsDb, wDb: wDb}
+func NewWorkoutScheduleAPI(wsDb db.WorkoutScheduleDb, wDb db.WorkoutDb, cDb db.CoachingDb, iDb db.IdempotencyDb) *WorkoutScheduleAPI {
+	return &WorkoutScheduleAPI{wsDb: wsDb, wDb: wDb, cDb: cDb, iDb: iDb}
 }
 
 func (s *WorkoutScheduleAPI) ScheduleWorkout(ctx context.Context, rq *workout.ScheduleWorkoutRequest) (*workout.ScheduleWorkoutResponse, error) {
 	if err := rq.Validate(); err != nil {
 		return nil, validationError(func() error { return err.(workout.ScheduleWorkoutRequestValidationError).Cause() })
 	}
+	return runIdempotent(ctx, s.iDb, rq, rq.RequestId, &workout.ScheduleWorkoutResponse{}, func() (*workout.ScheduleWorkoutResponse, error) {
+		return s.scheduleWorkout(ctx, rq)
+	})
+}
+
+func (s *WorkoutScheduleAPI) scheduleWorkout(ctx context.Context, rq *workout.ScheduleWorkoutRequest) (*workout.ScheduleWorkoutResponse, error) {
+	loc, err := loadLocation(rq.WorkoutSchedule.GetTimezone())
+	if err != nil {
+		return nil, err
+	}
 	userId, err := s.getValidatedWorkoutOwnerId(ctx, rq.WorkoutSchedule.WorkoutId)
 	if err != nil {
 		return nil, err
@@ -36,84 +63,633 @@ func (s *WorkoutScheduleAPI) ScheduleWorkout(ctx context.Context, rq *workout.Sc
 		OwnerID:     userId,
 		WorkoutID:   rq.WorkoutSchedule.WorkoutId,
 		ScheduledAt: rq.WorkoutSchedule.ScheduleAt.AsTime(),
+		Timezone:    loc.String(),
+	}
+	slots := []model.WorkoutSchedule{ws}
+	var series *model.WorkoutScheduleSeries
+	if rq.WorkoutSchedule.Recurrence != nil {
+		series, err = newWorkoutScheduleSeries(userId, rq.WorkoutSchedule, loc)
+		if err != nil {
+			return nil, err
+		}
+		slots, err = series.OccurrencesBetween(series.StartsAt, series.StartsAt.Add(scheduleConflictHorizon))
+		if err != nil {
+			log.Printf("error expanding workout schedule series: %v", err)
+			return nil, status.Error(codes.Internal, "error saving workout schedule")
+		}
+	}
+	duration, conflicts, err := s.findScheduleConflicts(userId, ws.WorkoutID, slots)
+	if err != nil {
+		return nil, err
+	}
+	if len(conflicts) > 0 && rq.FailOnConflict {
+		return nil, scheduleConflictError(conflicts)
+	}
+	resp := &workout.ScheduleWorkoutResponse{
+		EstimatedDuration: durationpb.New(duration),
+		Conflicts:         scheduleConflictsToProto(conflicts),
+	}
+	if series != nil {
+		resp.SeriesId, err = s.wsDb.SaveWorkoutScheduleSeries(*series)
+	} else {
+		resp.Id, err = s.wsDb.SaveWorkoutSchedule(ws)
 	}
-	id, err := s.wsDb.SaveWorkoutSchedule(ws)
 	if err != nil {
 		log.Printf("error saving workout schedule: %v", err)
 		return nil, status.Error(codes.Internal, "error saving workout schedule")
 	}
-	return &workout.ScheduleWorkoutResponse{
-		Id: id,
-	}, nil
+	return resp, nil
+}
+
+// newWorkoutScheduleSeries creates recurring schedule, its id is the id returned when scheduling. Occurrences keep
+// wall-clock time of the start in given location.
+func newWorkoutScheduleSeries(userId string, ws *workout.WorkoutSchedule, loc *time.Location) (*model.WorkoutScheduleSeries, error) {
+	rule, err := rrule.Parse(ws.GetRecurrence())
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid recurrence: %v", err)
+	}
+	series := &model.WorkoutScheduleSeries{
+		OwnerID:    userId,
+		WorkoutID:  ws.WorkoutId,
+		StartsAt:   ws.ScheduleAt.AsTime(),
+		Timezone:   loc.String(),
+		Recurrence: rule,
+	}
+	for _, excluded := range ws.ExcludedDates {
+		series.ExcludedDates = append(series.ExcludedDates, excluded.AsTime())
+	}
+	return series, nil
+}
+
+// findScheduleConflicts returns estimated duration of the workout and pending schedules overlapping with any
+// of the slots. Durations are estimated from workout exercises and calibrated with user's completed sessions.
+func (s *WorkoutScheduleAPI) findScheduleConflicts(userId, workoutId string, slots []model.WorkoutSchedule) (time.Duration, []model.ScheduleConflict, error) {
+	completed, err := s.wsDb.GetCompletedWorkoutSchedules(userId, calibrationSessions)
+	if err != nil {
+		log.Printf("error getting completed workout schedules: %v", err)
+		return 0, nil, status.Error(codes.Internal, "error estimating workout duration")
+	}
+	var existing []model.WorkoutSchedule
+	if len(slots) > 0 {
+		from := slots[0].ScheduledAt.Add(-model.MaxWorkoutDuration)
+		to := slots[len(slots)-1].ScheduledAt.Add(model.MaxWorkoutDuration)
+		if existing, err = s.getWorkoutSchedulesBetweenDates(userId, from, to); err != nil {
+			return 0, nil, err
+		}
+	}
+	workoutIds := []string{workoutId}
+	for _, ws := range slices.Concat(completed, existing) {
+		workoutIds = append(workoutIds, ws.WorkoutID)
+	}
+	summaries, err := s.wDb.GetWorkoutSummaries(workoutIds)
+	if err != nil {
+		log.Printf("error getting workout summaries: %v", err)
+		return 0, nil, status.Error(codes.Internal, "error estimating workout duration")
+	}
+	var sessions []model.CompletedSession
+	for _, ws := range completed {
+		sessions = append(sessions, model.CompletedSession{
+			Estimated: summaries[ws.WorkoutID].EstimateDuration(),
+			Actual:    ws.CompletedAt.Sub(ws.ScheduledAt),
+		})
+	}
+	calibration := model.NewDurationCalibration(sessions)
+	durations := make(map[string]time.Duration, len(workoutIds))
+	for _, id := range workoutIds {
+		durations[id] = calibration.Apply(summaries[id].EstimateDuration())
+	}
+	return durations[workoutId], model.FindScheduleConflicts(slots, existing, durations, time.Now().UTC()), nil
+}
+
+func scheduleConflictsToProto(conflicts []model.ScheduleConflict) []*workout.ScheduleConflict {
+	now := time.Now().UTC()
+	var result []*workout.ScheduleConflict
+	for _, conflict := range conflicts {
+		result = append(result, &workout.ScheduleConflict{
+			WorkoutSchedule:   conflict.Schedule.ToProto(now),
+			EstimatedDuration: durationpb.New(conflict.EstimatedDuration),
+		})
+	}
+	return result
+}
+
+// scheduleConflictError rejects scheduling, conflicting schedules are attached as error details.
+func scheduleConflictError(conflicts []model.ScheduleConflict) error {
+	st := status.New(codes.FailedPrecondition, "workout schedule conflicts with existing schedules")
+	withDetails, err := st.WithDetails(&workout.ScheduleConflicts{Conflicts: scheduleConflictsToProto(conflicts)})
+	if err != nil {
+		log.Printf("error attaching schedule conflicts: %v", err)
+		return st.Err()
+	}
+	return withDetails.Err()
 }
 
 func (s *WorkoutScheduleAPI) MarkWorkoutComplete(ctx context.Context, rq *workout.MarkWorkoutCompleteRequest) (*emptypb.Empty, error) {
 	if err := rq.Validate(); err != nil {
 		return nil, validationError(func() error { return err.(workout.MarkWorkoutCompleteRequestValidationError) })
 	}
-	userId, err := auth.GetUserId(ctx)
-	if err != nil {
-		return nil, status.Error(codes.Internal, "user id not found in context")
+	if err := validateExerciseSetResults(rq.Results); err != nil {
+		return nil, err
 	}
-	isOwner, err := s.wsDb.IsWorkoutScheduleOwner(rq.Id, userId)
+	userId, err := s.getValidatedWorkoutScheduleOwnerId(ctx, rq.Id)
 	if err != nil {
-		log.Printf("error getting workout schedule owner: %v", err)
-		return nil, status.Error(codes.Internal, "error getting workout schedule owner")
+		return nil, err
+	}
+	var sets []model.ExerciseSet
+	for _, result := range rq.Results {
+		sets = append(sets, model.FromExerciseSetResultProto(result))
 	}
-	if !isOwner {
-		return nil, status.Error(codes.PermissionDenied, "access forbidden")
+	err = s.wsDb.CompleteWorkoutSchedule(rq.Id, userId, sets)
+	if errors.Is(err, db.ErrExerciseNotInWorkout) {
+		return nil, status.Error(codes.InvalidArgument, "results reference exercise which is not part of the workout")
 	}
-	err = s.wsDb.UpdateWorkoutScheduleCompleted(rq.Id)
 	if err != nil {
-		log.Printf("error updating workout schedule: %v", err)
+		log.Printf("error completing workout schedule: %v", err)
 		return nil, status.Error(codes.Internal, "error updating workout schedule")
 	}
 	return &emptypb.Empty{}, nil
 }
 
+// validateExerciseSetResults checks that each set of the exercise is reported at most once.
+func validateExerciseSetResults(results []*workout.ExerciseSetResult) error {
+	type exerciseSet struct {
+		exerciseId string
+		set        int32
+	}
+	seen := make(map[exerciseSet]bool, len(results))
+	for _, result := range results {
+		key := exerciseSet{result.ExerciseId, result.Set}
+		if seen[key] {
+			return status.Errorf(codes.InvalidArgument, "duplicate set %d of exercise %s", result.Set, result.ExerciseId)
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+func (s *WorkoutScheduleAPI) ReopenWorkoutSchedule(ctx context.Context, rq *workout.ReopenWorkoutScheduleRequest) (*emptypb.Empty, error) {
+	if err := rq.Validate(); err != nil {
+		return nil, validationError(func() error { return err.(workout.ReopenWorkoutScheduleRequestValidationError) })
+	}
+	if _, err := s.getValidatedWorkoutScheduleOwnerId(ctx, rq.Id); err != nil {
+		return nil, err
+	}
+	if err := s.wsDb.ReopenWorkoutSchedule(rq.Id); err != nil {
+		return nil, workoutScheduleUpdateError(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *WorkoutScheduleAPI) UpdateWorkoutSchedule(ctx context.Context, rq *workout.UpdateWorkoutScheduleRequest) (*emptypb.Empty, error) {
+	if err := rq.Validate(); err != nil {
+		return nil, validationError(func() error { return err.(workout.UpdateWorkoutScheduleRequestValidationError) })
+	}
+	if err := validateWorkoutScheduleUpdate(rq); err != nil {
+		return nil, err
+	}
+	if _, err := s.getValidatedWorkoutScheduleOwnerId(ctx, rq.Id); err != nil {
+		return nil, err
+	}
+	ws := model.WorkoutSchedule{
+		ID:          rq.Id,
+		ScheduledAt: rq.GetWorkoutSchedule().GetScheduleAt().AsTime(),
+	}
+	if err := s.wsDb.UpdateWorkoutSchedule(ws, rq.UpdateMask); err != nil {
+		return nil, workoutScheduleUpdateError(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// validateWorkoutScheduleUpdate validates fields listed in update mask, workout schedule message can't be validated
+// as a whole since fields not being updated are omitted.
+func validateWorkoutScheduleUpdate(rq *workout.UpdateWorkoutScheduleRequest) error {
+	for _, path := range rq.GetUpdateMask().GetPaths() {
+		switch path {
+		case "schedule_at":
+			scheduleAt := rq.GetWorkoutSchedule().GetScheduleAt()
+			if scheduleAt == nil {
+				return status.Error(codes.InvalidArgument, "schedule_at is required")
+			}
+			if !scheduleAt.AsTime().After(time.Now()) {
+				return status.Error(codes.InvalidArgument, "schedule_at must be in the future")
+			}
+		default:
+			return status.Errorf(codes.InvalidArgument, "unsupported update mask path: %s", path)
+		}
+	}
+	return nil
+}
+
+func (s *WorkoutScheduleAPI) CancelWorkoutSchedule(ctx context.Context, rq *workout.CancelWorkoutScheduleRequest) (*emptypb.Empty, error) {
+	if err := rq.Validate(); err != nil {
+		return nil, validationError(func() error { return err.(workout.CancelWorkoutScheduleRequestValidationError) })
+	}
+	if _, err := s.getValidatedWorkoutScheduleOwnerId(ctx, rq.Id); err != nil {
+		return nil, err
+	}
+	if err := s.wsDb.CancelWorkoutSchedule(rq.Id, rq.Reason); err != nil {
+		return nil, workoutScheduleUpdateError(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *WorkoutScheduleAPI) DeleteWorkoutSchedule(ctx context.Context, rq *workout.DeleteWorkoutScheduleRequest) (*emptypb.Empty, error) {
+	if err := rq.Validate(); err != nil {
+		return nil, validationError(func() error { return err.(workout.DeleteWorkoutScheduleRequestValidationError) })
+	}
+	if _, err := s.getValidatedWorkoutScheduleOwnerId(ctx, rq.Id); err != nil {
+		return nil, err
+	}
+	if err := s.wsDb.DeleteWorkoutSchedule(rq.Id); err != nil {
+		if errors.Is(err, db.ErrWorkoutScheduleNotFound) {
+			return nil, status.Error(codes.NotFound, "workout schedule not found")
+		}
+		log.Printf("error deleting workout schedule: %v", err)
+		return nil, status.Error(codes.Internal, "error deleting workout schedule")
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func workoutScheduleUpdateError(err error) error {
+	if errors.Is(err, db.ErrWorkoutScheduleNotFound) {
+		return status.Error(codes.NotFound, "workout schedule not found")
+	}
+	if errors.Is(err, db.ErrWorkoutScheduleCompleted) {
+		return status.Error(codes.FailedPrecondition, "workout schedule already completed")
+	}
+	log.Printf("error updating workout schedule: %v", err)
+	return status.Error(codes.Internal, "error updating workout schedule")
+}
+
+func (s *WorkoutScheduleAPI) ListWorkoutSchedules(ctx context.Context, rq *workout.ListWorkoutSchedulesRequest) (*workout.ListWorkoutSchedulesResponse, error) {
+	if err := rq.Validate(); err != nil {
+		return nil, validationError(func() error { return err.(workout.ListWorkoutSchedulesRequestValidationError) })
+	}
+	after, err := decodePageToken(rq.PageToken)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid page token")
+	}
+	userId, err := getAccountId(ctx, s.cDb, rq.ClientId)
+	if err != nil {
+		return nil, err
+	}
+	pageSize := int(rq.PageSize)
+	if pageSize == 0 {
+		pageSize = defaultPageSize
+	}
+	//one more than page size is fetched to find out whether there is next page
+	filter := model.WorkoutScheduleFilter{After: after, Now: time.Now().UTC(), Limit: pageSize + 1}
+	for _, st := range rq.Statuses {
+		filter.Statuses = append(filter.Statuses, model.WorkoutScheduleStatusFromProto(st))
+	}
+	if rq.StartDate != nil {
+		from := rq.StartDate.AsTime()
+		filter.From = &from
+	}
+	if rq.EndDate != nil {
+		to := rq.EndDate.AsTime()
+		filter.To = &to
+	}
+	schedules, err := s.wsDb.ListWorkoutSchedules(userId, filter)
+	if err != nil {
+		log.Printf("error listing workout schedules: %v", err)
+		return nil, status.Error(codes.Internal, "error listing workout schedules")
+	}
+	occurrences, err := s.listSeriesOccurrences(userId, filter)
+	if err != nil {
+		return nil, err
+	}
+	schedules = append(schedules, occurrences...)
+	slices.SortFunc(schedules, model.CompareWorkoutSchedules)
+
+	var resp workout.ListWorkoutSchedulesResponse
+	if len(schedules) > pageSize {
+		schedules = schedules[:pageSize]
+		resp.NextPageToken = encodePageToken(schedules[pageSize-1].Cursor())
+	}
+	var workoutIds []string
+	for _, ws := range schedules {
+		if !slices.Contains(workoutIds, ws.WorkoutID) {
+			workoutIds = append(workoutIds, ws.WorkoutID)
+		}
+	}
+	summaries, err := s.wDb.GetWorkoutSummaries(workoutIds)
+	if err != nil {
+		log.Printf("error getting workout summaries: %v", err)
+		return nil, status.Error(codes.Internal, "error listing workout schedules")
+	}
+	for _, ws := range schedules {
+		resp.WorkoutSchedules = append(resp.WorkoutSchedules, ws.ToListItemProto(filter.Now, summaries[ws.WorkoutID]))
+	}
+	return &resp, nil
+}
+
+// listSeriesOccurrences expands recurring series for schedule listing. Occurrences which are not stored can be
+// only pending or missed, so expansion range is narrowed down to requested statuses.
+func (s *WorkoutScheduleAPI) listSeriesOccurrences(userId string, filter model.WorkoutScheduleFilter) ([]model.WorkoutSchedule, error) {
+	includePending := len(filter.Statuses) == 0 || slices.Contains(filter.Statuses, model.WorkoutScheduleStatusPending)
+	includeMissed := len(filter.Statuses) == 0 || slices.Contains(filter.Statuses, model.WorkoutScheduleStatusMissed)
+	if !includePending && !includeMissed {
+		return nil, nil
+	}
+	var from, to time.Time
+	if filter.From != nil {
+		from = *filter.From
+	}
+	if filter.To != nil {
+		to = *filter.To
+	}
+	if filter.After != nil && filter.After.ScheduledAt.After(from) {
+		from = filter.After.ScheduledAt
+	}
+	if !includeMissed && filter.MissedBefore().After(from) {
+		from = filter.MissedBefore()
+	}
+	if !includePending && (to.IsZero() || !to.Before(filter.MissedBefore())) {
+		to = filter.MissedBefore().Add(-time.Nanosecond)
+	}
+	if !to.IsZero() && to.Before(from) {
+		return nil, nil
+	}
+	seriesList, err := s.wsDb.GetWorkoutScheduleSeriesByOwner(userId)
+	if err != nil {
+		log.Printf("error getting workout schedule series: %v", err)
+		return nil, status.Error(codes.Internal, "error listing workout schedules")
+	}
+	var occurrences []model.WorkoutSchedule
+	for _, series := range seriesList {
+		seriesOccurrences, err := series.Occurrences(from, to, filter.After, filter.Limit)
+		if err != nil {
+			log.Printf("error expanding workout schedule series: %v", err)
+			return nil, status.Error(codes.Internal, "error listing workout schedules")
+		}
+		occurrences = append(occurrences, seriesOccurrences...)
+	}
+	return occurrences, nil
+}
+
+// encodePageToken encodes position of the last listed workout schedule as opaque page token.
+func encodePageToken(cursor model.WorkoutScheduleCursor) string {
+	return encodeCursorToken(cursor.ScheduledAt, cursor.Key)
+}
+
+func decodePageToken(token string) (*model.WorkoutScheduleCursor, error) {
+	if token == "" {
+		return nil, nil
+	}
+	scheduledAt, key, err := decodeCursorToken(token)
+	if err != nil {
+		return nil, err
+	}
+	return &model.WorkoutScheduleCursor{ScheduledAt: scheduledAt, Key: key}, nil
+}
+
+// encodeCursorToken encodes position within items sorted by time and uuid key as opaque page token.
+func encodeCursorToken(t time.Time, key string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(t.Format(time.RFC3339Nano) + "|" + key))
+}
+
+func decodeCursorToken(token string) (time.Time, string, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return time.Time{}, "", err
+	}
+	encodedTime, key, ok := strings.Cut(string(data), "|")
+	if !ok {
+		return time.Time{}, "", fmt.Errorf("malformed page token")
+	}
+	t, err := time.Parse(time.RFC3339Nano, encodedTime)
+	if err != nil {
+		return time.Time{}, "", err
+	}
+	if _, err := uuid.Parse(key); err != nil {
+		return time.Time{}, "", err
+	}
+	return t, key, nil
+}
+
 func (s *WorkoutScheduleAPI) GetWorkoutScheduleReport(ctx context.Context, rq *workout.GetWorkoutScheduleReportRequest) (*workout.GetWorkoutScheduleReportResponse, error) {
 	if err := rq.Validate(); err != nil {
 		return nil, validationError(func() error { return err.(workout.GetWorkoutScheduleReportRequestValidationError) })
 	}
-	userId, err := auth.GetUserId(ctx)
+	from, to, err := reportTimeRange(rq)
 	if err != nil {
-		return nil, status.Error(codes.Internal, "user id not found in context")
+		return nil, err
 	}
-	schedules, err := s.wsDb.GetWorkoutSchedulesBetweenDates(userId, rq.StartDate.AsTime(), rq.EndDate.AsTime())
+	userId, err := getAccountId(ctx, s.cDb, rq.ClientId)
 	if err != nil {
-		log.Printf("error getting workout schedules: %v", err)
-		return nil, status.Error(codes.Internal, "error getting workout schedules")
+		return nil, err
 	}
+	schedules, err := s.getWorkoutSchedulesBetweenDates(userId, from, to)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
 	var respSchedules []*workout.WorkoutSchedule
 	for _, ws := range schedules {
-		respSchedules = append(respSchedules, &workout.WorkoutSchedule{
-			Id:         ws.ID,
-			WorkoutId:  ws.WorkoutID,
-			ScheduleAt: timestamppb.New(ws.ScheduledAt),
-			CreatedAt:  timestamppb.New(ws.CreatedAt),
-			Completed:  ws.Completed,
-		})
+		respSchedules = append(respSchedules, ws.ToProto(now))
 	}
 	return &workout.GetWorkoutScheduleReportResponse{
 		WorkoutSchedules: respSchedules,
 	}, nil
 }
 
-func (s *WorkoutScheduleAPI) getValidatedWorkoutOwnerId(ctx context.Context, workoutId string) (string, error) {
+// reportTimeRange resolves report range either from absolute times or from local dates, local range spans from
+// the midnight starting the first day until the midnight ending the last one.
+func reportTimeRange(rq *workout.GetWorkoutScheduleReportRequest) (time.Time, time.Time, error) {
+	if rq.LocalDateRange == nil {
+		if rq.StartDate == nil || rq.EndDate == nil {
+			return time.Time{}, time.Time{}, status.Error(codes.InvalidArgument, "start_date and end_date or local_date_range is required")
+		}
+		return rq.StartDate.AsTime(), rq.EndDate.AsTime(), nil
+	}
+	loc, err := loadLocation(rq.LocalDateRange.GetTimezone())
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	from, err := time.ParseInLocation(time.DateOnly, rq.LocalDateRange.StartDate, loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, status.Errorf(codes.InvalidArgument, "invalid start date: %s", rq.LocalDateRange.StartDate)
+	}
+	to, err := time.ParseInLocation(time.DateOnly, rq.LocalDateRange.EndDate, loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, status.Errorf(codes.InvalidArgument, "invalid end date: %s", rq.LocalDateRange.EndDate)
+	}
+	if to.Before(from) {
+		return time.Time{}, time.Time{}, status.Error(codes.InvalidArgument, "end date must not be before start date")
+	}
+	return from.UTC(), to.AddDate(0, 0, 1).Add(-time.Nanosecond).UTC(), nil
+}
+
+func (s *WorkoutScheduleAPI) GetAdherenceStats(ctx context.Context, rq *workout.GetAdherenceStatsRequest) (*workout.GetAdherenceStatsResponse, error) {
+	if err := rq.Validate(); err != nil {
+		return nil, validationError(func() error { return err.(workout.GetAdherenceStatsRequestValidationError) })
+	}
+	loc, err := loadLocation(rq.GetTimezone())
+	if err != nil {
+		return nil, err
+	}
+	userId, err := getAccountId(ctx, s.cDb, rq.ClientId)
+	if err != nil {
+		return nil, err
+	}
+	schedules, err := s.getWorkoutSchedulesBetweenDates(userId, rq.StartDate.AsTime(), rq.EndDate.AsTime())
+	if err != nil {
+		return nil, err
+	}
+	return model.CalculateAdherenceStats(schedules, time.Now().UTC(), loc).ToProto(), nil
+}
+
+func (s *WorkoutScheduleAPI) UpdateScheduleOccurrence(ctx context.Context, rq *workout.UpdateScheduleOccurrenceRequest) (*workout.UpdateScheduleOccurrenceResponse, error) {
+	if err := rq.Validate(); err != nil {
+		return nil, validationError(func() error { return err.(workout.UpdateScheduleOccurrenceRequestValidationError) })
+	}
+	series, err := s.getValidatedOccurrenceSeries(ctx, rq.SeriesId, rq.RecurrenceId.AsTime())
+	if err != nil {
+		return nil, err
+	}
+	recurrenceId, scheduleAt := rq.RecurrenceId.AsTime(), rq.ScheduleAt.AsTime()
+	var id string
+	if rq.Scope == workout.RecurrenceScope_RECURRENCE_SCOPE_THIS_OCCURRENCE {
+		id, err = s.wsDb.SaveScheduleOccurrence(series, recurrenceId, scheduleAt)
+	} else {
+		var continuation model.WorkoutScheduleSeries
+		continuation, err = series.ContinuationFrom(recurrenceId, scheduleAt)
+		if err == nil {
+			id, err = s.wsDb.TruncateWorkoutScheduleSeries(series, recurrenceId, &continuation)
+		}
+	}
+	if err != nil {
+		log.Printf("error updating workout schedule series occurrence: %v", err)
+		return nil, status.Error(codes.Internal, "error updating workout schedule")
+	}
+	return &workout.UpdateScheduleOccurrenceResponse{
+		Id: id,
+	}, nil
+}
+
+func (s *WorkoutScheduleAPI) CancelScheduleOccurrence(ctx context.Context, rq *workout.CancelScheduleOccurrenceRequest) (*emptypb.Empty, error) {
+	if err := rq.Validate(); err != nil {
+		return nil, validationError(func() error { return err.(workout.CancelScheduleOccurrenceRequestValidationError) })
+	}
+	series, err := s.getValidatedOccurrenceSeries(ctx, rq.SeriesId, rq.RecurrenceId.AsTime())
+	if err != nil {
+		return nil, err
+	}
+	if rq.Scope == workout.RecurrenceScope_RECURRENCE_SCOPE_THIS_OCCURRENCE {
+		err = s.wsDb.CancelScheduleOccurrence(series.ID, rq.RecurrenceId.AsTime())
+	} else {
+		_, err = s.wsDb.TruncateWorkoutScheduleSeries(series, rq.RecurrenceId.AsTime(), nil)
+	}
+	if err != nil {
+		log.Printf("error cancelling workout schedule series occurrence: %v", err)
+		return nil, status.Error(codes.Internal, "error updating workout schedule")
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *WorkoutScheduleAPI) CompleteScheduleOccurrence(ctx context.Context, rq *workout.CompleteScheduleOccurrenceRequest) (*workout.CompleteScheduleOccurrenceResponse, error) {
+	if err := rq.Validate(); err != nil {
+		return nil, validationError(func() error { return err.(workout.CompleteScheduleOccurrenceRequestValidationError) })
+	}
+	series, err := s.getValidatedOccurrenceSeries(ctx, rq.SeriesId, rq.RecurrenceId.AsTime())
+	if err != nil {
+		return nil, err
+	}
+	id, err := s.wsDb.CompleteScheduleOccurrence(series, rq.RecurrenceId.AsTime())
+	if err != nil {
+		log.Printf("error completing workout schedule series occurrence: %v", err)
+		return nil, status.Error(codes.Internal, "error updating workout schedule")
+	}
+	return &workout.CompleteScheduleOccurrenceResponse{
+		Id: id,
+	}, nil
+}
+
+// getWorkoutSchedulesBetweenDates returns stored workout schedules together with expanded occurrences of recurring
+// series, sorted by schedule time.
+func (s *WorkoutScheduleAPI) getWorkoutSchedulesBetweenDates(userId string, from, to time.Time) ([]model.WorkoutSchedule, error) {
+	schedules, err := s.wsDb.GetWorkoutSchedulesBetweenDates(userId, from, to)
+	if err != nil {
+		log.Printf("error getting workout schedules: %v", err)
+		return nil, status.Error(codes.Internal, "error getting workout schedules")
+	}
+	seriesList, err := s.wsDb.GetWorkoutScheduleSeriesStartedBefore(userId, to)
+	if err != nil {
+		log.Printf("error getting workout schedule series: %v", err)
+		return nil, status.Error(codes.Internal, "error getting workout schedules")
+	}
+	for _, series := range seriesList {
+		occurrences, err := series.OccurrencesBetween(from, to)
+		if err != nil {
+			log.Printf("error expanding workout schedule series: %v", err)
+			return nil, status.Error(codes.Internal, "error getting workout schedules")
+		}
+		schedules = append(schedules, occurrences...)
+	}
+	slices.SortStableFunc(schedules, func(ws1, ws2 model.WorkoutSchedule) int {
+		return ws1.ScheduledAt.Compare(ws2.ScheduledAt)
+	})
+	return schedules, nil
+}
+
+// getValidatedOccurrenceSeries returns series owned by the user or by client of the user, which has an occurrence
+// at given recurrence id.
+func (s *WorkoutScheduleAPI) getValidatedOccurrenceSeries(ctx context.Context, seriesId string, recurrenceId time.Time) (model.WorkoutScheduleSeries, error) {
+	userId, err := auth.GetUserId(ctx)
+	if err != nil {
+		return model.WorkoutScheduleSeries{}, status.Error(codes.Internal, "user id not found in context")
+	}
+	series, err := s.wsDb.GetWorkoutScheduleSeries(seriesId)
+	if errors.Is(err, db.ErrWorkoutScheduleSeriesNotFound) {
+		return model.WorkoutScheduleSeries{}, status.Error(codes.NotFound, "workout schedule series not found")
+	}
+	if err != nil {
+		log.Printf("error getting workout schedule series: %v", err)
+		return model.WorkoutScheduleSeries{}, status.Error(codes.Internal, "error getting workout schedule series")
+	}
+	if series.OwnerID != userId {
+		if err := validateCoachOf(s.cDb, userId, series.OwnerID); err != nil {
+			return model.WorkoutScheduleSeries{}, err
+		}
+	}
+	startsAt, err := series.LocalStartsAt()
+	if err != nil {
+		log.Printf("error getting workout schedule series: %v", err)
+		return model.WorkoutScheduleSeries{}, status.Error(codes.Internal, "error getting workout schedule series")
+	}
+	if !series.Recurrence.IsOccurrence(startsAt, recurrenceId, series.ExcludedDates) {
+		return model.WorkoutScheduleSeries{}, status.Error(codes.NotFound, "occurrence not found")
+	}
+	return series, nil
+}
+
+// getValidatedWorkoutScheduleOwnerId returns owner of the workout schedule when the user is the owner or active coach
+// of the owner, so coaches manage schedules they created in account of their clients.
+func (s *WorkoutScheduleAPI) getValidatedWorkoutScheduleOwnerId(ctx context.Context, scheduleId string) (string, error) {
 	userId, err := auth.GetUserId(ctx)
 	if err != nil {
 		return "", status.Error(codes.Internal, "user id not found in context")
 	}
-	isOwner, err := s.wDb.IsWorkoutOwner(workoutId, userId)
-	if errors.Is(err, db.ErrWorkoutNotFound) {
-		return "", status.Error(codes.NotFound, "workout not found")
+	owner, err := s.wsDb.GetWorkoutScheduleOwner(scheduleId)
+	if errors.Is(err, db.ErrWorkoutScheduleNotFound) {
+		return "", status.Error(codes.NotFound, "workout schedule not found")
 	}
 	if err != nil {
-		log.Printf("error getting workout data: %v", err)
-		return "", status.Error(codes.Internal, "error getting workout data")
+		log.Printf("error getting workout schedule owner: %v", err)
+		return "", status.Error(codes.Internal, "error getting workout schedule owner")
+	}
+	if owner == userId {
+		return owner, nil
 	}
-	if !isOwner {
-		return "", status.Error(codes.PermissionDenied, "access forbidden")
+	if err := validateCoachOf(s.cDb, userId, owner); err != nil {
+		return "", err
 	}
-	return userId, nil
+	return owner, nil
+}
+
+// getValidatedWorkoutOwnerId returns owner of the workout for its owner and active coach of the owner, so coaches
+// schedule workouts in account of their clients.
+func (s *WorkoutScheduleAPI) getValidatedWorkoutOwnerId(ctx context.Context, workoutId string) (string, error) {
+	return getValidatedWorkoutOwnerId(ctx, s.wDb, s.cDb, workoutId)
 }
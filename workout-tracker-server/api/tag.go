@@ -0,0 +1,118 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"log"
+	workout "proto/workout/v1/generated"
+	"strings"
+	"workout-tracker-server/auth"
+	"workout-tracker-server/db"
+	"workout-tracker-server/model"
+)
+
+type TagAPI struct {
+	workout.UnimplementedTagServiceServer
+	db db.TagDb
+}
+
+func NewTagAPI(db db.TagDb) *TagAPI {
+	return &TagAPI{db: db}
+}
+
+func (t *TagAPI) ListTags(ctx context.Context, _ *emptypb.Empty) (*workout.ListTagsResponse, error) {
+	userId, err := auth.GetUserId(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "user id not found in context")
+	}
+	tags, err := t.db.GetTags(userId)
+	if err != nil {
+		log.Printf("error listing tags: %v", err)
+		return nil, status.Error(codes.Internal, "error listing tags")
+	}
+	var resp workout.ListTagsResponse
+	for _, tag := range tags {
+		resp.Tags = append(resp.Tags, tag.ToProto())
+	}
+	return &resp, nil
+}
+
+func (t *TagAPI) RenameTag(ctx context.Context, rq *workout.RenameTagRequest) (*workout.Tag, error) {
+	if err := rq.Validate(); err != nil {
+		return nil, validationError(func() error { return err.(workout.RenameTagRequestValidationError) })
+	}
+	name := strings.TrimSpace(rq.Name)
+	if name == "" {
+		return nil, status.Error(codes.InvalidArgument, "tag name must not be blank")
+	}
+	if _, err := t.getOwnedTag(ctx, rq.Id); err != nil {
+		return nil, err
+	}
+	tag, err := t.db.RenameTag(rq.Id, name)
+	if errors.Is(err, db.ErrTagExists) {
+		return nil, status.Error(codes.AlreadyExists, "tag with the name already exists, merge the tags instead")
+	}
+	if err != nil {
+		log.Printf("error renaming tag: %v", err)
+		return nil, status.Error(codes.Internal, "error renaming tag")
+	}
+	return tag.ToProto(), nil
+}
+
+func (t *TagAPI) MergeTags(ctx context.Context, rq *workout.MergeTagsRequest) (*workout.Tag, error) {
+	if err := rq.Validate(); err != nil {
+		return nil, validationError(func() error { return err.(workout.MergeTagsRequestValidationError) })
+	}
+	target, err := t.getOwnedTag(ctx, rq.TargetId)
+	if err != nil {
+		return nil, err
+	}
+	for _, sourceId := range rq.SourceIds {
+		if sourceId == target.ID {
+			return nil, status.Error(codes.InvalidArgument, "tag can't be merged into itself")
+		}
+		if _, err := t.getOwnedTag(ctx, sourceId); err != nil {
+			return nil, err
+		}
+	}
+	tag, err := t.db.MergeTags(rq.SourceIds, rq.TargetId)
+	if err != nil {
+		log.Printf("error merging tags: %v", err)
+		return nil, status.Error(codes.Internal, "error merging tags")
+	}
+	return tag.ToProto(), nil
+}
+
+func (t *TagAPI) DeleteTag(ctx context.Context, rq *workout.DeleteTagRequest) (*emptypb.Empty, error) {
+	if err := rq.Validate(); err != nil {
+		return nil, validationError(func() error { return err.(workout.DeleteTagRequestValidationError) })
+	}
+	if _, err := t.getOwnedTag(ctx, rq.Id); err != nil {
+		return nil, err
+	}
+	if err := t.db.DeleteTag(rq.Id); err != nil {
+		log.Printf("error deleting tag: %v", err)
+		return nil, status.Error(codes.Internal, "error deleting tag")
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// getOwnedTag returns tag of the caller, tags of other users are reported as not found.
+func (t *TagAPI) getOwnedTag(ctx context.Context, id string) (model.Tag, error) {
+	userId, err := auth.GetUserId(ctx)
+	if err != nil {
+		return model.Tag{}, status.Error(codes.Internal, "user id not found in context")
+	}
+	tag, err := t.db.GetTag(id)
+	if errors.Is(err, db.ErrTagNotFound) || (err == nil && tag.OwnerID != userId) {
+		return model.Tag{}, status.Error(codes.NotFound, "tag not found")
+	}
+	if err != nil {
+		log.Printf("error getting tag: %v", err)
+		return model.Tag{}, status.Error(codes.Internal, "error getting tag")
+	}
+	return tag, nil
+}
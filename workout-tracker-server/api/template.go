@@ -0,0 +1,154 @@
+package api
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"log"
+	workout "proto/workout/v1/generated"
+	"slices"
+	"workout-tracker-server/auth"
+	"workout-tracker-server/db"
+	"workout-tracker-server/model"
+)
+
+type WorkoutTemplateAPI struct {
+	workout.UnimplementedWorkoutTemplateServiceServer
+	db  db.WorkoutTemplateDb
+	wDb db.WorkoutDb
+}
+
+func NewWorkoutTemplateAPI(db db.WorkoutTemplateDb, wDb db.WorkoutDb) *WorkoutTemplateAPI {
+	return &WorkoutTemplateAPI{db: db, wDb: wDb}
+}
+
+// CreateWorkoutTemplate saves copy of user's workout as template.
+func (t *WorkoutTemplateAPI) CreateWorkoutTemplate(ctx context.Context, rq *workout.CreateWorkoutTemplateRequest) (*workout.CreateWorkoutTemplateResponse, error) {
+	if err := rq.Validate(); err != nil {
+		return nil, validationError(func() error { return err.(workout.CreateWorkoutTemplateRequestValidationError) })
+	}
+	userId, err := auth.GetUserId(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "user id not found in context")
+	}
+	isOwner, err := t.wDb.IsWorkoutOwner(rq.WorkoutId, userId)
+	if errors.Is(err, db.ErrWorkoutNotFound) {
+		return nil, status.Error(codes.NotFound, "workout not found")
+	}
+	if err != nil {
+		log.Printf("error getting workout data: %v", err)
+		return nil, status.Error(codes.Internal, "error getting workout data")
+	}
+	if !isOwner {
+		return nil, status.Error(codes.PermissionDenied, "access forbidden")
+	}
+	id, err := t.wDb.CopyWorkout(rq.WorkoutId, model.WorkoutCopy{OwnerID: userId, Name: rq.Name, Template: true})
+	if err != nil {
+		log.Printf("error saving workout template: %v", err)
+		return nil, status.Error(codes.Internal, "error saving workout template")
+	}
+	return &workout.CreateWorkoutTemplateResponse{
+		Id: id,
+	}, nil
+}
+
+// ListWorkoutTemplates returns system templates and user's own templates, without exercises.
+func (t *WorkoutTemplateAPI) ListWorkoutTemplates(ctx context.Context, _ *emptypb.Empty) (*workout.ListWorkoutTemplatesResponse, error) {
+	userId, err := auth.GetUserId(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "user id not found in context")
+	}
+	templates, err := t.db.GetWorkoutTemplates(userId)
+	if err != nil {
+		log.Printf("error listing workout templates: %v", err)
+		return nil, status.Error(codes.Internal, "error listing workout templates")
+	}
+	var resp workout.ListWorkoutTemplatesResponse
+	for _, template := range templates {
+		resp.Templates = append(resp.Templates, template.ToTemplateProto())
+	}
+	return &resp, nil
+}
+
+func (t *WorkoutTemplateAPI) GetWorkoutTemplate(ctx context.Context, rq *workout.GetWorkoutTemplateRequest) (*workout.WorkoutTemplate, error) {
+	if err := rq.Validate(); err != nil {
+		return nil, validationError(func() error { return err.(workout.GetWorkoutTemplateRequestValidationError) })
+	}
+	template, err := t.getVisibleTemplate(ctx, rq.Id)
+	if err != nil {
+		return nil, err
+	}
+	template.Exercises = slices.SortedFunc(slices.Values(template.Exercises), func(e1, e2 model.WorkoutExercise) int {
+		return cmp.Compare(e1.Order, e2.Order)
+	})
+	return template.ToTemplateProto(), nil
+}
+
+// DeleteWorkoutTemplate deletes user's own template, workouts instantiated from it are kept without lineage.
+func (t *WorkoutTemplateAPI) DeleteWorkoutTemplate(ctx context.Context, rq *workout.DeleteWorkoutTemplateRequest) (*emptypb.Empty, error) {
+	if err := rq.Validate(); err != nil {
+		return nil, validationError(func() error { return err.(workout.DeleteWorkoutTemplateRequestValidationError) })
+	}
+	template, err := t.getVisibleTemplate(ctx, rq.Id)
+	if err != nil {
+		return nil, err
+	}
+	if template.IsSystemTemplate() {
+		return nil, status.Error(codes.PermissionDenied, "system template can't be deleted")
+	}
+	if err := t.db.DeleteWorkoutTemplate(rq.Id); err != nil {
+		log.Printf("error deleting workout template: %v", err)
+		return nil, status.Error(codes.Internal, "error deleting workout template")
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// InstantiateWorkoutTemplate creates user's workout from the template, created workout references the template.
+func (t *WorkoutTemplateAPI) InstantiateWorkoutTemplate(ctx context.Context, rq *workout.InstantiateWorkoutTemplateRequest) (*workout.CreateWorkoutResponse, error) {
+	if err := rq.Validate(); err != nil {
+		return nil, validationError(func() error { return err.(workout.InstantiateWorkoutTemplateRequestValidationError) })
+	}
+	template, err := t.getVisibleTemplate(ctx, rq.Id)
+	if err != nil {
+		return nil, err
+	}
+	userId, err := auth.GetUserId(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "user id not found in context")
+	}
+	name := template.Name
+	if rq.Name != nil {
+		name = *rq.Name
+	}
+	id, err := t.wDb.CopyWorkout(rq.Id, model.WorkoutCopy{OwnerID: userId, Name: name, SourceTemplateID: &template.ID})
+	if errors.Is(err, db.ErrWorkoutCopyExercise) {
+		return nil, status.Error(codes.FailedPrecondition, "workout template references custom exercise of other user")
+	}
+	if err != nil {
+		log.Printf("error instantiating workout template: %v", err)
+		return nil, status.Error(codes.Internal, "error instantiating workout template")
+	}
+	return &workout.CreateWorkoutResponse{
+		Id: id,
+	}, nil
+}
+
+// getVisibleTemplate returns template available to the user, other users' templates are reported as not found.
+func (t *WorkoutTemplateAPI) getVisibleTemplate(ctx context.Context, id string) (model.Workout, error) {
+	userId, err := auth.GetUserId(ctx)
+	if err != nil {
+		return model.Workout{}, status.Error(codes.Internal, "user id not found in context")
+	}
+	template, err := t.wDb.GetWorkout(id)
+	if err != nil && !errors.Is(err, db.ErrWorkoutNotFound) {
+		log.Printf("error getting workout template: %v", err)
+		return model.Workout{}, status.Error(codes.Internal, "error getting workout template")
+	}
+	if err != nil || !template.IsVisibleTemplate(userId) {
+		return model.Workout{}, status.Error(codes.NotFound, "workout template not found")
+	}
+	return template, nil
+}
@@ -0,0 +1,163 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	workout "proto/workout/v1/generated"
+	"testing"
+	"workout-tracker-server/auth"
+	"workout-tracker-server/db"
+	"workout-tracker-server/mocks"
+	"workout-tracker-server/model"
+)
+
+const exerciseTestUserId = "2f6c1b0e-8a3d-4e5f-9b7c-1d2e3f4a5b6c"
+
+// UserExerciseAPISuite calls exercise service as authenticated user.
+type UserExerciseAPISuite struct {
+	suite.Suite
+	dbMock         *mocks.ExerciseDb
+	exerciseClient workout.ExerciseServiceClient
+	cleanup        func()
+}
+
+func TestUserExerciseAPISuite(t *testing.T) {
+	suite.Run(t, new(UserExerciseAPISuite))
+}
+
+func (s *UserExerciseAPISuite) SetupSuite() {
+	dbMock := mocks.NewExerciseDb(s.T())
+	lis := bufconn.Listen(1024 * 1024)
+
+	closeSrv := setupUserExerciseTestServer(s.T(), lis, dbMock)
+	client, closeCl := setupExerciseTestClient(s.T(), lis)
+
+	s.dbMock = dbMock
+	s.exerciseClient = client
+
+	s.cleanup = func() {
+		closeCl()
+		closeSrv()
+	}
+}
+
+func (s *UserExerciseAPISuite) TearDownSuite() {
+	s.cleanup()
+}
+
+func setupUserExerciseTestServer(t *testing.T, listener *bufconn.Listener, dbMock *mocks.ExerciseDb) func() {
+	server := grpc.NewServer(grpc.UnaryInterceptor(
+		func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			return handler(auth.WithUserId(ctx, exerciseTestUserId), req)
+		},
+	))
+	workout.RegisterExerciseServiceServer(server, NewExerciseAPI(dbMock))
+	go func() {
+		if err := server.Serve(listener); err != nil {
+			t.Fatalf("Server exited with error: %v", err)
+		}
+	}()
+	return func() {
+		server.Stop()
+	}
+}
+
+func (s *UserExerciseAPISuite) TestGetExercisesMergesCustomExercises() {
+	group := "legs"
+	ownerId := exerciseTestUserId
+	s.dbMock.EXPECT().GetUserExercises(exerciseTestUserId, group, "").Return([]model.Exercise{
+		{ID: "1", Name: "Bulgarian split squat", MuscleGroup: "LEGS", Category: "STRENGTH", OwnerID: &ownerId},
+		{ID: "2", Name: "Squat", MuscleGroup: "LEGS", Category: "STRENGTH"},
+	}, nil).Once()
+
+	resp, err := s.exerciseClient.GetExercises(context.Background(), &workout.GetExercisesRequest{MuscleGroupFilter: &group})
+
+	s.Require().NoError(err)
+	s.Require().Len(resp.Exercises, 2)
+	s.Require().Equal("1", resp.Exercises[0].Id)
+	s.Require().True(resp.Exercises[0].Custom)
+	s.Require().Equal("2", resp.Exercises[1].Id)
+	s.Require().False(resp.Exercises[1].Custom)
+}
+
+func (s *UserExerciseAPISuite) TestGetExercisesErrorFromDb() {
+	s.dbMock.EXPECT().GetUserExercises(exerciseTestUserId, "", "").Return(nil, fmt.Errorf("some err")).Once()
+
+	resp, err := s.exerciseClient.GetExercises(context.Background(), &workout.GetExercisesRequest{})
+
+	s.Require().Nil(resp)
+	s.assertStatusError(codes.Internal, "error getting exercises", err)
+}
+
+func (s *UserExerciseAPISuite) TestCreateExerciseBlankName() {
+	resp, err := s.exerciseClient.CreateExercise(context.Background(), &workout.CreateExerciseRequest{
+		Name: " ", MuscleGroup: "legs", Category: "strength",
+	})
+
+	s.Require().Nil(resp)
+	s.assertStatusError(codes.InvalidArgument, "exercise name, muscle group and category must not be blank", err)
+}
+
+func (s *UserExerciseAPISuite) TestCreateExercise() {
+	ownerId := exerciseTestUserId
+	s.dbMock.EXPECT().SaveExercise(model.Exercise{
+		Name: "Bulgarian split squat", MuscleGroup: "LEGS", Category: "STRENGTH", OwnerID: &ownerId,
+	}).Return("1", nil).Once()
+
+	resp, err := s.exerciseClient.CreateExercise(context.Background(), &workout.CreateExerciseRequest{
+		Name: "Bulgarian split squat ", MuscleGroup: "legs", Category: "Strength",
+	})
+
+	s.Require().NoError(err)
+	s.Require().Equal("1", resp.Id)
+	s.Require().Equal("LEGS", resp.MuscleGroup)
+	s.Require().True(resp.Custom)
+}
+
+func (s *UserExerciseAPISuite) TestUpdateGlobalExercise() {
+	id := "c3339fa8-f9d6-481d-b983-f9cdc24ca4d0"
+	s.dbMock.EXPECT().GetExercise(id).Return(model.Exercise{ID: id, Name: "Squat"}, nil).Once()
+
+	resp, err := s.exerciseClient.UpdateExercise(context.Background(), &workout.UpdateExerciseRequest{
+		Id: id, Name: "Back squat", MuscleGroup: "legs", Category: "strength",
+	})
+
+	s.Require().Nil(resp)
+	s.assertStatusError(codes.PermissionDenied, "global exercises can't be modified", err)
+}
+
+func (s *UserExerciseAPISuite) TestDeleteExerciseOfOtherUser() {
+	id := "d4b3c2a1-0f9e-4d8c-8b7a-6f5e4d3c2b1a"
+	otherUserId := "7e8f9a0b-1c2d-4e3f-8a4b-5c6d7e8f9a0b"
+	s.dbMock.EXPECT().GetExercise(id).Return(model.Exercise{ID: id, OwnerID: &otherUserId}, nil).Once()
+
+	resp, err := s.exerciseClient.DeleteExercise(context.Background(), &workout.DeleteExerciseRequest{Id: id})
+
+	s.Require().Nil(resp)
+	s.assertStatusError(codes.NotFound, "exercise not found", err)
+}
+
+func (s *UserExerciseAPISuite) TestDeleteExerciseInUse() {
+	id := "d4b3c2a1-0f9e-4d8c-8b7a-6f5e4d3c2b1a"
+	ownerId := exerciseTestUserId
+	s.dbMock.EXPECT().GetExercise(id).Return(model.Exercise{ID: id, OwnerID: &ownerId}, nil).Once()
+	s.dbMock.EXPECT().DeleteExercise(id).Return(db.ErrExerciseInUse).Once()
+
+	resp, err := s.exerciseClient.DeleteExercise(context.Background(), &workout.DeleteExerciseRequest{Id: id})
+
+	s.Require().Nil(resp)
+	s.assertStatusError(codes.FailedPrecondition, "exercise is used by workouts or workout history", err)
+}
+
+func (s *UserExerciseAPISuite) assertStatusError(code codes.Code, message string, err error) {
+	s.Require().NotNil(err, "Error is nil")
+	st, ok := status.FromError(err)
+	s.Require().True(ok, "Error is not a status error")
+	s.Require().Equal(code, st.Code(), "Error code is not as expected - got: %v, expected: %v", st.Code(), code.String())
+	s.Require().Equal(message, st.Message(), "Error message is incorrect")
+}
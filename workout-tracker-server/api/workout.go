@@ -4,7 +4,9 @@ import (
 	"cmp"
 	"context"
 	"errors"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/emptypb"
 	"log"
@@ -15,13 +17,25 @@ import (
 	"workout-tracker-server/model"
 )
 
+const (
+	// progressionHistorySessions is number of recently completed sessions of each exercise used for next session plans.
+	progressionHistorySessions = 5
+	// etagHeader is response header with etag of workout, gateway maps it to ETag HTTP header.
+	etagHeader = "etag"
+	// ifMatchHeader is request header with etag forwarded by gateway from If-Match HTTP header.
+	ifMatchHeader = "if-match"
+)
+
 type WorkoutAPI struct {
 	workout.UnimplementedWorkoutServiceServer
-	db db.WorkoutDb
+	db  db.WorkoutDb
+	hDb db.ExerciseHistoryDb
+	cDb db.CoachingDb
+	iDb db.IdempotencyDb
 }
 
-func NewWorkoutAPI(db db.WorkoutDb) *WorkoutAPI {
-	return &WorkoutAPI{db: db}
+func NewWorkoutAPI(db db.WorkoutDb, hDb db.ExerciseHistoryDb, cDb db.CoachingDb, iDb db.IdempotencyDb) *WorkoutAPI {
+	return &WorkoutAPI{db: db, hDb: hDb, cDb: cDb, iDb: iDb}
 }
 
 func validationError(errProvider func() error) error {
@@ -35,13 +49,31 @@ func (w *WorkoutAPI) CreateWorkout(ctx context.Context, rq *workout.CreateWorkou
 	if err := rq.Validate(); err != nil {
 		return nil, validationError(func() error { return err.(workout.CreateWorkoutRequestValidationError).Cause() })
 	}
-	userId, err := auth.GetUserId(ctx)
+	return runIdempotent(ctx, w.iDb, rq, rq.RequestId, &workout.CreateWorkoutResponse{}, func() (*workout.CreateWorkoutResponse, error) {
+		return w.createWorkout(ctx, rq)
+	})
+}
+
+func (w *WorkoutAPI) createWorkout(ctx context.Context, rq *workout.CreateWorkoutRequest) (*workout.CreateWorkoutResponse, error) {
+	userId, err := getAccountId(ctx, w.cDb, rq.ClientId)
 	if err != nil {
-		return nil, status.Error(codes.Internal, "user id not found in context")
+		return nil, err
 	}
 	wrk := model.FromWorkoutProto(rq.Workout)
+	if err := wrk.ValidateGroups(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := wrk.ValidateExercises(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
 	wrk.OwnerID = userId
 	id, err := w.db.SaveWorkout(wrk)
+	if errors.Is(err, db.ErrFolderNotFound) {
+		return nil, status.Error(codes.InvalidArgument, "folder not found")
+	}
+	if errors.Is(err, db.ErrExerciseNotFound) {
+		return nil, status.Error(codes.InvalidArgument, "exercise not found")
+	}
 	if err != nil {
 		log.Printf("error saving workout: %v", err)
 		return nil, status.Error(codes.Internal, "error saving workout")
@@ -55,25 +87,94 @@ func (w *WorkoutAPI) UpdateWorkout(ctx context.Context, rq *workout.UpdateWorkou
 	if err := rq.Validate(); err != nil {
 		return nil, validationError(func() error { return err.(workout.UpdateWorkoutRequestValidationError).Cause() })
 	}
+	wrk := model.FromWorkoutProto(rq.GetWorkout())
+	if err := validateWorkoutUpdate(wrk, rq.UpdateMask.GetPaths()); err != nil {
+		return nil, err
+	}
+	version, err := requestWorkoutVersion(ctx, rq.Etag)
+	if err != nil {
+		return nil, err
+	}
+	wrk.Version = version
 	if err := w.validateWorkoutOwner(ctx, rq.Workout.Id); err != nil {
 		return nil, err
 	}
-	if err := w.db.UpdateWorkout(model.FromWorkoutProto(rq.GetWorkout()), rq.UpdateMask); err != nil {
+	if err := w.db.UpdateWorkout(wrk, rq.UpdateMask); err != nil {
+		if errors.Is(err, db.ErrWorkoutVersionMismatch) {
+			return nil, status.Error(codes.Aborted, "workout was modified, get current etag and retry")
+		}
 		if errors.Is(err, db.ErrWorkoutExerciseNotFound) {
 			return nil, status.Error(codes.NotFound, "workout exercise not found")
 		}
+		if errors.Is(err, db.ErrExerciseGroupNotFound) {
+			return nil, status.Error(codes.NotFound, "exercise group not found")
+		}
+		if errors.Is(err, db.ErrFolderNotFound) {
+			return nil, status.Error(codes.InvalidArgument, "folder not found")
+		}
+		if errors.Is(err, db.ErrExerciseNotFound) {
+			return nil, status.Error(codes.InvalidArgument, "exercise not found")
+		}
 		log.Printf("error updating workout: %v", err)
 		return nil, status.Error(codes.Internal, "error updating workout")
 	}
+	//successful update increments the version checked above
+	setETagHeader(ctx, version+1)
 	return &emptypb.Empty{}, nil
 }
 
-func (w *WorkoutAPI) ListWorkouts(ctx context.Context, _ *emptypb.Empty) (*workout.ListWorkoutsResponse, error) {
-	userId, err := auth.GetUserId(ctx)
+// requestWorkoutVersion resolves expected workout version from etag of the request or from If-Match header.
+func requestWorkoutVersion(ctx context.Context, etag string) (int32, error) {
+	if etag == "" {
+		if values := metadata.ValueFromIncomingContext(ctx, ifMatchHeader); len(values) > 0 {
+			etag = values[0]
+		}
+	}
+	if etag == "" {
+		return 0, status.Error(codes.InvalidArgument, "etag is required")
+	}
+	version, err := model.ParseWorkoutETag(etag)
 	if err != nil {
-		return nil, status.Error(codes.Internal, "user id not found in context")
+		return 0, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return version, nil
+}
+
+func setETagHeader(ctx context.Context, version int32) {
+	if err := grpc.SetHeader(ctx, metadata.Pairs(etagHeader, model.WorkoutETag(version))); err != nil {
+		log.Printf("error setting etag header: %v", err)
+	}
+}
+
+// validateWorkoutUpdate checks updated exercises and groups, group labels of exercises are checked only when groups
+// are updated together with exercises, otherwise they are resolved against stored groups.
+func validateWorkoutUpdate(wrk model.Workout, paths []string) error {
+	if slices.Contains(paths, "exercises") {
+		if err := wrk.ValidateExercises(); err != nil {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+	if !slices.Contains(paths, "groups") {
+		return nil
+	}
+	if !slices.Contains(paths, "exercises") {
+		wrk.Exercises = nil
+	}
+	if err := wrk.ValidateGroups(); err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
 	}
-	workouts, err := w.db.GetWorkouts(userId)
+	return nil
+}
+
+func (w *WorkoutAPI) ListWorkouts(ctx context.Context, rq *workout.ListWorkoutsRequest) (*workout.ListWorkoutsResponse, error) {
+	if err := rq.Validate(); err != nil {
+		return nil, validationError(func() error { return err.(workout.ListWorkoutsRequestValidationError) })
+	}
+	userId, err := getAccountId(ctx, w.cDb, rq.ClientId)
+	if err != nil {
+		return nil, err
+	}
+	workouts, err := w.db.GetWorkouts(userId, model.WorkoutFilter{Tags: rq.Tags, FolderID: rq.FolderId})
 	if err != nil {
 		log.Printf("error listing workouts: %v", err)
 		return nil, status.Error(codes.Internal, "error listing workouts")
@@ -89,7 +190,7 @@ func (w *WorkoutAPI) GetWorkout(ctx context.Context, rq *workout.GetWorkoutReque
 	if err := rq.Validate(); err != nil {
 		return nil, validationError(func() error { return err.(workout.GetWorkoutRequestValidationError) })
 	}
-	if err := w.validateWorkoutOwner(ctx, rq.Id); err != nil {
+	if err := w.validateWorkoutAccess(ctx, rq.Id); err != nil {
 		return nil, err
 	}
 	if wrk, err := w.db.GetWorkout(rq.Id); err != nil {
@@ -104,6 +205,7 @@ func (w *WorkoutAPI) GetWorkout(ctx context.Context, rq *workout.GetWorkoutReque
 				return cmp.Compare(e1.Order, e2.Order)
 			},
 		)
+		setETagHeader(ctx, wrk.Version)
 		return &workout.GetWorkoutResponse{
 			Workout: wrk.ToProto(),
 		}, nil
@@ -124,6 +226,174 @@ func (w *WorkoutAPI) DeleteWorkout(ctx context.Context, rq *workout.DeleteWorkou
 	return &emptypb.Empty{}, nil
 }
 
+func (w *WorkoutAPI) ListDeletedWorkouts(ctx context.Context, rq *workout.ListWorkoutsRequest) (*workout.ListWorkoutsResponse, error) {
+	if err := rq.Validate(); err != nil {
+		return nil, validationError(func() error { return err.(workout.ListWorkoutsRequestValidationError) })
+	}
+	userId, err := getAccountId(ctx, w.cDb, rq.ClientId)
+	if err != nil {
+		return nil, err
+	}
+	workouts, err := w.db.GetDeletedWorkouts(userId, model.WorkoutFilter{Tags: rq.Tags, FolderID: rq.FolderId})
+	if err != nil {
+		log.Printf("error listing deleted workouts: %v", err)
+		return nil, status.Error(codes.Internal, "error listing deleted workouts")
+	}
+	var resp workout.ListWorkoutsResponse
+	for _, wrk := range workouts {
+		resp.Workouts = append(resp.Workouts, wrk.ToProto())
+	}
+	return &resp, nil
+}
+
+// RestoreWorkout moves owner's workout back from trash, purged workouts can't be restored. Like delete, restore
+// is not allowed to coaches.
+func (w *WorkoutAPI) RestoreWorkout(ctx context.Context, rq *workout.RestoreWorkoutRequest) (*emptypb.Empty, error) {
+	if err := rq.Validate(); err != nil {
+		return nil, validationError(func() error { return err.(workout.RestoreWorkoutRequestValidationError) })
+	}
+	userId, err := auth.GetUserId(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "user id not found in context")
+	}
+	if err := w.db.RestoreWorkout(rq.Id, userId); err != nil {
+		if errors.Is(err, db.ErrWorkoutNotFound) {
+			return nil, status.Error(codes.NotFound, "deleted workout not found")
+		}
+		log.Printf("error restoring workout: %v", err)
+		return nil, status.Error(codes.Internal, "error restoring workout")
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// GetNextSessionPlan suggests targets of workout exercises for the next session from recently completed sessions.
+func (w *WorkoutAPI) GetNextSessionPlan(ctx context.Context, rq *workout.GetNextSessionPlanRequest) (*workout.NextSessionPlan, error) {
+	if err := rq.Validate(); err != nil {
+		return nil, validationError(func() error { return err.(workout.GetNextSessionPlanRequestValidationError) })
+	}
+	ownerId, err := getValidatedWorkoutOwnerId(ctx, w.db, w.cDb, rq.WorkoutId)
+	if err != nil {
+		return nil, err
+	}
+	wrk, err := w.db.GetWorkout(rq.WorkoutId)
+	if err != nil {
+		if errors.Is(err, db.ErrWorkoutNotFound) {
+			return nil, status.Error(codes.NotFound, "workout not found")
+		}
+		log.Printf("error getting workout: %v", err)
+		return nil, status.Error(codes.Internal, "error getting workout")
+	}
+	var exerciseIds []string
+	for _, ex := range wrk.Exercises {
+		exerciseIds = append(exerciseIds, ex.ExerciseID)
+	}
+	history, err := w.hDb.GetExerciseHistory(ownerId, exerciseIds, progressionHistorySessions)
+	if err != nil {
+		log.Printf("error getting exercise history: %v", err)
+		return nil, status.Error(codes.Internal, "error getting exercise history")
+	}
+	strategy := model.NewProgressionStrategy(rq.Strategy)
+	plan := &workout.NextSessionPlan{
+		WorkoutId: wrk.ID,
+		Strategy:  rq.Strategy,
+	}
+	if plan.Strategy == workout.ProgressionStrategy_PROGRESSION_STRATEGY_UNSPECIFIED {
+		plan.Strategy = workout.ProgressionStrategy_PROGRESSION_STRATEGY_LINEAR
+	}
+	exercises := slices.SortedFunc(slices.Values(wrk.Exercises), func(e1, e2 model.WorkoutExercise) int {
+		return cmp.Compare(e1.Order, e2.Order)
+	})
+	for _, ex := range exercises {
+		plan.Exercises = append(plan.Exercises, strategy.Suggest(ex, history[ex.ExerciseID]).ToProto())
+	}
+	return plan, nil
+}
+
+// CopyWorkout deep copies user's workout under new name.
+func (w *WorkoutAPI) CopyWorkout(ctx context.Context, rq *workout.CopyWorkoutRequest) (*workout.CreateWorkoutResponse, error) {
+	if err := rq.Validate(); err != nil {
+		return nil, validationError(func() error { return err.(workout.CopyWorkoutRequestValidationError) })
+	}
+	if err := w.validateWorkoutOwner(ctx, rq.Id); err != nil {
+		return nil, err
+	}
+	userId, err := auth.GetUserId(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "user id not found in context")
+	}
+	id, err := w.db.CopyWorkout(rq.Id, model.WorkoutCopy{OwnerID: userId, Name: rq.NewName})
+	if errors.Is(err, db.ErrWorkoutNotFound) {
+		return nil, status.Error(codes.NotFound, "workout not found")
+	}
+	if err != nil {
+		log.Printf("error copying workout: %v", err)
+		return nil, status.Error(codes.Internal, "error copying workout")
+	}
+	return &workout.CreateWorkoutResponse{
+		Id: id,
+	}, nil
+}
+
+func (w *WorkoutAPI) ListWorkoutVersions(ctx context.Context, rq *workout.ListWorkoutVersionsRequest) (*workout.ListWorkoutVersionsResponse, error) {
+	if err := rq.Validate(); err != nil {
+		return nil, validationError(func() error { return err.(workout.ListWorkoutVersionsRequestValidationError) })
+	}
+	if err := w.validateWorkoutAccess(ctx, rq.Id); err != nil {
+		return nil, err
+	}
+	versions, err := w.db.GetWorkoutVersions(rq.Id)
+	if err != nil {
+		log.Printf("error listing workout versions: %v", err)
+		return nil, status.Error(codes.Internal, "error listing workout versions")
+	}
+	var resp workout.ListWorkoutVersionsResponse
+	for _, version := range versions {
+		resp.Versions = append(resp.Versions, version.ToProto())
+	}
+	return &resp, nil
+}
+
+func (w *WorkoutAPI) GetWorkoutVersion(ctx context.Context, rq *workout.GetWorkoutVersionRequest) (*workout.WorkoutVersion, error) {
+	if err := rq.Validate(); err != nil {
+		return nil, validationError(func() error { return err.(workout.GetWorkoutVersionRequestValidationError) })
+	}
+	if err := w.validateWorkoutAccess(ctx, rq.Id); err != nil {
+		return nil, err
+	}
+	version, err := w.db.GetWorkoutVersion(rq.Id, rq.Version)
+	if errors.Is(err, db.ErrWorkoutVersionNotFound) {
+		return nil, status.Error(codes.NotFound, "workout version not found")
+	}
+	if err != nil {
+		log.Printf("error getting workout version: %v", err)
+		return nil, status.Error(codes.Internal, "error getting workout version")
+	}
+	return version.ToProto(), nil
+}
+
+// RestoreWorkoutVersion brings workout back to content of given version, restored content becomes the newest version.
+func (w *WorkoutAPI) RestoreWorkoutVersion(ctx context.Context, rq *workout.GetWorkoutVersionRequest) (*workout.WorkoutVersion, error) {
+	if err := rq.Validate(); err != nil {
+		return nil, validationError(func() error { return err.(workout.GetWorkoutVersionRequestValidationError) })
+	}
+	if err := w.validateWorkoutOwner(ctx, rq.Id); err != nil {
+		return nil, err
+	}
+	version, err := w.db.RestoreWorkoutVersion(rq.Id, rq.Version)
+	if errors.Is(err, db.ErrWorkoutVersionNotFound) {
+		return nil, status.Error(codes.NotFound, "workout version not found")
+	}
+	if errors.Is(err, db.ErrWorkoutVersionExerciseDeleted) {
+		return nil, status.Error(codes.FailedPrecondition, "workout version references deleted exercise")
+	}
+	if err != nil {
+		log.Printf("error restoring workout version: %v", err)
+		return nil, status.Error(codes.Internal, "error restoring workout version")
+	}
+	return version.ToProto(), nil
+}
+
+// validateWorkoutOwner allows modifying, deleting and copying the workout only to its owner.
 func (w *WorkoutAPI) validateWorkoutOwner(ctx context.Context, workoutId string) error {
 	userId, err := auth.GetUserId(ctx)
 	if err != nil {
@@ -142,3 +412,9 @@ func (w *WorkoutAPI) validateWorkoutOwner(ctx context.Context, workoutId string)
 	}
 	return nil
 }
+
+// validateWorkoutAccess allows viewing the workout to its owner and to active coach of the owner.
+func (w *WorkoutAPI) validateWorkoutAccess(ctx context.Context, workoutId string) error {
+	_, err := getValidatedWorkoutOwnerId(ctx, w.db, w.cDb, workoutId)
+	return err
+}
@@ -0,0 +1,218 @@
+package api
+
+import (
+	"context"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+	"net"
+	workout "proto/workout/v1/generated"
+	"testing"
+	"workout-tracker-server/auth"
+	"workout-tracker-server/db"
+	"workout-tracker-server/mocks"
+	"workout-tracker-server/model"
+)
+
+const (
+	coachTestUserId  = "5a1d3c2b-4e6f-4a8b-9c0d-1e2f3a4b5c6d"
+	clientTestUserId = "6b2e4d3c-5f7a-4b9c-8d1e-2f3a4b5c6d7e"
+	clientWorkoutId  = "7c3f5e4d-6a8b-4c0d-9e2f-3a4b5c6d7e8f"
+)
+
+type WorkoutAPISuite struct {
+	suite.Suite
+	wDbMock       *mocks.WorkoutDb
+	cDbMock       *mocks.CoachingDb
+	workoutClient workout.WorkoutServiceClient
+	cleanup       func()
+}
+
+func TestWorkoutAPISuite(t *testing.T) {
+	suite.Run(t, new(WorkoutAPISuite))
+}
+
+func (s *WorkoutAPISuite) SetupSuite() {
+	wDbMock := mocks.NewWorkoutDb(s.T())
+	cDbMock := mocks.NewCoachingDb(s.T())
+	lis := bufconn.Listen(1024 * 1024)
+
+	closeSrv := setupWorkoutTestServer(s.T(), lis, wDbMock, cDbMock)
+	client, closeCl := setupWorkoutTestClient(s.T(), lis)
+
+	s.wDbMock = wDbMock
+	s.cDbMock = cDbMock
+	s.workoutClient = client
+
+	s.cleanup = func() {
+		closeCl()
+		closeSrv()
+	}
+}
+
+func (s *WorkoutAPISuite) TearDownSuite() {
+	s.cleanup()
+}
+
+// setupWorkoutTestServer serves requests of coach of the client owning clientWorkoutId.
+func setupWorkoutTestServer(t *testing.T, listener *bufconn.Listener, wDbMock *mocks.WorkoutDb, cDbMock *mocks.CoachingDb) func() {
+	server := grpc.NewServer(grpc.UnaryInterceptor(
+		func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			return handler(auth.WithUserId(ctx, coachTestUserId), req)
+		},
+	))
+	workout.RegisterWorkoutServiceServer(server, NewWorkoutAPI(wDbMock, mocks.NewExerciseHistoryDb(t), cDbMock, mocks.NewIdempotencyDb(t)))
+	go func() {
+		if err := server.Serve(listener); err != nil {
+			t.Fatalf("Server exited with error: %v", err)
+		}
+	}()
+	return func() {
+		server.Stop()
+	}
+}
+
+func setupWorkoutTestClient(t *testing.T, listener *bufconn.Listener) (workout.WorkoutServiceClient, func()) {
+	client, err := grpc.NewClient("passthrough://",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return listener.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("error creating client: %v", err)
+	}
+	return workout.NewWorkoutServiceClient(client), func() { client.Close() }
+}
+
+func (s *WorkoutAPISuite) TestCoachGetsClientWorkout() {
+	s.wDbMock.EXPECT().GetWorkoutOwner(clientWorkoutId).Return(clientTestUserId, nil).Once()
+	s.cDbMock.EXPECT().IsCoachOf(coachTestUserId, clientTestUserId).Return(true, nil).Once()
+	s.wDbMock.EXPECT().GetWorkout(clientWorkoutId).Return(model.Workout{ID: clientWorkoutId, OwnerID: clientTestUserId, Name: "Leg day", Version: 1}, nil).Once()
+
+	resp, err := s.workoutClient.GetWorkout(context.Background(), &workout.GetWorkoutRequest{Id: clientWorkoutId})
+
+	s.Require().NoError(err)
+	s.Require().Equal("Leg day", resp.Workout.Name)
+}
+
+func (s *WorkoutAPISuite) TestGetWorkoutOfOtherUser() {
+	s.wDbMock.EXPECT().GetWorkoutOwner(clientWorkoutId).Return(clientTestUserId, nil).Once()
+	s.cDbMock.EXPECT().IsCoachOf(coachTestUserId, clientTestUserId).Return(false, nil).Once()
+
+	resp, err := s.workoutClient.GetWorkout(context.Background(), &workout.GetWorkoutRequest{Id: clientWorkoutId})
+
+	s.Require().Nil(resp)
+	s.assertStatusError(codes.PermissionDenied, "access forbidden", err)
+}
+
+func (s *WorkoutAPISuite) TestCoachListsClientWorkoutVersions() {
+	s.wDbMock.EXPECT().GetWorkoutOwner(clientWorkoutId).Return(clientTestUserId, nil).Once()
+	s.cDbMock.EXPECT().IsCoachOf(coachTestUserId, clientTestUserId).Return(true, nil).Once()
+	s.wDbMock.EXPECT().GetWorkoutVersions(clientWorkoutId).Return(nil, nil).Once()
+
+	resp, err := s.workoutClient.ListWorkoutVersions(context.Background(), &workout.ListWorkoutVersionsRequest{Id: clientWorkoutId})
+
+	s.Require().NoError(err)
+	s.Require().Empty(resp.Versions)
+}
+
+func (s *WorkoutAPISuite) TestCoachCreatesClientWorkout() {
+	clientId := clientTestUserId
+	s.cDbMock.EXPECT().IsCoachOf(coachTestUserId, clientTestUserId).Return(true, nil).Once()
+	s.wDbMock.EXPECT().SaveWorkout(mock.MatchedBy(func(wrk model.Workout) bool {
+		return wrk.OwnerID == clientTestUserId
+	})).Return(clientWorkoutId, nil).Once()
+
+	resp, err := s.workoutClient.CreateWorkout(context.Background(), &workout.CreateWorkoutRequest{
+		Workout:  &workout.Workout{Name: "Leg day"},
+		ClientId: &clientId,
+	})
+
+	s.Require().NoError(err)
+	s.Require().Equal(clientWorkoutId, resp.Id)
+}
+
+func (s *WorkoutAPISuite) TestCoachCantUpdateClientWorkout() {
+	s.wDbMock.EXPECT().IsWorkoutOwner(clientWorkoutId, coachTestUserId).Return(false, nil).Once()
+
+	resp, err := s.workoutClient.UpdateWorkout(context.Background(), &workout.UpdateWorkoutRequest{
+		Workout:    &workout.Workout{Id: clientWorkoutId, Name: "Renamed"},
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"name"}},
+		Etag:       model.WorkoutETag(1),
+	})
+
+	s.Require().Nil(resp)
+	s.assertStatusError(codes.PermissionDenied, "access forbidden", err)
+}
+
+func (s *WorkoutAPISuite) TestCoachCantDeleteClientWorkout() {
+	s.wDbMock.EXPECT().IsWorkoutOwner(clientWorkoutId, coachTestUserId).Return(false, nil).Once()
+
+	resp, err := s.workoutClient.DeleteWorkout(context.Background(), &workout.DeleteWorkoutRequest{Id: clientWorkoutId})
+
+	s.Require().Nil(resp)
+	s.assertStatusError(codes.PermissionDenied, "access forbidden", err)
+}
+
+func (s *WorkoutAPISuite) TestCoachCantRestoreClientWorkout() {
+	s.wDbMock.EXPECT().RestoreWorkout(clientWorkoutId, coachTestUserId).Return(db.ErrWorkoutNotFound).Once()
+
+	resp, err := s.workoutClient.RestoreWorkout(context.Background(), &workout.RestoreWorkoutRequest{Id: clientWorkoutId})
+
+	s.Require().Nil(resp)
+	s.assertStatusError(codes.NotFound, "deleted workout not found", err)
+}
+
+func (s *WorkoutAPISuite) TestCoachListsClientDeletedWorkouts() {
+	clientId := clientTestUserId
+	s.cDbMock.EXPECT().IsCoachOf(coachTestUserId, clientTestUserId).Return(true, nil).Once()
+	s.wDbMock.EXPECT().GetDeletedWorkouts(clientTestUserId, model.WorkoutFilter{}).Return([]model.Workout{
+		{ID: clientWorkoutId, OwnerID: clientTestUserId, Name: "Back Day"},
+	}, nil).Once()
+
+	resp, err := s.workoutClient.ListDeletedWorkouts(context.Background(), &workout.ListWorkoutsRequest{ClientId: &clientId})
+
+	s.Require().NoError(err)
+	s.Require().Len(resp.Workouts, 1)
+	s.Require().Equal(clientWorkoutId, resp.Workouts[0].Id)
+}
+
+func (s *WorkoutAPISuite) TestCoachCantCopyClientWorkout() {
+	s.wDbMock.EXPECT().IsWorkoutOwner(clientWorkoutId, coachTestUserId).Return(false, nil).Once()
+
+	resp, err := s.workoutClient.CopyWorkout(context.Background(), &workout.CopyWorkoutRequest{Id: clientWorkoutId, NewName: "Copy"})
+
+	s.Require().Nil(resp)
+	s.assertStatusError(codes.PermissionDenied, "access forbidden", err)
+}
+
+func (s *WorkoutAPISuite) TestCoachCantRestoreClientWorkoutVersion() {
+	s.wDbMock.EXPECT().IsWorkoutOwner(clientWorkoutId, coachTestUserId).Return(false, nil).Once()
+
+	resp, err := s.workoutClient.RestoreWorkoutVersion(context.Background(), &workout.GetWorkoutVersionRequest{Id: clientWorkoutId, Version: 1})
+
+	s.Require().Nil(resp)
+	s.assertStatusError(codes.PermissionDenied, "access forbidden", err)
+}
+
+func (s *WorkoutAPISuite) TestRestoreWorkoutVersionWithDeletedExercise() {
+	s.wDbMock.EXPECT().IsWorkoutOwner(clientWorkoutId, coachTestUserId).Return(true, nil).Once()
+	s.wDbMock.EXPECT().RestoreWorkoutVersion(clientWorkoutId, int32(1)).Return(model.WorkoutVersion{}, db.ErrWorkoutVersionExerciseDeleted).Once()
+
+	resp, err := s.workoutClient.RestoreWorkoutVersion(context.Background(), &workout.GetWorkoutVersionRequest{Id: clientWorkoutId, Version: 1})
+
+	s.Require().Nil(resp)
+	s.assertStatusError(codes.FailedPrecondition, "workout version references deleted exercise", err)
+}
+
+func (s *WorkoutAPISuite) assertStatusError(code codes.Code, message string, err error) {
+	s.Require().NotNil(err, "Error is nil")
+	st, ok := status.FromError(err)
+	s.Require().True(ok, "Error is not a status error")
+	s.Require().Equal(code, st.Code(), "Error code is not as expected - got: %v, expected: %v", st.Code(), code.String())
+	s.Require().Equal(message, st.Message(), "Error message is incorrect")
+}
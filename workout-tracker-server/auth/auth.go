@@ -35,12 +35,36 @@ func (a *Authorization) Auth(ctx context.Context) (context.Context, error) {
 	if err != nil {
 		return nil, err
 	}
-	ctx = context.WithValue(ctx, userIdCtxKey, userId)
-	return ctx, nil
+	return WithUserId(ctx, userId), nil
 }
 
-func Secured(_ context.Context, meta interceptors.CallMeta) bool {
-	return meta.Service != generated.ExerciseService_ServiceDesc.ServiceName
+// OptionalAuth authenticates user when token is sent, calls without token pass through anonymously.
+func (a *Authorization) OptionalAuth(ctx context.Context) (context.Context, error) {
+	userId, err := readUserId(ctx, a.SigningKey)
+	if errors.Is(err, errMissingToken) {
+		return ctx, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return WithUserId(ctx, userId), nil
+}
+
+// WithUserId returns context of authenticated user.
+func WithUserId(ctx context.Context, userId string) context.Context {
+	return context.WithValue(ctx, userIdCtxKey, userId)
+}
+
+// Secured selects calls requiring JWT. Calendar feed is authenticated by its own token, since calendar apps
+// subscribing to it can't send authorization header. Exercise catalog is public.
+func Secured(ctx context.Context, meta interceptors.CallMeta) bool {
+	return !OptionallySecured(ctx, meta) &&
+		meta.FullMethod() != generated.CalendarService_GetCalendarFeed_FullMethodName
+}
+
+// OptionallySecured selects public calls, which return additional data to authenticated user.
+func OptionallySecured(_ context.Context, meta interceptors.CallMeta) bool {
+	return meta.FullMethod() == generated.ExerciseService_GetExercises_FullMethodName
 }
 
 func readUserId(ctx context.Context, signingKey []byte) (string, error) {
@@ -0,0 +1,49 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+)
+
+var ErrCalendarFeedNotFound = fmt.Errorf("calendar feed not found")
+
+const (
+	upsertCalendarFeed = `INSERT INTO calendar_feed (user_id, token_hash) VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET token_hash = EXCLUDED.token_hash, created_at = now()`
+	deleteCalendarFeed      = "DELETE FROM calendar_feed WHERE user_id = $1"
+	selectCalendarFeedOwner = "SELECT user_id FROM calendar_feed WHERE token_hash = $1"
+)
+
+type CalendarDb interface {
+	SaveCalendarFeed(userId, tokenHash string) error
+	DeleteCalendarFeed(userId string) error
+	GetCalendarFeedOwner(tokenHash string) (string, error)
+}
+
+// SaveCalendarFeed stores user's calendar feed token hash, replacing the previous one.
+func (p *PostgresDb) SaveCalendarFeed(userId, tokenHash string) error {
+	_, err := p.db.Exec(context.Background(), upsertCalendarFeed, userId, tokenHash)
+	return err
+}
+
+func (p *PostgresDb) DeleteCalendarFeed(userId string) error {
+	tag, err := p.db.Exec(context.Background(), deleteCalendarFeed, userId)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrCalendarFeedNotFound
+	}
+	return nil
+}
+
+func (p *PostgresDb) GetCalendarFeedOwner(tokenHash string) (string, error) {
+	var userId string
+	err := p.db.QueryRow(context.Background(), selectCalendarFeedOwner, tokenHash).Scan(&userId)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", ErrCalendarFeedNotFound
+	}
+	return userId, err
+}
@@ -0,0 +1,127 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"workout-tracker-server/model"
+)
+
+var (
+	ErrCoachingRelationshipNotFound = fmt.Errorf("coaching relationship not found")
+	ErrCoachingRelationshipExists   = fmt.Errorf("coaching relationship already exists")
+	ErrCoachingClientNotFound       = fmt.Errorf("coaching client not found")
+	ErrCoachingSelfInvite           = fmt.Errorf("coach can't invite themselves")
+)
+
+const (
+	coachingColumns = "c.id, c.coach, c.client_email, c.client, c.created_at, c.accepted_at"
+
+	insertCoachingInvite = `INSERT INTO coaching (id, coach, client_email, client) VALUES ($1, $2, $3, $4)
+		ON CONFLICT DO NOTHING RETURNING ` + coachingColumns
+	selectCoachingRelationship  = "SELECT " + coachingColumns + " FROM coaching c WHERE c.id = $1"
+	selectCoachingRelationships = "SELECT " + coachingColumns + ` FROM coaching c
+		WHERE c.coach = $1 OR c.client = $1 ORDER BY c.created_at`
+	// invite is bound to the account resolved at invite time, only that account can accept it
+	acceptCoachingInvite = `UPDATE coaching SET accepted_at = now()
+		WHERE id = $1 AND client = $2 AND accepted_at IS NULL`
+	selectIsCoach              = "SELECT EXISTS (SELECT 1 FROM coaching WHERE coach = $1 AND client = $2 AND accepted_at IS NOT NULL)"
+	deleteCoachingRelationship = "DELETE FROM coaching WHERE id = $1"
+)
+
+type CoachingDb interface {
+	SaveCoachingInvite(coachId, clientEmail string) (model.CoachingRelationship, error)
+	GetCoachingRelationship(id string) (model.CoachingRelationship, error)
+	GetCoachingRelationships(userId string) ([]model.CoachingRelationship, error)
+	AcceptCoachingInvite(id, clientId string) error
+	DeleteCoachingRelationship(id string) error
+	IsCoachOf(coachId, clientId string) (bool, error)
+}
+
+// SaveCoachingInvite creates pending invite for the user registered with the email, returns ErrCoachingClientNotFound
+// when there is no such user. Each coach can invite given client only once.
+func (p *PostgresDb) SaveCoachingInvite(coachId, clientEmail string) (model.CoachingRelationship, error) {
+	var clientId string
+	err := p.db.QueryRow(context.Background(), selectUserIdByEmail, clientEmail).Scan(&clientId)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return model.CoachingRelationship{}, ErrCoachingClientNotFound
+	}
+	if err != nil {
+		return model.CoachingRelationship{}, err
+	}
+	if clientId == coachId {
+		return model.CoachingRelationship{}, ErrCoachingSelfInvite
+	}
+	relationship, err := scanCoachingRelationship(p.db.QueryRow(context.Background(), insertCoachingInvite,
+		uuid.New().String(), coachId, clientEmail, clientId))
+	if errors.Is(err, ErrCoachingRelationshipNotFound) {
+		return model.CoachingRelationship{}, ErrCoachingRelationshipExists
+	}
+	return relationship, err
+}
+
+func (p *PostgresDb) GetCoachingRelationship(id string) (model.CoachingRelationship, error) {
+	return scanCoachingRelationship(p.db.QueryRow(context.Background(), selectCoachingRelationship, id))
+}
+
+// GetCoachingRelationships returns relationships of the user as coach or client including pending invites, sorted by
+// creation time.
+func (p *PostgresDb) GetCoachingRelationships(userId string) ([]model.CoachingRelationship, error) {
+	rows, err := p.db.Query(context.Background(), selectCoachingRelationships, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var relationships []model.CoachingRelationship
+	for rows.Next() {
+		relationship, err := scanCoachingRelationship(rows)
+		if err != nil {
+			return nil, err
+		}
+		relationships = append(relationships, relationship)
+	}
+	return relationships, rows.Err()
+}
+
+// AcceptCoachingInvite activates pending invite, invite must be addressed to account of the client.
+func (p *PostgresDb) AcceptCoachingInvite(id, clientId string) error {
+	tag, err := p.db.Exec(context.Background(), acceptCoachingInvite, id, clientId)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrCoachingRelationshipNotFound
+	}
+	return nil
+}
+
+func (p *PostgresDb) DeleteCoachingRelationship(id string) error {
+	tag, err := p.db.Exec(context.Background(), deleteCoachingRelationship, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrCoachingRelationshipNotFound
+	}
+	return nil
+}
+
+// IsCoachOf reports whether the client accepted invite of the coach.
+func (p *PostgresDb) IsCoachOf(coachId, clientId string) (bool, error) {
+	var isCoach bool
+	err := p.db.QueryRow(context.Background(), selectIsCoach, coachId, clientId).Scan(&isCoach)
+	return isCoach, err
+}
+
+func scanCoachingRelationship(row pgx.Row) (model.CoachingRelationship, error) {
+	var relationship model.CoachingRelationship
+	err := row.Scan(&relationship.ID, &relationship.CoachID, &relationship.ClientEmail, &relationship.ClientID,
+		&relationship.CreatedAt, &relationship.AcceptedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return model.CoachingRelationship{}, ErrCoachingRelationshipNotFound
+	}
+	return relationship, err
+}
@@ -0,0 +1,233 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"strconv"
+	"workout-tracker-server/model"
+)
+
+var (
+	ErrCommentNotFound       = fmt.Errorf("comment not found")
+	ErrCommentTargetNotFound = fmt.Errorf("comment target not found")
+	ErrNotificationNotFound  = fmt.Errorf("notification not found")
+)
+
+const (
+	commentColumns = `c.id, c.author, c.workout_id, c.workout_exercise_id, c.schedule_id, c.parent_id, c.body,
+		(SELECT count(*) FROM comment r WHERE r.parent_id = c.id), c.created_at, c.updated_at`
+
+	insertComment = `INSERT INTO comment (id, author, workout_id, workout_exercise_id, schedule_id, parent_id, body)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING created_at`
+	// author of the parent comment is notified about the reply unless they reply to themselves
+	insertReplyNotification = `INSERT INTO notification (id, "user", type, comment_id)
+		SELECT $1, author, $2, $3 FROM comment WHERE id = $4 AND author <> $5`
+	selectComment = "SELECT " + commentColumns + " FROM comment c WHERE c.id = $1"
+	updateComment = `UPDATE comment SET body = $2, updated_at = now() WHERE id = $1`
+	deleteComment = "DELETE FROM comment WHERE id = $1"
+
+	// targets of templates can't be commented, sessions are never shared
+	selectWorkoutCommentAccess = `SELECT w.owner, EXISTS (SELECT 1 FROM workout_share s WHERE s.workout_id = w.id AND s.recipient_id = $2)
+		FROM workout w WHERE w.id = $1 AND NOT w.template AND w.deleted_at IS NULL`
+	selectWorkoutExerciseCommentAccess = `SELECT w.owner, EXISTS (SELECT 1 FROM workout_share s WHERE s.workout_id = w.id AND s.recipient_id = $2)
+		FROM workout_exercise we JOIN workout w ON w.id = we.workout_id
+		WHERE we.workout_exercise_id = $1 AND NOT w.template AND w.deleted_at IS NULL`
+	selectScheduleCommentAccess = "SELECT owner, false FROM workout_schedule WHERE id = $1"
+
+	notificationColumns    = `n.id, n."user", n.type, n.created_at, n.read_at`
+	updateNotificationRead = `UPDATE notification SET read_at = coalesce(read_at, now()) WHERE id = $1 AND "user" = $2`
+)
+
+type CommentDb interface {
+	// SaveComment stores the comment and notifies author of the parent comment about the reply.
+	SaveComment(comment model.Comment) (model.Comment, error)
+	GetComment(id string) (model.Comment, error)
+	ListComments(filter model.CommentFilter) ([]model.Comment, error)
+	UpdateComment(id, body string) (model.Comment, error)
+	DeleteComment(id string) error
+	GetCommentTargetAccess(target model.CommentTarget, userId string) (model.CommentTargetAccess, error)
+	ListNotifications(userId string, filter model.NotificationFilter) ([]model.Notification, error)
+	MarkNotificationRead(id, userId string) error
+}
+
+func (p *PostgresDb) SaveComment(comment model.Comment) (model.Comment, error) {
+	ctx := context.Background()
+	tx, err := p.db.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.ReadCommitted, AccessMode: pgx.ReadWrite})
+	if err != nil {
+		return model.Comment{}, err
+	}
+	defer tx.Rollback(ctx)
+	comment.ID = uuid.New().String()
+	err = tx.QueryRow(ctx, insertComment, comment.ID, comment.AuthorID, comment.Target.WorkoutID, comment.Target.WorkoutExerciseID,
+		comment.Target.ScheduleID, comment.ParentID, comment.Body,
+	).Scan(&comment.CreatedAt)
+	if err != nil {
+		return model.Comment{}, err
+	}
+	if comment.ParentID != nil {
+		_, err = tx.Exec(ctx, insertReplyNotification, uuid.New().String(), model.NotificationCommentReply, comment.ID, *comment.ParentID, comment.AuthorID)
+		if err != nil {
+			return model.Comment{}, err
+		}
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return model.Comment{}, err
+	}
+	return comment, nil
+}
+
+func (p *PostgresDb) GetComment(id string) (model.Comment, error) {
+	return scanComment(p.db.QueryRow(context.Background(), selectComment, id))
+}
+
+// ListComments returns top level comments of the target or replies to the parent, sorted by creation time and id.
+func (p *PostgresDb) ListComments(filter model.CommentFilter) ([]model.Comment, error) {
+	query := "SELECT " + commentColumns + " FROM comment c WHERE "
+	var args []any
+	arg := func(value any) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
+	}
+	switch {
+	case filter.ParentID != nil:
+		query += "c.parent_id = " + arg(*filter.ParentID)
+	case filter.Target != nil && filter.Target.WorkoutID != nil:
+		query += "c.parent_id IS NULL AND c.workout_id = " + arg(*filter.Target.WorkoutID)
+	case filter.Target != nil && filter.Target.WorkoutExerciseID != nil:
+		query += "c.parent_id IS NULL AND c.workout_exercise_id = " + arg(*filter.Target.WorkoutExerciseID)
+	case filter.Target != nil && filter.Target.ScheduleID != nil:
+		query += "c.parent_id IS NULL AND c.schedule_id = " + arg(*filter.Target.ScheduleID)
+	default:
+		return nil, fmt.Errorf("comment filter requires target or parent")
+	}
+	if filter.After != nil {
+		query += fmt.Sprintf(" AND (c.created_at, c.id) > (%s, %s::uuid)", arg(filter.After.CreatedAt), arg(filter.After.ID))
+	}
+	query += " ORDER BY c.created_at, c.id"
+	if filter.Limit > 0 {
+		query += " LIMIT " + arg(filter.Limit)
+	}
+	rows, err := p.db.Query(context.Background(), query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var comments []model.Comment
+	for rows.Next() {
+		comment, err := scanComment(rows)
+		if err != nil {
+			return nil, err
+		}
+		comments = append(comments, comment)
+	}
+	return comments, rows.Err()
+}
+
+func (p *PostgresDb) UpdateComment(id, body string) (model.Comment, error) {
+	tag, err := p.db.Exec(context.Background(), updateComment, id, body)
+	if err != nil {
+		return model.Comment{}, err
+	}
+	if tag.RowsAffected() == 0 {
+		return model.Comment{}, ErrCommentNotFound
+	}
+	return p.GetComment(id)
+}
+
+// DeleteComment deletes the comment together with its replies.
+func (p *PostgresDb) DeleteComment(id string) error {
+	tag, err := p.db.Exec(context.Background(), deleteComment, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrCommentNotFound
+	}
+	return nil
+}
+
+func (p *PostgresDb) GetCommentTargetAccess(target model.CommentTarget, userId string) (model.CommentTargetAccess, error) {
+	var query string
+	var args []any
+	switch {
+	case target.WorkoutID != nil:
+		query, args = selectWorkoutCommentAccess, []any{*target.WorkoutID, userId}
+	case target.WorkoutExerciseID != nil:
+		query, args = selectWorkoutExerciseCommentAccess, []any{*target.WorkoutExerciseID, userId}
+	case target.ScheduleID != nil:
+		query, args = selectScheduleCommentAccess, []any{*target.ScheduleID}
+	default:
+		return model.CommentTargetAccess{}, ErrCommentTargetNotFound
+	}
+	var access model.CommentTargetAccess
+	err := p.db.QueryRow(context.Background(), query, args...).Scan(&access.OwnerID, &access.SharedWithUser)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return model.CommentTargetAccess{}, ErrCommentTargetNotFound
+	}
+	return access, err
+}
+
+// ListNotifications returns notifications of the user with comments that triggered them, newest first.
+func (p *PostgresDb) ListNotifications(userId string, filter model.NotificationFilter) ([]model.Notification, error) {
+	query := "SELECT " + notificationColumns + ", " + commentColumns + ` FROM notification n JOIN comment c ON c.id = n.comment_id
+		WHERE n."user" = $1`
+	args := []any{userId}
+	arg := func(value any) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
+	}
+	if filter.UnreadOnly {
+		query += " AND n.read_at IS NULL"
+	}
+	if filter.Before != nil {
+		query += fmt.Sprintf(" AND (n.created_at, n.id) < (%s, %s::uuid)", arg(filter.Before.CreatedAt), arg(filter.Before.ID))
+	}
+	query += " ORDER BY n.created_at DESC, n.id DESC"
+	if filter.Limit > 0 {
+		query += " LIMIT " + arg(filter.Limit)
+	}
+	rows, err := p.db.Query(context.Background(), query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var notifications []model.Notification
+	for rows.Next() {
+		var n model.Notification
+		c := &n.Comment
+		err := rows.Scan(&n.ID, &n.UserID, &n.Type, &n.CreatedAt, &n.ReadAt,
+			&c.ID, &c.AuthorID, &c.Target.WorkoutID, &c.Target.WorkoutExerciseID, &c.Target.ScheduleID, &c.ParentID, &c.Body,
+			&c.ReplyCount, &c.CreatedAt, &c.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, n)
+	}
+	return notifications, rows.Err()
+}
+
+func (p *PostgresDb) MarkNotificationRead(id, userId string) error {
+	tag, err := p.db.Exec(context.Background(), updateNotificationRead, id, userId)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotificationNotFound
+	}
+	return nil
+}
+
+func scanComment(row pgx.Row) (model.Comment, error) {
+	var c model.Comment
+	err := row.Scan(&c.ID, &c.AuthorID, &c.Target.WorkoutID, &c.Target.WorkoutExerciseID, &c.Target.ScheduleID, &c.ParentID, &c.Body,
+		&c.ReplyCount, &c.CreatedAt, &c.UpdatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return model.Comment{}, ErrCommentNotFound
+	}
+	return c, err
+}
@@ -2,16 +2,30 @@ package db
 
 import (
 	"context"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"log"
+	"time"
 )
 
 type PostgresDb struct {
 	db *pgxpool.Pool
 }
 
+// querier is implemented by both connection pool and transaction.
+type querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
 func NewPostgresDb(conn string) *PostgresDb {
-	dbPool, err := pgxpool.New(context.Background(), conn)
+	config, err := pgxpool.ParseConfig(conn)
+	if err != nil {
+		log.Fatalf("error parsing database config: %v", err)
+	}
+	config.AfterConnect = registerUTCTimestamptz
+	dbPool, err := pgxpool.NewWithConfig(context.Background(), config)
 	if err != nil {
 		log.Fatalf("error connecting to database: %v", err)
 	}
@@ -21,3 +35,12 @@ func NewPostgresDb(conn string) *PostgresDb {
 	}
 	return &PostgresDb{db: dbPool}
 }
+
+// registerUTCTimestamptz scans timestamptz values in UTC instead of server's local timezone, timezone of workout
+// schedule is stored in separate column.
+func registerUTCTimestamptz(_ context.Context, conn *pgx.Conn) error {
+	timestamptz := &pgtype.Type{Name: "timestamptz", OID: pgtype.TimestamptzOID, Codec: &pgtype.TimestamptzCodec{ScanLocation: time.UTC}}
+	conn.TypeMap().RegisterType(timestamptz)
+	conn.TypeMap().RegisterType(&pgtype.Type{Name: "_timestamptz", OID: pgtype.TimestamptzArrayOID, Codec: &pgtype.ArrayCodec{ElementType: timestamptz}})
+	return nil
+}
@@ -2,19 +2,55 @@ package db
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"strings"
 	"workout-tracker-server/model"
 )
 
-const selectFromExercise = "SELECT * FROM exercise"
+var (
+	ErrExerciseNotFound = fmt.Errorf("exercise not found")
+	ErrExerciseExists   = fmt.Errorf("exercise already exists")
+	ErrExerciseInUse    = fmt.Errorf("exercise is in use")
+)
+
+const (
+	exerciseColumns    = "id, name, description, category, muscle_group, owner"
+	selectFromExercise = "SELECT " + exerciseColumns + " FROM exercise"
+	selectExercise     = selectFromExercise + " WHERE id = $1"
+	insertExercise     = `INSERT INTO exercise (id, owner, name, description, category, muscle_group) VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (owner, name) DO NOTHING RETURNING id`
+	// renaming to name of other exercise of the owner is rejected by the unique constraint, it's checked upfront instead
+	updateExercise = `UPDATE exercise e SET name = $2, description = $3, category = $4, muscle_group = $5 WHERE e.id = $1
+		AND NOT EXISTS (SELECT 1 FROM exercise o WHERE o.owner = e.owner AND o.name = $2 AND o.id <> e.id)`
+	// exercises used by workouts or recorded in history are kept, global exercises are never deleted
+	deleteUnusedExercise = `DELETE FROM exercise e WHERE e.id = $1 AND e.owner IS NOT NULL
+		AND NOT EXISTS (SELECT 1 FROM workout_exercise we WHERE we.exercise_id = e.id)
+		AND NOT EXISTS (SELECT 1 FROM exercise_set_result r WHERE r.exercise_id = e.id)`
+)
 
 type ExerciseDb interface {
 	GetExercises(muscleGroup string, category string) ([]model.Exercise, error)
+	GetUserExercises(userId string, muscleGroup string, category string) ([]model.Exercise, error)
+	GetExercise(id string) (model.Exercise, error)
+	SaveExercise(exercise model.Exercise) (string, error)
+	UpdateExercise(exercise model.Exercise) (model.Exercise, error)
+	DeleteExercise(id string) error
 }
 
+// GetExercises returns global exercises sorted by name.
 func (p *PostgresDb) GetExercises(muscleGroup string, category string) ([]model.Exercise, error) {
-	query, args := getExercisesQuery(muscleGroup, category)
+	return p.queryExercises(getExercisesQuery("", muscleGroup, category))
+}
+
+// GetUserExercises returns global exercises merged with user's custom exercises, sorted by name.
+func (p *PostgresDb) GetUserExercises(userId string, muscleGroup string, category string) ([]model.Exercise, error) {
+	return p.queryExercises(getExercisesQuery(userId, muscleGroup, category))
+}
+
+func (p *PostgresDb) queryExercises(query string, args []any) ([]model.Exercise, error) {
 	rows, err := p.db.Query(context.Background(), query, args...)
 	if err != nil {
 		return nil, err
@@ -22,8 +58,7 @@ func (p *PostgresDb) GetExercises(muscleGroup string, category string) ([]model.
 	defer rows.Close()
 	var exercises []model.Exercise
 	for rows.Next() {
-		var ex model.Exercise
-		err := rows.Scan(&ex.ID, &ex.Name, &ex.Description, &ex.Category, &ex.MuscleGroup)
+		ex, err := scanExercise(rows)
 		if err != nil {
 			return nil, err
 		}
@@ -32,11 +67,67 @@ func (p *PostgresDb) GetExercises(muscleGroup string, category string) ([]model.
 	return exercises, nil
 }
 
-func getExercisesQuery(muscleGroup string, category string) (string, []any) {
+func (p *PostgresDb) GetExercise(id string) (model.Exercise, error) {
+	ex, err := scanExercise(p.db.QueryRow(context.Background(), selectExercise, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return model.Exercise{}, ErrExerciseNotFound
+	}
+	return ex, err
+}
+
+// SaveExercise saves custom exercise of its owner, returns ErrExerciseExists when the owner has exercise with the name.
+func (p *PostgresDb) SaveExercise(exercise model.Exercise) (string, error) {
+	var id string
+	err := p.db.QueryRow(context.Background(), insertExercise, uuid.New().String(), exercise.OwnerID, exercise.Name,
+		exercise.Description, exercise.Category, exercise.MuscleGroup).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", ErrExerciseExists
+	}
+	return id, err
+}
+
+// UpdateExercise updates custom exercise, returns ErrExerciseExists when the owner has other exercise with the name.
+// Workouts referencing the exercise see the change.
+func (p *PostgresDb) UpdateExercise(exercise model.Exercise) (model.Exercise, error) {
+	result, err := p.db.Exec(context.Background(), updateExercise, exercise.ID, exercise.Name, exercise.Description,
+		exercise.Category, exercise.MuscleGroup)
+	if err != nil {
+		return model.Exercise{}, err
+	}
+	if result.RowsAffected() == 0 {
+		if _, err := p.GetExercise(exercise.ID); err != nil {
+			return model.Exercise{}, err
+		}
+		return model.Exercise{}, ErrExerciseExists
+	}
+	return p.GetExercise(exercise.ID)
+}
+
+// DeleteExercise deletes custom exercise, returns ErrExerciseInUse when workouts or workout history reference it.
+func (p *PostgresDb) DeleteExercise(id string) error {
+	result, err := p.db.Exec(context.Background(), deleteUnusedExercise, id)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		if _, err := p.GetExercise(id); err != nil {
+			return err
+		}
+		return ErrExerciseInUse
+	}
+	return nil
+}
+
+func getExercisesQuery(userId string, muscleGroup string, category string) (string, []any) {
 	query := selectFromExercise
-	var conditions []string
+	conditions := []string{"owner IS NULL"}
 	var args []any
 	index := 1
+	if userId != "" {
+		conditions[0] = "(owner IS NULL OR owner = $1)"
+		args = append(args, userId)
+		index++
+	}
 	if muscleGroup != "" {
 		conditions = append(conditions, fmt.Sprintf("muscle_group = $%d", index))
 		args = append(args, strings.ToUpper(muscleGroup))
@@ -47,9 +138,14 @@ func getExercisesQuery(muscleGroup string, category string) (string, []any) {
 		args = append(args, strings.ToUpper(category))
 		index++
 	}
-	if len(conditions) > 0 {
-		query += " WHERE "
-		query += strings.Join(conditions, " AND ")
-	}
+	query += " WHERE "
+	query += strings.Join(conditions, " AND ")
+	query += " ORDER BY name"
 	return query, args
 }
+
+func scanExercise(row pgx.Row) (model.Exercise, error) {
+	var ex model.Exercise
+	err := row.Scan(&ex.ID, &ex.Name, &ex.Description, &ex.Category, &ex.MuscleGroup, &ex.OwnerID)
+	return ex, err
+}
@@ -0,0 +1,97 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"workout-tracker-server/model"
+)
+
+var (
+	ErrFolderNotFound = fmt.Errorf("folder not found")
+	ErrFolderExists   = fmt.Errorf("folder already exists")
+)
+
+const (
+	folderColumns = `f.id, f.owner, f.name, f.created_at, (SELECT count(*) FROM workout w WHERE w.folder_id = f.id AND w.deleted_at IS NULL)`
+
+	insertFolder     = `INSERT INTO folder (id, owner, name) VALUES ($1, $2, $3) ON CONFLICT (owner, name) DO NOTHING RETURNING id`
+	selectFolders    = "SELECT " + folderColumns + " FROM folder f WHERE f.owner = $1 ORDER BY f.name"
+	selectFolder     = "SELECT " + folderColumns + " FROM folder f WHERE f.id = $1"
+	updateFolderName = `UPDATE folder f SET name = $2 WHERE f.id = $1
+		AND NOT EXISTS (SELECT 1 FROM folder o WHERE o.owner = f.owner AND o.name = $2 AND o.id <> f.id)`
+	deleteFolder = "DELETE FROM folder WHERE id = $1"
+)
+
+type FolderDb interface {
+	SaveFolder(folder model.Folder) (string, error)
+	GetFolders(userId string) ([]model.Folder, error)
+	GetFolder(id string) (model.Folder, error)
+	RenameFolder(id, name string) (model.Folder, error)
+	DeleteFolder(id string) error
+}
+
+// SaveFolder stores folder, returns ErrFolderExists when the owner has folder with the same name.
+func (p *PostgresDb) SaveFolder(folder model.Folder) (string, error) {
+	var id string
+	err := p.db.QueryRow(context.Background(), insertFolder, uuid.New().String(), folder.OwnerID, folder.Name).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", ErrFolderExists
+	}
+	return id, err
+}
+
+// GetFolders returns user's folders with workout counts, sorted by name.
+func (p *PostgresDb) GetFolders(userId string) ([]model.Folder, error) {
+	rows, err := p.db.Query(context.Background(), selectFolders, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var folders []model.Folder
+	for rows.Next() {
+		folder, err := scanFolder(rows)
+		if err != nil {
+			return nil, err
+		}
+		folders = append(folders, folder)
+	}
+	return folders, rows.Err()
+}
+
+func (p *PostgresDb) GetFolder(id string) (model.Folder, error) {
+	folder, err := scanFolder(p.db.QueryRow(context.Background(), selectFolder, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return model.Folder{}, ErrFolderNotFound
+	}
+	return folder, err
+}
+
+// RenameFolder returns ErrFolderExists when the owner has other folder with the name.
+func (p *PostgresDb) RenameFolder(id, name string) (model.Folder, error) {
+	tag, err := p.db.Exec(context.Background(), updateFolderName, id, name)
+	if err != nil {
+		return model.Folder{}, err
+	}
+	if tag.RowsAffected() == 0 {
+		if _, err := p.GetFolder(id); err != nil {
+			return model.Folder{}, err
+		}
+		return model.Folder{}, ErrFolderExists
+	}
+	return p.GetFolder(id)
+}
+
+// DeleteFolder deletes folder, its workouts are kept without folder.
+func (p *PostgresDb) DeleteFolder(id string) error {
+	_, err := p.db.Exec(context.Background(), deleteFolder, id)
+	return err
+}
+
+func scanFolder(row pgx.Row) (model.Folder, error) {
+	var folder model.Folder
+	err := row.Scan(&folder.ID, &folder.OwnerID, &folder.Name, &folder.CreatedAt, &folder.WorkoutCount)
+	return folder, err
+}
@@ -0,0 +1,117 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"slices"
+	"time"
+	"workout-tracker-server/model"
+)
+
+var ErrExerciseNotInWorkout = fmt.Errorf("exercise not in workout")
+
+const (
+	deleteExerciseSetResults = "DELETE FROM exercise_set_result WHERE schedule_id = $1"
+	insertExerciseSetResult  = `INSERT INTO exercise_set_result (schedule_id, owner, exercise_id, set_number, repetitions, weight, rpe)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`
+	selectScheduledWorkoutExerciseIds = `SELECT we.exercise_id FROM workout_exercise we
+		JOIN workout_schedule ws ON ws.workout = we.workout_id WHERE ws.id = $1`
+	// selectExerciseHistory returns sets of the most recent completed sessions of each exercise, sessions are
+	// ranked per exercise so that the limit applies to sessions and not to sets
+	selectExerciseHistory = `SELECT exercise_id, schedule_id, completed_at, set_number, repetitions, weight, rpe FROM (
+			SELECT r.exercise_id, r.schedule_id, ws.completed_at, r.set_number, r.repetitions, r.weight, r.rpe,
+				dense_rank() OVER (PARTITION BY r.exercise_id ORDER BY ws.completed_at DESC, r.schedule_id) AS session
+			FROM exercise_set_result r JOIN workout_schedule ws ON ws.id = r.schedule_id
+			WHERE r.owner = $1 AND r.exercise_id = ANY($2) AND ws.completed AND ws.completed_at IS NOT NULL
+		) h WHERE session <= $3 ORDER BY exercise_id, completed_at DESC, schedule_id, set_number`
+)
+
+type ExerciseHistoryDb interface {
+	SaveExerciseSets(scheduleId, userId string, sets []model.ExerciseSet) error
+	GetExerciseHistory(userId string, exerciseIds []string, sessions int) (map[string][]model.ExerciseSession, error)
+}
+
+// SaveExerciseSets replaces sets performed in the workout schedule.
+func (p *PostgresDb) SaveExerciseSets(scheduleId, userId string, sets []model.ExerciseSet) error {
+	tx, err := p.db.BeginTx(context.Background(), pgx.TxOptions{IsoLevel: pgx.ReadCommitted, AccessMode: pgx.ReadWrite})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(context.Background())
+	if err := replaceExerciseSets(tx, scheduleId, userId, sets); err != nil {
+		return err
+	}
+	return tx.Commit(context.Background())
+}
+
+func replaceExerciseSets(tx pgx.Tx, scheduleId, userId string, sets []model.ExerciseSet) error {
+	if _, err := tx.Exec(context.Background(), deleteExerciseSetResults, scheduleId); err != nil {
+		return err
+	}
+	for _, set := range sets {
+		_, err := tx.Exec(context.Background(), insertExerciseSetResult,
+			scheduleId, userId, set.ExerciseID, set.Set, set.Repetitions, set.Weight, set.RPE,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateScheduledExercises checks that sets reference only exercises of the scheduled workout.
+func validateScheduledExercises(tx pgx.Tx, scheduleId string, sets []model.ExerciseSet) error {
+	rows, err := tx.Query(context.Background(), selectScheduledWorkoutExerciseIds, scheduleId)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	var exerciseIds []string
+	for rows.Next() {
+		var exerciseId string
+		if err := rows.Scan(&exerciseId); err != nil {
+			return err
+		}
+		exerciseIds = append(exerciseIds, exerciseId)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, set := range sets {
+		if !slices.Contains(exerciseIds, set.ExerciseID) {
+			return ErrExerciseNotInWorkout
+		}
+	}
+	return nil
+}
+
+// GetExerciseHistory returns given number of the most recent completed sessions of each exercise keyed by
+// exercise id, sessions are sorted from the most recent.
+func (p *PostgresDb) GetExerciseHistory(userId string, exerciseIds []string, sessions int) (map[string][]model.ExerciseSession, error) {
+	history := make(map[string][]model.ExerciseSession)
+	if len(exerciseIds) == 0 {
+		return history, nil
+	}
+	rows, err := p.db.Query(context.Background(), selectExerciseHistory, userId, exerciseIds, sessions)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var scheduleId string
+		var set model.ExerciseSet
+		var completedAt time.Time
+		if err := rows.Scan(&set.ExerciseID, &scheduleId, &completedAt, &set.Set, &set.Repetitions, &set.Weight, &set.RPE); err != nil {
+			return nil, err
+		}
+		exerciseSessions := history[set.ExerciseID]
+		if n := len(exerciseSessions); n == 0 || exerciseSessions[n-1].ScheduleID != scheduleId {
+			exerciseSessions = append(exerciseSessions, model.ExerciseSession{ScheduleID: scheduleId, CompletedAt: completedAt})
+		}
+		last := &exerciseSessions[len(exerciseSessions)-1]
+		last.Sets = append(last.Sets, set)
+		history[set.ExerciseID] = exerciseSessions
+	}
+	return history, rows.Err()
+}
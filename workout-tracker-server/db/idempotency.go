@@ -0,0 +1,85 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"time"
+	"workout-tracker-server/model"
+)
+
+var ErrIdempotencyKeyNotFound = fmt.Errorf("idempotency key not found")
+
+const (
+	// expired key and key of abandoned request without response are taken over as if they didn't exist
+	insertIdempotencyKey = `INSERT INTO idempotency_key (user_id, method, key, request_hash, created_at) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id, method, key) DO UPDATE SET request_hash = EXCLUDED.request_hash, response = NULL, created_at = EXCLUDED.created_at
+		WHERE idempotency_key.created_at < $6 OR (idempotency_key.response IS NULL AND idempotency_key.created_at < $7) RETURNING true`
+	selectIdempotencyKey = `SELECT user_id, method, key, request_hash, response, created_at FROM idempotency_key
+		WHERE user_id = $1 AND method = $2 AND key = $3`
+	// reservation is identified by its creation time, so request whose key was taken over doesn't touch the new one
+	updateIdempotencyKeyResponse = `UPDATE idempotency_key SET response = $5 WHERE user_id = $1 AND method = $2 AND key = $3 AND created_at = $4`
+	deleteIdempotencyKey         = `DELETE FROM idempotency_key WHERE user_id = $1 AND method = $2 AND key = $3 AND created_at = $4 AND response IS NULL`
+	deleteExpiredIdempotencyKeys = `DELETE FROM idempotency_key WHERE created_at < $1`
+)
+
+type IdempotencyDb interface {
+	ReserveIdempotencyKey(key model.IdempotencyKey, expiredBefore, leaseExpiredBefore time.Time) (bool, error)
+	GetIdempotencyKey(userId, method, key string) (model.IdempotencyKey, error)
+	SaveIdempotentResponse(key model.IdempotencyKey, response []byte) error
+	DeleteIdempotencyKey(key model.IdempotencyKey) error
+	DeleteExpiredIdempotencyKeys(before time.Time) (int64, error)
+}
+
+// ReserveIdempotencyKey stores key without response before the request is processed. Returns false when the key
+// is already used and not expired, so only one of concurrent requests with the same key is processed. Key without
+// response reserved before leaseExpiredBefore belongs to abandoned request and is reserved again.
+func (p *PostgresDb) ReserveIdempotencyKey(key model.IdempotencyKey, expiredBefore, leaseExpiredBefore time.Time) (bool, error) {
+	var reserved bool
+	err := p.db.QueryRow(context.Background(), insertIdempotencyKey,
+		key.UserID, key.Method, key.Key, key.RequestHash, key.CreatedAt, expiredBefore, leaseExpiredBefore,
+	).Scan(&reserved)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return reserved, nil
+}
+
+func (p *PostgresDb) GetIdempotencyKey(userId, method, key string) (model.IdempotencyKey, error) {
+	var ik model.IdempotencyKey
+	err := p.db.QueryRow(context.Background(), selectIdempotencyKey, userId, method, key).
+		Scan(&ik.UserID, &ik.Method, &ik.Key, &ik.RequestHash, &ik.Response, &ik.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return model.IdempotencyKey{}, ErrIdempotencyKeyNotFound
+	}
+	if err != nil {
+		return model.IdempotencyKey{}, err
+	}
+	return ik, nil
+}
+
+// SaveIdempotentResponse stores response of processed request for replay, unless the key was taken over meanwhile.
+func (p *PostgresDb) SaveIdempotentResponse(key model.IdempotencyKey, response []byte) error {
+	_, err := p.db.Exec(context.Background(), updateIdempotencyKeyResponse, key.UserID, key.Method, key.Key, key.CreatedAt, response)
+	return err
+}
+
+// DeleteIdempotencyKey releases reserved key of failed request, so it can be retried. Keys with stored response
+// and keys taken over by retry are kept.
+func (p *PostgresDb) DeleteIdempotencyKey(key model.IdempotencyKey) error {
+	_, err := p.db.Exec(context.Background(), deleteIdempotencyKey, key.UserID, key.Method, key.Key, key.CreatedAt)
+	return err
+}
+
+// DeleteExpiredIdempotencyKeys deletes keys created before given time, returns number of deleted keys.
+func (p *PostgresDb) DeleteExpiredIdempotencyKeys(before time.Time) (int64, error) {
+	tag, err := p.db.Exec(context.Background(), deleteExpiredIdempotencyKeys, before)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
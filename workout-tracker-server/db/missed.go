@@ -0,0 +1,51 @@
+package db
+
+import (
+	"context"
+	"time"
+	"workout-tracker-server/model"
+)
+
+const (
+	selectWorkoutScheduleSeriesBefore = "SELECT id, owner, workout, starts_at, timezone, recurrence, excluded_dates, created_at FROM workout_schedule_series WHERE starts_at < $1"
+	// transition and its event are stored in single statement, so event is never lost nor duplicated
+	updateWorkoutSchedulesMissed = `WITH missed AS (
+			UPDATE workout_schedule SET missed_at = $1
+			WHERE NOT completed AND cancelled_at IS NULL AND missed_at IS NULL AND scheduled_at < $2
+			RETURNING id, owner
+		)
+		INSERT INTO workout_schedule_event (id, schedule_id, owner, type, created_at)
+		SELECT gen_random_uuid(), id, owner, $3, $1 FROM missed
+		RETURNING id, schedule_id, owner, type, created_at`
+)
+
+type MissedScheduleDb interface {
+	GetWorkoutScheduleSeriesBefore(to time.Time) ([]model.WorkoutScheduleSeries, error)
+	MaterializeScheduleOccurrence(series model.WorkoutScheduleSeries, recurrenceId time.Time) (string, error)
+	MarkWorkoutSchedulesMissed(before, now time.Time) ([]model.WorkoutScheduleEvent, error)
+}
+
+// GetWorkoutScheduleSeriesBefore returns series of all users that started before given time, with detached
+// occurrence dates.
+func (p *PostgresDb) GetWorkoutScheduleSeriesBefore(to time.Time) ([]model.WorkoutScheduleSeries, error) {
+	return p.getWorkoutScheduleSeriesList(selectWorkoutScheduleSeriesBefore, to)
+}
+
+// MarkWorkoutSchedulesMissed marks not completed and not cancelled workout schedules scheduled before given time
+// as missed and records missed event for each of them. Returns recorded events.
+func (p *PostgresDb) MarkWorkoutSchedulesMissed(before, now time.Time) ([]model.WorkoutScheduleEvent, error) {
+	rows, err := p.db.Query(context.Background(), updateWorkoutSchedulesMissed, now, before, model.WorkoutScheduleEventMissed)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var events []model.WorkoutScheduleEvent
+	for rows.Next() {
+		var event model.WorkoutScheduleEvent
+		if err := rows.Scan(&event.ID, &event.ScheduleID, &event.OwnerID, &event.Type, &event.CreatedAt); err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
@@ -0,0 +1,73 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	"time"
+	"workout-tracker-server/model"
+)
+
+const (
+	selectProfile = `SELECT reminder_lead_time_minutes, reminder_channels, webhook_url, default_rest_seconds, warm_up_rest_seconds
+		FROM user_profile WHERE user_id = $1`
+	upsertProfile = `INSERT INTO user_profile (user_id, reminder_lead_time_minutes, reminder_channels, webhook_url, default_rest_seconds, warm_up_rest_seconds)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (user_id) DO UPDATE SET reminder_lead_time_minutes = EXCLUDED.reminder_lead_time_minutes,
+		reminder_channels = EXCLUDED.reminder_channels, webhook_url = EXCLUDED.webhook_url,
+		default_rest_seconds = EXCLUDED.default_rest_seconds, warm_up_rest_seconds = EXCLUDED.warm_up_rest_seconds,
+		updated_at = now()`
+)
+
+type ProfileDb interface {
+	GetProfile(userId string) (model.Profile, error)
+	SaveProfile(profile model.Profile) error
+}
+
+// GetProfile returns user's profile, default profile is returned when user hasn't stored one yet.
+func (p *PostgresDb) GetProfile(userId string) (model.Profile, error) {
+	profile := model.Profile{UserID: userId}
+	var leadTimeMinutes, restSeconds int
+	var warmUpRestSeconds *int
+	var channels []string
+	err := p.db.QueryRow(context.Background(), selectProfile, userId).Scan(&leadTimeMinutes, &channels, &profile.WebhookURL, &restSeconds, &warmUpRestSeconds)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.DefaultProfile(userId), nil
+		}
+		return model.Profile{}, err
+	}
+	profile.ReminderLeadTime = time.Duration(leadTimeMinutes) * time.Minute
+	profile.RestDefaults = restDefaults(restSeconds, warmUpRestSeconds)
+	for _, channel := range channels {
+		profile.ReminderChannels = append(profile.ReminderChannels, model.ReminderChannel(channel))
+	}
+	return profile, nil
+}
+
+// SaveProfile creates or replaces user's profile, lead time is stored with minute precision and rests with second precision.
+func (p *PostgresDb) SaveProfile(profile model.Profile) error {
+	channels := make([]string, 0, len(profile.ReminderChannels))
+	for _, channel := range profile.ReminderChannels {
+		channels = append(channels, string(channel))
+	}
+	var warmUpRestSeconds *int
+	if profile.RestDefaults.WarmUpRest != nil {
+		seconds := int(*profile.RestDefaults.WarmUpRest / time.Second)
+		warmUpRestSeconds = &seconds
+	}
+	_, err := p.db.Exec(context.Background(), upsertProfile,
+		profile.UserID, int(profile.ReminderLeadTime/time.Minute), channels, profile.WebhookURL,
+		int(profile.RestDefaults.Rest/time.Second), warmUpRestSeconds,
+	)
+	return err
+}
+
+func restDefaults(restSeconds int, warmUpRestSeconds *int) model.RestDefaults {
+	defaults := model.RestDefaults{Rest: time.Duration(restSeconds) * time.Second}
+	if warmUpRestSeconds != nil {
+		warmUpRest := time.Duration(*warmUpRestSeconds) * time.Second
+		defaults.WarmUpRest = &warmUpRest
+	}
+	return defaults
+}
@@ -0,0 +1,264 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"workout-tracker-server/model"
+)
+
+var (
+	ErrProgramNotFound           = fmt.Errorf("program not found")
+	ErrProgramEnrollmentNotFound = fmt.Errorf("program enrollment not found")
+	ErrProgramWorkoutDeleted     = fmt.Errorf("program workout deleted")
+)
+
+const (
+	insertProgram     = "INSERT INTO program (id, owner, name, description) VALUES ($1, $2, $3, $4)"
+	insertProgramWeek = "INSERT INTO program_week (program_id, week, weight_increase_percent, deload) VALUES ($1, $2, $3, $4)"
+	// days and sessions are inserted only for workouts which are not in trash
+	insertProgramDay = `INSERT INTO program_day (program_id, week, day, workout)
+		SELECT $1, $2, $3, $4 WHERE EXISTS (SELECT 1 FROM workout WHERE id = $4 AND NOT template AND deleted_at IS NULL)`
+	selectProgramById       = "SELECT id, owner, name, description, created_at FROM program WHERE id = $1"
+	selectProgramsByOwner   = "SELECT id, owner, name, description, created_at FROM program WHERE owner = $1 ORDER BY created_at"
+	selectProgramWeeks      = "SELECT program_id, week, weight_increase_percent, deload FROM program_week WHERE program_id = ANY($1) ORDER BY week"
+	selectProgramDays       = "SELECT program_id, week, day, workout FROM program_day WHERE program_id = ANY($1) ORDER BY week, day"
+	deleteProgram           = "DELETE FROM program WHERE id = $1"
+	insertProgramEnrollment = "INSERT INTO program_enrollment (id, program_id, owner, start_date, timezone) VALUES ($1, $2, $3, $4, $5)"
+	insertProgramSession    = `INSERT INTO workout_schedule (id, owner, workout, scheduled_at, timezone, program_enrollment_id, program_week, program_day, workout_version)
+		SELECT $1, $2, $3, $4, $5, $6, $7, $8, w.version FROM workout w WHERE w.id = $3 AND w.deleted_at IS NULL`
+	selectProgramEnrollment = "SELECT id, program_id, owner, start_date, timezone, created_at FROM program_enrollment WHERE id = $1"
+	selectProgramSessions   = "SELECT " + workoutScheduleColumns + ", program_week, program_day FROM workout_schedule WHERE program_enrollment_id = $1 ORDER BY program_week, program_day"
+	updateProgramSessionAt  = "UPDATE workout_schedule SET scheduled_at = $1, missed_at = NULL WHERE id = $2 AND NOT completed AND cancelled_at IS NULL"
+)
+
+type ProgramDb interface {
+	SaveProgram(program model.Program) (string, error)
+	GetProgram(id string) (model.Program, error)
+	GetPrograms(userId string) ([]model.Program, error)
+	DeleteProgram(id string) error
+	SaveProgramEnrollment(enrollment model.ProgramEnrollment) (string, error)
+	GetProgramEnrollment(id string) (model.ProgramEnrollment, error)
+	RescheduleProgramSessions(schedules []model.WorkoutSchedule) error
+	SkipProgramSessions(scheduleIds []string) error
+}
+
+func (p *PostgresDb) SaveProgram(program model.Program) (string, error) {
+	id := uuid.New().String()
+	tx, err := p.db.BeginTx(context.Background(), pgx.TxOptions{IsoLevel: pgx.ReadCommitted, AccessMode: pgx.ReadWrite})
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback(context.Background())
+	if _, err := tx.Exec(context.Background(), insertProgram, id, program.OwnerID, program.Name, program.Description); err != nil {
+		return "", err
+	}
+	for i, week := range program.Weeks {
+		if _, err := tx.Exec(context.Background(), insertProgramWeek, id, i+1, week.WeightIncreasePercent, week.Deload); err != nil {
+			return "", err
+		}
+		for _, day := range week.Days {
+			tag, err := tx.Exec(context.Background(), insertProgramDay, id, i+1, day.Day, day.WorkoutID)
+			if err != nil {
+				return "", err
+			}
+			if tag.RowsAffected() == 0 {
+				return "", ErrWorkoutNotFound
+			}
+		}
+	}
+	if err := tx.Commit(context.Background()); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// GetProgram returns program with its weeks and days.
+func (p *PostgresDb) GetProgram(id string) (model.Program, error) {
+	programs, err := p.getPrograms(selectProgramById, id)
+	if err != nil {
+		return model.Program{}, err
+	}
+	if len(programs) == 0 {
+		return model.Program{}, ErrProgramNotFound
+	}
+	return programs[0], nil
+}
+
+// GetPrograms returns user's programs with their weeks and days, sorted by creation time.
+func (p *PostgresDb) GetPrograms(userId string) ([]model.Program, error) {
+	return p.getPrograms(selectProgramsByOwner, userId)
+}
+
+func (p *PostgresDb) getPrograms(query string, args ...any) ([]model.Program, error) {
+	rows, err := p.db.Query(context.Background(), query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var programs []model.Program
+	for rows.Next() {
+		var program model.Program
+		if err := rows.Scan(&program.ID, &program.OwnerID, &program.Name, &program.Description, &program.CreatedAt); err != nil {
+			return nil, err
+		}
+		programs = append(programs, program)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(programs) == 0 {
+		return nil, nil
+	}
+	return programs, p.fillProgramWeeks(programs)
+}
+
+// fillProgramWeeks loads weeks and days of the programs, weeks are stored with numbers starting at 1.
+func (p *PostgresDb) fillProgramWeeks(programs []model.Program) error {
+	ids := make([]string, 0, len(programs))
+	indexes := make(map[string]int, len(programs))
+	for i, program := range programs {
+		ids = append(ids, program.ID)
+		indexes[program.ID] = i
+	}
+	rows, err := p.db.Query(context.Background(), selectProgramWeeks, ids)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var programId string
+		var number int
+		var week model.ProgramWeek
+		if err := rows.Scan(&programId, &number, &week.WeightIncreasePercent, &week.Deload); err != nil {
+			return err
+		}
+		program := &programs[indexes[programId]]
+		program.Weeks = append(program.Weeks, week)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	rows, err = p.db.Query(context.Background(), selectProgramDays, ids)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var programId string
+		var number int
+		var day model.ProgramDay
+		if err := rows.Scan(&programId, &number, &day.Day, &day.WorkoutID); err != nil {
+			return err
+		}
+		program := &programs[indexes[programId]]
+		if number < 1 || number > len(program.Weeks) {
+			return fmt.Errorf("day of missing week %d in program %s", number, programId)
+		}
+		program.Weeks[number-1].Days = append(program.Weeks[number-1].Days, day)
+	}
+	return rows.Err()
+}
+
+func (p *PostgresDb) DeleteProgram(id string) error {
+	tag, err := p.db.Exec(context.Background(), deleteProgram, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrProgramNotFound
+	}
+	return nil
+}
+
+// SaveProgramEnrollment saves enrollment together with its sessions as workout schedules, returns
+// ErrProgramWorkoutDeleted when a session workout was moved to trash.
+func (p *PostgresDb) SaveProgramEnrollment(enrollment model.ProgramEnrollment) (string, error) {
+	id := uuid.New().String()
+	tx, err := p.db.BeginTx(context.Background(), pgx.TxOptions{IsoLevel: pgx.ReadCommitted, AccessMode: pgx.ReadWrite})
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback(context.Background())
+	_, err = tx.Exec(context.Background(), insertProgramEnrollment, id, enrollment.ProgramID, enrollment.OwnerID, enrollment.StartDate, enrollment.Timezone)
+	if err != nil {
+		return "", err
+	}
+	for _, session := range enrollment.Sessions {
+		ws := session.Schedule
+		tag, err := tx.Exec(context.Background(), insertProgramSession,
+			uuid.New().String(), ws.OwnerID, ws.WorkoutID, ws.ScheduledAt, ws.Timezone, id, session.Week, session.Day,
+		)
+		if err != nil {
+			return "", err
+		}
+		if tag.RowsAffected() == 0 {
+			return "", ErrProgramWorkoutDeleted
+		}
+	}
+	if err := tx.Commit(context.Background()); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// GetProgramEnrollment returns enrollment with its sessions sorted by week and day.
+func (p *PostgresDb) GetProgramEnrollment(id string) (model.ProgramEnrollment, error) {
+	var enrollment model.ProgramEnrollment
+	err := p.db.QueryRow(context.Background(), selectProgramEnrollment, id).Scan(
+		&enrollment.ID, &enrollment.ProgramID, &enrollment.OwnerID, &enrollment.StartDate, &enrollment.Timezone, &enrollment.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.ProgramEnrollment{}, ErrProgramEnrollmentNotFound
+		}
+		return model.ProgramEnrollment{}, err
+	}
+	rows, err := p.db.Query(context.Background(), selectProgramSessions, id)
+	if err != nil {
+		return model.ProgramEnrollment{}, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var session model.ProgramSession
+		if session.Schedule, err = scanWorkoutSchedule(rows, &session.Week, &session.Day); err != nil {
+			return model.ProgramEnrollment{}, err
+		}
+		enrollment.Sessions = append(enrollment.Sessions, session)
+	}
+	return enrollment, rows.Err()
+}
+
+// RescheduleProgramSessions moves sessions to their new times, completed and cancelled sessions are not moved.
+func (p *PostgresDb) RescheduleProgramSessions(schedules []model.WorkoutSchedule) error {
+	tx, err := p.db.BeginTx(context.Background(), pgx.TxOptions{IsoLevel: pgx.ReadCommitted, AccessMode: pgx.ReadWrite})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(context.Background())
+	for _, ws := range schedules {
+		if _, err := tx.Exec(context.Background(), updateProgramSessionAt, ws.ScheduledAt, ws.ID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(context.Background())
+}
+
+// SkipProgramSessions cancels sessions, completed sessions are not cancelled.
+func (p *PostgresDb) SkipProgramSessions(scheduleIds []string) error {
+	tx, err := p.db.BeginTx(context.Background(), pgx.TxOptions{IsoLevel: pgx.ReadCommitted, AccessMode: pgx.ReadWrite})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(context.Background())
+	reason := model.ProgramSkippedReason
+	for _, id := range scheduleIds {
+		if _, err := tx.Exec(context.Background(), updateWorkoutScheduleCancelled, &reason, id); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(context.Background())
+}
@@ -0,0 +1,82 @@
+package db
+
+import (
+	"context"
+	"github.com/jackc/pgx/v5"
+	"time"
+)
+
+const (
+	// workouts used by programs stay in trash until the program is deleted
+	deleteDeletedWorkouts = `DELETE FROM workout w WHERE w.deleted_at < $1 AND w.purged_at IS NULL
+		AND NOT EXISTS (SELECT 1 FROM workout_schedule ws WHERE ws.workout = w.id AND ws.completed)
+		AND NOT EXISTS (SELECT 1 FROM program_day d WHERE d.workout = w.id)`
+	// completed schedules are history, workouts they reference keep only the row with name
+	updateDeletedWorkoutsPurged = `UPDATE workout w SET purged_at = $2, comment = NULL, folder_id = NULL
+		WHERE w.deleted_at < $1 AND w.purged_at IS NULL
+		AND NOT EXISTS (SELECT 1 FROM program_day d WHERE d.workout = w.id) RETURNING w.id`
+	deletePurgedWorkoutVersions       = "DELETE FROM workout_version WHERE workout_id = ANY($1)"
+	deletePurgedWorkoutShares         = "DELETE FROM workout_share WHERE workout_id = ANY($1)"
+	deletePurgedWorkoutTags           = "DELETE FROM workout_tag WHERE workout_id = ANY($1)"
+	deletePurgedWorkoutComments       = "DELETE FROM comment WHERE workout_id = ANY($1)"
+	deletePurgedWorkoutExercises      = "DELETE FROM workout_exercise WHERE workout_id = ANY($1)"
+	deletePurgedWorkoutExerciseGroups = "DELETE FROM workout_exercise_group WHERE workout_id = ANY($1)"
+)
+
+// purgedWorkoutCleanup removes content of purged workouts, comments of their exercises are removed with the exercises.
+var purgedWorkoutCleanup = []string{
+	deletePurgedWorkoutVersions,
+	deletePurgedWorkoutShares,
+	deletePurgedWorkoutTags,
+	deletePurgedWorkoutComments,
+	deletePurgedWorkoutExercises,
+	deletePurgedWorkoutExerciseGroups,
+}
+
+type WorkoutPurgeDb interface {
+	PurgeDeletedWorkouts(deletedBefore, now time.Time) (int64, error)
+}
+
+// PurgeDeletedWorkouts permanently deletes workouts moved to trash before given time, together with their schedules,
+// versions, shares, tags and comments. Workouts referenced by completed schedules keep only their row with name as
+// purged, so past sessions remain readable. Workouts used by programs are not purged. Returns number of purged workouts.
+func (p *PostgresDb) PurgeDeletedWorkouts(deletedBefore, now time.Time) (int64, error) {
+	ctx := context.Background()
+	tx, err := p.db.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.ReadCommitted, AccessMode: pgx.ReadWrite})
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+	deleted, err := tx.Exec(ctx, deleteDeletedWorkouts, deletedBefore)
+	if err != nil {
+		return 0, err
+	}
+	rows, err := tx.Query(ctx, updateDeletedWorkoutsPurged, deletedBefore, now)
+	if err != nil {
+		return 0, err
+	}
+	var purgedIds []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		purgedIds = append(purgedIds, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	if len(purgedIds) > 0 {
+		for _, query := range purgedWorkoutCleanup {
+			if _, err := tx.Exec(ctx, query, purgedIds); err != nil {
+				return 0, err
+			}
+		}
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return deleted.RowsAffected() + int64(len(purgedIds)), nil
+}
@@ -0,0 +1,148 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"time"
+	"workout-tracker-server/model"
+)
+
+const (
+	// due schedules are those starting within owner's lead time, only owners with any channel configured are included
+	selectDueReminders = `SELECT ws.id, ws.owner, coalesce(u.email, ''), w.name, ws.scheduled_at, ws.timezone, p.reminder_channels, p.webhook_url
+		FROM workout_schedule ws
+		JOIN user_profile p ON p.user_id = ws.owner
+		JOIN workout w ON w.id = ws.workout
+		LEFT JOIN "user" u ON u.id = ws.owner
+		WHERE NOT ws.completed AND ws.cancelled_at IS NULL AND cardinality(p.reminder_channels) > 0
+		AND ws.scheduled_at > $1 AND ws.scheduled_at <= $1::timestamptz + make_interval(mins => p.reminder_lead_time_minutes)`
+	selectReminderSeries = `SELECT s.id, s.owner, s.workout, s.starts_at, s.timezone, s.recurrence, s.excluded_dates, s.created_at, p.reminder_lead_time_minutes
+		FROM workout_schedule_series s
+		JOIN user_profile p ON p.user_id = s.owner
+		WHERE cardinality(p.reminder_channels) > 0 AND s.starts_at <= $1::timestamptz + make_interval(mins => p.reminder_lead_time_minutes)`
+	insertScheduleOccurrenceIfAbsent = `INSERT INTO workout_schedule(id, owner, workout, scheduled_at, timezone, series_id, recurrence_id, workout_version)
+		VALUES ($1,$2,$3,$4,$5,$6,$4,(SELECT version FROM workout WHERE id = $3))
+		ON CONFLICT (series_id, recurrence_id) DO NOTHING`
+	selectScheduleOccurrenceId = "SELECT id FROM workout_schedule WHERE series_id = $1 AND recurrence_id = $2"
+	// claim succeeds for new delivery or for failed (or abandoned) one which claim expired and has attempts left
+	claimReminderDelivery = `INSERT INTO reminder_delivery (schedule_id, channel, claimed_at) VALUES ($1, $2, $3)
+		ON CONFLICT (schedule_id, channel) DO UPDATE SET claimed_at = EXCLUDED.claimed_at, attempts = reminder_delivery.attempts + 1
+		WHERE reminder_delivery.sent_at IS NULL AND reminder_delivery.claimed_at <= $4 AND reminder_delivery.attempts < $5
+		RETURNING schedule_id`
+	updateReminderDelivered = "UPDATE reminder_delivery SET sent_at = now(), last_error = NULL WHERE schedule_id = $1 AND channel = $2"
+	updateReminderFailed    = "UPDATE reminder_delivery SET last_error = $1 WHERE schedule_id = $2 AND channel = $3"
+)
+
+const (
+	// reminderClaimTimeout is time after which failed or abandoned delivery can be claimed again.
+	reminderClaimTimeout = 5 * time.Minute
+	maxReminderAttempts  = 3
+)
+
+type ReminderDb interface {
+	GetDueReminders(now time.Time) ([]model.Reminder, error)
+	GetReminderSeries(now time.Time) ([]model.ReminderSeries, error)
+	MaterializeScheduleOccurrence(series model.WorkoutScheduleSeries, recurrenceId time.Time) (string, error)
+	ClaimReminderDelivery(scheduleId string, channel model.ReminderChannel, now time.Time) (bool, error)
+	MarkReminderDelivered(scheduleId string, channel model.ReminderChannel) error
+	MarkReminderFailed(scheduleId string, channel model.ReminderChannel, cause error) error
+}
+
+// GetDueReminders returns reminders of not completed and not cancelled workout schedules starting within
+// owner's reminder lead time from now.
+func (p *PostgresDb) GetDueReminders(now time.Time) ([]model.Reminder, error) {
+	rows, err := p.db.Query(context.Background(), selectDueReminders, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var reminders []model.Reminder
+	for rows.Next() {
+		var reminder model.Reminder
+		var channels []string
+		err := rows.Scan(&reminder.ScheduleID, &reminder.OwnerID, &reminder.Email, &reminder.WorkoutName, &reminder.ScheduledAt, &reminder.Timezone, &channels, &reminder.WebhookURL)
+		if err != nil {
+			return nil, err
+		}
+		for _, channel := range channels {
+			reminder.Channels = append(reminder.Channels, model.ReminderChannel(channel))
+		}
+		reminders = append(reminders, reminder)
+	}
+	return reminders, rows.Err()
+}
+
+// GetReminderSeries returns recurring series, which can have occurrences within owner's reminder lead time from now.
+func (p *PostgresDb) GetReminderSeries(now time.Time) ([]model.ReminderSeries, error) {
+	rows, err := p.db.Query(context.Background(), selectReminderSeries, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []model.ReminderSeries
+	for rows.Next() {
+		var leadTimeMinutes int
+		series, err := scanWorkoutScheduleSeries(rows, &leadTimeMinutes)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, model.ReminderSeries{Series: series, LeadTime: time.Duration(leadTimeMinutes) * time.Minute})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	seriesList := make([]model.WorkoutScheduleSeries, 0, len(result))
+	for _, rs := range result {
+		seriesList = append(seriesList, rs.Series)
+	}
+	if err := p.fillDetachedDates(seriesList); err != nil {
+		return nil, err
+	}
+	for i := range result {
+		result[i].Series = seriesList[i]
+	}
+	return result, nil
+}
+
+// MaterializeScheduleOccurrence stores occurrence of the series as workout schedule unless it's already stored.
+// Returns id of the workout schedule.
+func (p *PostgresDb) MaterializeScheduleOccurrence(series model.WorkoutScheduleSeries, recurrenceId time.Time) (string, error) {
+	_, err := p.db.Exec(context.Background(), insertScheduleOccurrenceIfAbsent,
+		uuid.New().String(), series.OwnerID, series.WorkoutID, recurrenceId, series.Timezone, series.ID,
+	)
+	if err != nil {
+		return "", err
+	}
+	var id string
+	err = p.db.QueryRow(context.Background(), selectScheduleOccurrenceId, series.ID, recurrenceId).Scan(&id)
+	return id, err
+}
+
+// ClaimReminderDelivery reserves sending of the reminder through given channel. Only one caller succeeds,
+// so reminder is not sent twice when multiple replicas are running.
+func (p *PostgresDb) ClaimReminderDelivery(scheduleId string, channel model.ReminderChannel, now time.Time) (bool, error) {
+	var id string
+	err := p.db.QueryRow(context.Background(), claimReminderDelivery,
+		scheduleId, string(channel), now, now.Add(-reminderClaimTimeout), maxReminderAttempts,
+	).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (p *PostgresDb) MarkReminderDelivered(scheduleId string, channel model.ReminderChannel) error {
+	_, err := p.db.Exec(context.Background(), updateReminderDelivered, scheduleId, string(channel))
+	return err
+}
+
+// MarkReminderFailed records delivery failure, delivery is retried once its claim expires.
+func (p *PostgresDb) MarkReminderFailed(scheduleId string, channel model.ReminderChannel, cause error) error {
+	_, err := p.db.Exec(context.Background(), updateReminderFailed, cause.Error(), scheduleId, string(channel))
+	return err
+}
@@ -0,0 +1,87 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+	"workout-tracker-server/model"
+)
+
+// bucket start is computed from local time in user's timezone ($4) and converted back to absolute time
+const reportPeriodExpr = "date_trunc($5, ws.scheduled_at AT TIME ZONE $4) AT TIME ZONE $4"
+
+var (
+	selectScheduleCountsPerPeriod = fmt.Sprintf(`SELECT %s AS period, count(*), count(*) FILTER (WHERE ws.completed)
+		FROM workout_schedule ws
+		WHERE ws.owner = $1 AND ws.scheduled_at >= $2 AND ws.scheduled_at <= $3 AND ws.cancelled_at IS NULL
+		GROUP BY period`, reportPeriodExpr)
+	selectVolumePerPeriod = fmt.Sprintf(`SELECT %s AS period, e.muscle_group, sum(we.sets * we.repetitions * coalesce(we.weight, 0))::float8, sum(we.sets)
+		FROM workout_schedule ws
+		JOIN workout_exercise we ON we.workout_id = ws.workout
+		JOIN exercise e ON e.id = we.exercise_id
+		WHERE ws.owner = $1 AND ws.completed AND ws.scheduled_at >= $2 AND ws.scheduled_at <= $3
+		GROUP BY period, e.muscle_group`, reportPeriodExpr)
+)
+
+type ReportDb interface {
+	GetProgressReport(userId string, from, to time.Time, bucket model.ReportBucket, timezone string) ([]model.ProgressReportEntry, error)
+}
+
+// GetProgressReport aggregates user's workout schedules into time buckets, entries are sorted by period start.
+// Periods without any scheduled workout are not returned.
+func (p *PostgresDb) GetProgressReport(userId string, from, to time.Time, bucket model.ReportBucket, timezone string) ([]model.ProgressReportEntry, error) {
+	entries := make(map[int64]*model.ProgressReportEntry)
+	rows, err := p.db.Query(context.Background(), selectScheduleCountsPerPeriod, userId, from, to, timezone, string(bucket))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var entry model.ProgressReportEntry
+		if err := rows.Scan(&entry.PeriodStart, &entry.ScheduledCount, &entry.SessionCount); err != nil {
+			return nil, err
+		}
+		entry.SetsPerMuscleGroup = make(map[string]int32)
+		entries[entry.PeriodStart.Unix()] = &entry
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = p.db.Query(context.Background(), selectVolumePerPeriod, userId, from, to, timezone, string(bucket))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			period      time.Time
+			muscleGroup string
+			volume      float64
+			sets        int32
+		)
+		if err := rows.Scan(&period, &muscleGroup, &volume, &sets); err != nil {
+			return nil, err
+		}
+		//every completed workout is also counted as scheduled, so entry always exists
+		entry, ok := entries[period.Unix()]
+		if !ok {
+			continue
+		}
+		entry.TotalVolume += volume
+		entry.SetsPerMuscleGroup[muscleGroup] += sets
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	result := make([]model.ProgressReportEntry, 0, len(entries))
+	for _, entry := range entries {
+		result = append(result, *entry)
+	}
+	slices.SortFunc(result, func(e1, e2 model.ProgressReportEntry) int {
+		return e1.PeriodStart.Compare(e2.PeriodStart)
+	})
+	return result, nil
+}
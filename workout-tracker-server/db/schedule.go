@@ -2,28 +2,84 @@ package db
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 	"workout-tracker-server/model"
+	"workout-tracker-server/rrule"
+)
+
+var (
+	ErrWorkoutScheduleNotFound       = fmt.Errorf("workout schedule not found")
+	ErrWorkoutScheduleCompleted      = fmt.Errorf("workout schedule completed")
+	ErrWorkoutScheduleSeriesNotFound = fmt.Errorf("workout schedule series not found")
 )
 
 const (
-	insertWorkoutSchedule          = "INSERT INTO workout_schedule(id, owner, workout, scheduled_at) VALUES ($1,$2,$3,$4)"
-	updateWorkoutScheduleCompleted = "UPDATE workout_schedule SET completed = true WHERE id = $1"
-	selectWorkoutScheduleOwner     = "SELECT owner FROM workout_schedule WHERE id = $1"
-	selectWorkoutSchedulesBetween  = "SELECT id, owner, workout, scheduled_at, crated_at, completed FROM workout_schedule WHERE owner = $1 AND scheduled_at >= $2 AND scheduled_at <= $3"
+	insertWorkoutSchedule          = "INSERT INTO workout_schedule(id, owner, workout, scheduled_at, timezone, workout_version) VALUES ($1,$2,$3,$4,$5,(SELECT version FROM workout WHERE id = $3))"
+	updateWorkoutScheduleCompleted = `UPDATE workout_schedule SET completed = true, completed_at = now(), cancelled_at = NULL, cancel_reason = NULL,
+		workout_version = (SELECT version FROM workout WHERE id = workout_schedule.workout) WHERE id = $1`
+	selectWorkoutScheduleOwner      = "SELECT owner FROM workout_schedule WHERE id = $1"
+	workoutScheduleColumns          = "id, owner, workout, scheduled_at, timezone, crated_at, completed, completed_at, series_id, recurrence_id, cancelled_at, cancel_reason, missed_at, workout_version"
+	selectWorkoutSchedulesBetween   = "SELECT " + workoutScheduleColumns + " FROM workout_schedule WHERE owner = $1 AND scheduled_at >= $2 AND scheduled_at <= $3 ORDER BY scheduled_at"
+	selectWorkoutSchedules          = "SELECT " + workoutScheduleColumns + " FROM workout_schedule WHERE owner = $1"
+	selectCompletedWorkoutSchedules = "SELECT " + workoutScheduleColumns + " FROM workout_schedule WHERE owner = $1 AND completed AND completed_at IS NOT NULL ORDER BY completed_at DESC LIMIT $2"
+	updateWorkoutScheduleAt         = "UPDATE workout_schedule SET scheduled_at = $1, missed_at = NULL WHERE id = $2 AND NOT completed"
+	updateWorkoutScheduleCancelled  = "UPDATE workout_schedule SET cancelled_at = now(), cancel_reason = $1 WHERE id = $2 AND NOT completed"
+	updateWorkoutScheduleReopened   = "UPDATE workout_schedule SET completed = false, completed_at = NULL, cancelled_at = NULL, cancel_reason = NULL WHERE id = $1"
+	deleteWorkoutSchedule           = "DELETE FROM workout_schedule WHERE id = $1 RETURNING series_id, recurrence_id"
+
+	insertWorkoutScheduleSeries              = "INSERT INTO workout_schedule_series(id, owner, workout, starts_at, timezone, recurrence, excluded_dates) VALUES ($1,$2,$3,$4,$5,$6,$7)"
+	selectWorkoutScheduleSeriesById          = "SELECT id, owner, workout, starts_at, timezone, recurrence, excluded_dates, created_at FROM workout_schedule_series WHERE id = $1"
+	selectWorkoutScheduleSeriesStartedBefore = "SELECT id, owner, workout, starts_at, timezone, recurrence, excluded_dates, created_at FROM workout_schedule_series WHERE owner = $1 AND starts_at <= $2"
+	selectWorkoutScheduleSeriesByOwner       = "SELECT id, owner, workout, starts_at, timezone, recurrence, excluded_dates, created_at FROM workout_schedule_series WHERE owner = $1"
+	selectDetachedOccurrences                = "SELECT series_id, recurrence_id FROM workout_schedule WHERE series_id = ANY($1)"
+	updateWorkoutScheduleSeriesRecurrence    = "UPDATE workout_schedule_series SET recurrence = $1 WHERE id = $2"
+	updateWorkoutScheduleSeriesExcludeDate   = "UPDATE workout_schedule_series SET excluded_dates = array_append(excluded_dates, $1) WHERE id = $2"
+	deleteWorkoutScheduleSeries              = "DELETE FROM workout_schedule_series WHERE id = $1"
+	deleteDetachedOccurrencesFrom            = "DELETE FROM workout_schedule WHERE series_id = $1 AND recurrence_id >= $2 AND NOT completed"
+	deleteDetachedOccurrence                 = "DELETE FROM workout_schedule WHERE series_id = $1 AND recurrence_id = $2 AND NOT completed"
+	upsertScheduleOccurrence                 = `INSERT INTO workout_schedule(id, owner, workout, scheduled_at, timezone, series_id, recurrence_id, workout_version) VALUES ($1,$2,$3,$4,$5,$6,$7,(SELECT version FROM workout WHERE id = $3))
+		ON CONFLICT (series_id, recurrence_id) DO UPDATE SET scheduled_at = EXCLUDED.scheduled_at RETURNING id`
+	upsertCompletedScheduleOccurrence = `INSERT INTO workout_schedule(id, owner, workout, scheduled_at, timezone, series_id, recurrence_id, completed, completed_at, workout_version)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,true,now(),(SELECT version FROM workout WHERE id = $3))
+		ON CONFLICT (series_id, recurrence_id) DO UPDATE SET completed = true, completed_at = EXCLUDED.completed_at, workout_version = EXCLUDED.workout_version RETURNING id`
 )
 
 type WorkoutScheduleDb interface {
 	SaveWorkoutSchedule(ws model.WorkoutSchedule) (string, error)
 	UpdateWorkoutScheduleCompleted(scheduleId string) error
+	CompleteWorkoutSchedule(scheduleId, userId string, sets []model.ExerciseSet) error
 	IsWorkoutScheduleOwner(scheduleId, userId string) (bool, error)
+	GetWorkoutScheduleOwner(scheduleId string) (string, error)
 	GetWorkoutSchedulesBetweenDates(userId string, from, to time.Time) ([]model.WorkoutSchedule, error)
+	ListWorkoutSchedules(userId string, filter model.WorkoutScheduleFilter) ([]model.WorkoutSchedule, error)
+	GetCompletedWorkoutSchedules(userId string, limit int) ([]model.WorkoutSchedule, error)
+	UpdateWorkoutSchedule(ws model.WorkoutSchedule, mask *fieldmaskpb.FieldMask) error
+	CancelWorkoutSchedule(scheduleId string, reason *string) error
+	ReopenWorkoutSchedule(scheduleId string) error
+	DeleteWorkoutSchedule(scheduleId string) error
+
+	SaveWorkoutScheduleSeries(series model.WorkoutScheduleSeries) (string, error)
+	GetWorkoutScheduleSeries(seriesId string) (model.WorkoutScheduleSeries, error)
+	GetWorkoutScheduleSeriesStartedBefore(userId string, to time.Time) ([]model.WorkoutScheduleSeries, error)
+	GetWorkoutScheduleSeriesByOwner(userId string) ([]model.WorkoutScheduleSeries, error)
+	SaveScheduleOccurrence(series model.WorkoutScheduleSeries, recurrenceId, scheduledAt time.Time) (string, error)
+	CompleteScheduleOccurrence(series model.WorkoutScheduleSeries, recurrenceId time.Time) (string, error)
+	CancelScheduleOccurrence(seriesId string, recurrenceId time.Time) error
+	TruncateWorkoutScheduleSeries(series model.WorkoutScheduleSeries, at time.Time, continuation *model.WorkoutScheduleSeries) (string, error)
 }
 
 func (p *PostgresDb) SaveWorkoutSchedule(ws model.WorkoutSchedule) (string, error) {
 	id := uuid.New().String()
-	_, err := p.db.Exec(context.Background(), insertWorkoutSchedule, id, ws.OwnerID, ws.WorkoutID, ws.ScheduledAt)
+	_, err := p.db.Exec(context.Background(), insertWorkoutSchedule, id, ws.OwnerID, ws.WorkoutID, ws.ScheduledAt, ws.Timezone)
 	if err != nil {
 		return "", err
 	}
@@ -35,29 +91,386 @@ func (p *PostgresDb) UpdateWorkoutScheduleCompleted(scheduleId string) error {
 	return err
 }
 
+// CompleteWorkoutSchedule marks workout schedule completed, replacing its performed sets when any are given, all
+// in one transaction. Returns ErrExerciseNotInWorkout when a set references exercise the workout doesn't have.
+func (p *PostgresDb) CompleteWorkoutSchedule(scheduleId, userId string, sets []model.ExerciseSet) error {
+	tx, err := p.db.BeginTx(context.Background(), pgx.TxOptions{IsoLevel: pgx.ReadCommitted, AccessMode: pgx.ReadWrite})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(context.Background())
+	if len(sets) > 0 {
+		if err := validateScheduledExercises(tx, scheduleId, sets); err != nil {
+			return err
+		}
+		if err := replaceExerciseSets(tx, scheduleId, userId, sets); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(context.Background(), updateWorkoutScheduleCompleted, scheduleId); err != nil {
+		return err
+	}
+	return tx.Commit(context.Background())
+}
+
 func (p *PostgresDb) IsWorkoutScheduleOwner(scheduleId, userId string) (bool, error) {
-	var owner string
-	err := p.db.QueryRow(context.Background(), selectWorkoutScheduleOwner, scheduleId).Scan(&owner)
+	owner, err := p.GetWorkoutScheduleOwner(scheduleId)
 	if err != nil {
 		return false, err
 	}
 	return owner == userId, nil
 }
 
+func (p *PostgresDb) GetWorkoutScheduleOwner(scheduleId string) (string, error) {
+	var owner string
+	err := p.db.QueryRow(context.Background(), selectWorkoutScheduleOwner, scheduleId).Scan(&owner)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", ErrWorkoutScheduleNotFound
+		}
+		return "", err
+	}
+	return owner, nil
+}
+
 func (p *PostgresDb) GetWorkoutSchedulesBetweenDates(userId string, from, to time.Time) ([]model.WorkoutSchedule, error) {
-	rows, err := p.db.Query(context.Background(), selectWorkoutSchedulesBetween, userId, from, to)
+	return p.getWorkoutSchedules(selectWorkoutSchedulesBetween, userId, from, to)
+}
+
+// ListWorkoutSchedules returns user's workout schedules matching the filter, sorted by schedule time and id.
+func (p *PostgresDb) ListWorkoutSchedules(userId string, filter model.WorkoutScheduleFilter) ([]model.WorkoutSchedule, error) {
+	query, args := createListWorkoutSchedulesQuery(userId, filter)
+	return p.getWorkoutSchedules(query, args...)
+}
+
+// GetCompletedWorkoutSchedules returns user's most recently completed workout schedules.
+func (p *PostgresDb) GetCompletedWorkoutSchedules(userId string, limit int) ([]model.WorkoutSchedule, error) {
+	return p.getWorkoutSchedules(selectCompletedWorkoutSchedules, userId, limit)
+}
+
+func createListWorkoutSchedulesQuery(userId string, filter model.WorkoutScheduleFilter) (string, []any) {
+	query := selectWorkoutSchedules
+	args := []any{userId}
+	arg := func(value any) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
+	}
+	if filter.From != nil {
+		query += " AND scheduled_at >= " + arg(*filter.From)
+	}
+	if filter.To != nil {
+		query += " AND scheduled_at <= " + arg(*filter.To)
+	}
+	if filter.After != nil {
+		query += fmt.Sprintf(" AND (scheduled_at, id) > (%s, %s::uuid)", arg(filter.After.ScheduledAt), arg(filter.After.Key))
+	}
+	if len(filter.Statuses) > 0 {
+		var conditions []string
+		for _, s := range filter.Statuses {
+			switch s {
+			case model.WorkoutScheduleStatusPending:
+				conditions = append(conditions, "(cancelled_at IS NULL AND NOT completed AND missed_at IS NULL AND scheduled_at >= "+arg(filter.MissedBefore())+")")
+			case model.WorkoutScheduleStatusMissed:
+				conditions = append(conditions, "(cancelled_at IS NULL AND NOT completed AND (missed_at IS NOT NULL OR scheduled_at < "+arg(filter.MissedBefore())+"))")
+			case model.WorkoutScheduleStatusCompleted:
+				conditions = append(conditions, "completed")
+			case model.WorkoutScheduleStatusCancelled:
+				conditions = append(conditions, "cancelled_at IS NOT NULL")
+			}
+		}
+		query += " AND (" + strings.Join(conditions, " OR ") + ")"
+	}
+	query += " ORDER BY scheduled_at, id"
+	if filter.Limit > 0 {
+		query += " LIMIT " + arg(filter.Limit)
+	}
+	return query, args
+}
+
+func (p *PostgresDb) getWorkoutSchedules(query string, args ...any) ([]model.WorkoutSchedule, error) {
+	rows, err := p.db.Query(context.Background(), query, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 	var schedules []model.WorkoutSchedule
 	for rows.Next() {
-		var ws model.WorkoutSchedule
-		err := rows.Scan(&ws.ID, &ws.OwnerID, &ws.WorkoutID, &ws.ScheduledAt, &ws.CreatedAt, &ws.Completed)
+		ws, err := scanWorkoutSchedule(rows)
 		if err != nil {
 			return nil, err
 		}
 		schedules = append(schedules, ws)
 	}
-	return schedules, nil
+	return schedules, rows.Err()
+}
+
+// scanWorkoutSchedule scans workout schedule columns, extra destinations are scanned from columns following them.
+func scanWorkoutSchedule(row pgx.Row, extra ...any) (model.WorkoutSchedule, error) {
+	var ws model.WorkoutSchedule
+	dest := append([]any{&ws.ID, &ws.OwnerID, &ws.WorkoutID, &ws.ScheduledAt, &ws.Timezone, &ws.CreatedAt, &ws.Completed, &ws.CompletedAt, &ws.SeriesID, &ws.RecurrenceID, &ws.CancelledAt, &ws.CancelReason, &ws.MissedAt, &ws.WorkoutVersion}, extra...)
+	err := row.Scan(dest...)
+	return ws, err
+}
+
+// UpdateWorkoutSchedule updates workout schedule with given mask, only "schedule_at" path is supported.
+// Completed workout schedule can't be rescheduled.
+func (p *PostgresDb) UpdateWorkoutSchedule(ws model.WorkoutSchedule, mask *fieldmaskpb.FieldMask) error {
+	if !slices.Contains(mask.GetPaths(), "schedule_at") {
+		return nil
+	}
+	tag, err := p.db.Exec(context.Background(), updateWorkoutScheduleAt, ws.ScheduledAt, ws.ID)
+	if err != nil {
+		return err
+	}
+	return p.checkWorkoutScheduleUpdated(ws.ID, tag)
+}
+
+// CancelWorkoutSchedule marks workout schedule as cancelled, completed workout schedule can't be cancelled.
+func (p *PostgresDb) CancelWorkoutSchedule(scheduleId string, reason *string) error {
+	tag, err := p.db.Exec(context.Background(), updateWorkoutScheduleCancelled, reason, scheduleId)
+	if err != nil {
+		return err
+	}
+	return p.checkWorkoutScheduleUpdated(scheduleId, tag)
+}
+
+// ReopenWorkoutSchedule reverts both completion and cancellation of workout schedule.
+func (p *PostgresDb) ReopenWorkoutSchedule(scheduleId string) error {
+	tag, err := p.db.Exec(context.Background(), updateWorkoutScheduleReopened, scheduleId)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrWorkoutScheduleNotFound
+	}
+	return nil
+}
+
+// DeleteWorkoutSchedule deletes workout schedule. Deleted occurrence of recurring series is excluded from the series,
+// so it's not expanded again.
+func (p *PostgresDb) DeleteWorkoutSchedule(scheduleId string) error {
+	tx, err := p.db.BeginTx(context.Background(), pgx.TxOptions{IsoLevel: pgx.ReadCommitted, AccessMode: pgx.ReadWrite})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(context.Background())
+	var seriesId *string
+	var recurrenceId *time.Time
+	err = tx.QueryRow(context.Background(), deleteWorkoutSchedule, scheduleId).Scan(&seriesId, &recurrenceId)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrWorkoutScheduleNotFound
+		}
+		return err
+	}
+	if seriesId != nil && recurrenceId != nil {
+		if _, err := tx.Exec(context.Background(), updateWorkoutScheduleSeriesExcludeDate, *recurrenceId, *seriesId); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(context.Background())
+}
+
+// checkWorkoutScheduleUpdated distinguishes missing workout schedule from completed one when no row was updated.
+func (p *PostgresDb) checkWorkoutScheduleUpdated(scheduleId string, tag pgconn.CommandTag) error {
+	if tag.RowsAffected() > 0 {
+		return nil
+	}
+	var owner string
+	err := p.db.QueryRow(context.Background(), selectWorkoutScheduleOwner, scheduleId).Scan(&owner)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrWorkoutScheduleNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return ErrWorkoutScheduleCompleted
+}
+
+func (p *PostgresDb) SaveWorkoutScheduleSeries(series model.WorkoutScheduleSeries) (string, error) {
+	id := uuid.New().String()
+	_, err := p.db.Exec(context.Background(), insertWorkoutScheduleSeries, workoutScheduleSeriesArgs(id, series)...)
+	if err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+func workoutScheduleSeriesArgs(id string, series model.WorkoutScheduleSeries) []any {
+	excludedDates := series.ExcludedDates
+	if excludedDates == nil {
+		excludedDates = []time.Time{}
+	}
+	return []any{id, series.OwnerID, series.WorkoutID, series.StartsAt, series.Timezone, series.Recurrence.String(), excludedDates}
+}
+
+// GetWorkoutScheduleSeries returns series with its detached occurrence dates.
+func (p *PostgresDb) GetWorkoutScheduleSeries(seriesId string) (model.WorkoutScheduleSeries, error) {
+	series, err := scanWorkoutScheduleSeries(p.db.QueryRow(context.Background(), selectWorkoutScheduleSeriesById, seriesId))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.WorkoutScheduleSeries{}, ErrWorkoutScheduleSeriesNotFound
+		}
+		return model.WorkoutScheduleSeries{}, err
+	}
+	seriesList := []model.WorkoutScheduleSeries{series}
+	if err := p.fillDetachedDates(seriesList); err != nil {
+		return model.WorkoutScheduleSeries{}, err
+	}
+	return seriesList[0], nil
+}
+
+// GetWorkoutScheduleSeriesStartedBefore returns user's series that started before given time, these are all series
+// that can have occurrences before that time. Series are returned with detached occurrence dates.
+func (p *PostgresDb) GetWorkoutScheduleSeriesStartedBefore(userId string, to time.Time) ([]model.WorkoutScheduleSeries, error) {
+	return p.getWorkoutScheduleSeriesList(selectWorkoutScheduleSeriesStartedBefore, userId, to)
+}
+
+// GetWorkoutScheduleSeriesByOwner returns all user's series with detached occurrence dates.
+func (p *PostgresDb) GetWorkoutScheduleSeriesByOwner(userId string) ([]model.WorkoutScheduleSeries, error) {
+	return p.getWorkoutScheduleSeriesList(selectWorkoutScheduleSeriesByOwner, userId)
+}
+
+func (p *PostgresDb) getWorkoutScheduleSeriesList(query string, args ...any) ([]model.WorkoutScheduleSeries, error) {
+	rows, err := p.db.Query(context.Background(), query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var seriesList []model.WorkoutScheduleSeries
+	for rows.Next() {
+		series, err := scanWorkoutScheduleSeries(rows)
+		if err != nil {
+			return nil, err
+		}
+		seriesList = append(seriesList, series)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if err := p.fillDetachedDates(seriesList); err != nil {
+		return nil, err
+	}
+	return seriesList, nil
+}
+
+func (p *PostgresDb) fillDetachedDates(seriesList []model.WorkoutScheduleSeries) error {
+	if len(seriesList) == 0 {
+		return nil
+	}
+	ids := make([]string, 0, len(seriesList))
+	for _, series := range seriesList {
+		ids = append(ids, series.ID)
+	}
+	rows, err := p.db.Query(context.Background(), selectDetachedOccurrences, ids)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var seriesId string
+		var recurrenceId time.Time
+		if err := rows.Scan(&seriesId, &recurrenceId); err != nil {
+			return err
+		}
+		for i := range seriesList {
+			if seriesList[i].ID == seriesId {
+				seriesList[i].DetachedDates = append(seriesList[i].DetachedDates, recurrenceId)
+			}
+		}
+	}
+	return rows.Err()
+}
+
+// scanWorkoutScheduleSeries scans series columns, extra destinations are scanned from columns following them.
+func scanWorkoutScheduleSeries(row pgx.Row, extra ...any) (model.WorkoutScheduleSeries, error) {
+	var series model.WorkoutScheduleSeries
+	var recurrence string
+	dest := append([]any{&series.ID, &series.OwnerID, &series.WorkoutID, &series.StartsAt, &series.Timezone, &recurrence, &series.ExcludedDates, &series.CreatedAt}, extra...)
+	err := row.Scan(dest...)
+	if err != nil {
+		return model.WorkoutScheduleSeries{}, err
+	}
+	series.Recurrence, err = rrule.Parse(recurrence)
+	if err != nil {
+		return model.WorkoutScheduleSeries{}, fmt.Errorf("invalid recurrence of series %s: %w", series.ID, err)
+	}
+	return series, nil
+}
+
+// SaveScheduleOccurrence detaches occurrence of the series into standalone workout schedule with given time.
+// If occurrence is already detached, its time is updated. Returns id of the workout schedule.
+func (p *PostgresDb) SaveScheduleOccurrence(series model.WorkoutScheduleSeries, recurrenceId, scheduledAt time.Time) (string, error) {
+	var id string
+	err := p.db.QueryRow(context.Background(), upsertScheduleOccurrence,
+		uuid.New().String(), series.OwnerID, series.WorkoutID, scheduledAt, series.Timezone, series.ID, recurrenceId,
+	).Scan(&id)
+	return id, err
+}
+
+// CompleteScheduleOccurrence marks occurrence of the series as completed, detaching it when needed.
+// Returns id of the workout schedule.
+func (p *PostgresDb) CompleteScheduleOccurrence(series model.WorkoutScheduleSeries, recurrenceId time.Time) (string, error) {
+	var id string
+	err := p.db.QueryRow(context.Background(), upsertCompletedScheduleOccurrence,
+		uuid.New().String(), series.OwnerID, series.WorkoutID, recurrenceId, series.Timezone, series.ID, recurrenceId,
+	).Scan(&id)
+	return id, err
+}
+
+// CancelScheduleOccurrence excludes single occurrence from the series, detached occurrence is removed unless completed.
+func (p *PostgresDb) CancelScheduleOccurrence(seriesId string, recurrenceId time.Time) error {
+	tx, err := p.db.BeginTx(context.Background(), pgx.TxOptions{IsoLevel: pgx.ReadCommitted, AccessMode: pgx.ReadWrite})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(context.Background())
+	tag, err := tx.Exec(context.Background(), updateWorkoutScheduleSeriesExcludeDate, recurrenceId, seriesId)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrWorkoutScheduleSeriesNotFound
+	}
+	if _, err := tx.Exec(context.Background(), deleteDetachedOccurrence, seriesId, recurrenceId); err != nil {
+		return err
+	}
+	return tx.Commit(context.Background())
+}
+
+// TruncateWorkoutScheduleSeries ends the series right before given occurrence, removing all following detached
+// occurrences unless completed. When continuation is given it's saved as new series, which id is returned.
+// Series truncated at its first occurrence is deleted, its completed occurrences are kept as standalone schedules.
+func (p *PostgresDb) TruncateWorkoutScheduleSeries(series model.WorkoutScheduleSeries, at time.Time, continuation *model.WorkoutScheduleSeries) (string, error) {
+	startsAt, err := series.LocalStartsAt()
+	if err != nil {
+		return "", err
+	}
+	tx, err := p.db.BeginTx(context.Background(), pgx.TxOptions{IsoLevel: pgx.ReadCommitted, AccessMode: pgx.ReadWrite})
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback(context.Background())
+	if _, err := tx.Exec(context.Background(), deleteDetachedOccurrencesFrom, series.ID, at); err != nil {
+		return "", err
+	}
+	if series.Recurrence.Index(startsAt, at) == 0 {
+		_, err = tx.Exec(context.Background(), deleteWorkoutScheduleSeries, series.ID)
+	} else {
+		_, err = tx.Exec(context.Background(), updateWorkoutScheduleSeriesRecurrence, series.Recurrence.EndingBefore(at).String(), series.ID)
+	}
+	if err != nil {
+		return "", err
+	}
+	var continuationId string
+	if continuation != nil {
+		continuationId = uuid.New().String()
+		if _, err := tx.Exec(context.Background(), insertWorkoutScheduleSeries, workoutScheduleSeriesArgs(continuationId, *continuation)...); err != nil {
+			return "", err
+		}
+	}
+	if err := tx.Commit(context.Background()); err != nil {
+		return "", err
+	}
+	return continuationId, nil
 }
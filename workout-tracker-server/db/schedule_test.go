@@ -7,9 +7,11 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
 	"testing"
 	"time"
 	"workout-tracker-server/model"
+	"workout-tracker-server/rrule"
 	"workout-tracker-server/test"
 )
 
@@ -76,6 +78,7 @@ func (s *ScheduleSuite) TestSaveGetBetweenDates() {
 	s.Require().Equal(ws.ScheduledAt, wss[0].ScheduledAt)
 	s.Require().NotEmpty(wss[0].CreatedAt)
 	s.Require().False(wss[0].Completed)
+	s.Require().Nil(wss[0].CompletedAt)
 }
 
 func (s *ScheduleSuite) TestGetWorkoutSchedulesBetweenDatesEmpty() {
@@ -157,6 +160,7 @@ func (s *ScheduleSuite) TestUpdateCompleted() {
 	s.Require().NoError(err)
 	s.Require().Len(wss, 1)
 	s.Require().True(wss[0].Completed)
+	s.Require().NotNil(wss[0].CompletedAt)
 }
 
 func (s *ScheduleSuite) TestIsOwner() {
@@ -176,3 +180,346 @@ func (s *ScheduleSuite) TestIsOwner() {
 	s.Require().NoError(err)
 	s.Require().True(isOwner)
 }
+
+func (s *ScheduleSuite) TestIsOwnerNotFound() {
+	_, err := s.wsDB.IsWorkoutScheduleOwner(uuid.New().String(), uuid.New().String())
+
+	s.Require().ErrorIs(err, ErrWorkoutScheduleNotFound)
+}
+
+func (s *ScheduleSuite) TestUpdateScheduledAt() {
+	//given
+	ws := s.saveWorkoutSchedule()
+	rescheduledAt := ws.ScheduledAt.Add(2 * time.Hour)
+
+	//when
+	err := s.wsDB.UpdateWorkoutSchedule(model.WorkoutSchedule{ID: ws.ID, ScheduledAt: rescheduledAt}, &fieldmaskpb.FieldMask{Paths: []string{"schedule_at"}})
+
+	//then
+	s.Require().NoError(err)
+	wss, err := s.wsDB.GetWorkoutSchedulesBetweenDates(ws.OwnerID, rescheduledAt, rescheduledAt)
+	s.Require().NoError(err)
+	s.Require().Len(wss, 1)
+}
+
+func (s *ScheduleSuite) TestUpdateCompletedScheduleFails() {
+	//given
+	ws := s.saveWorkoutSchedule()
+	s.Require().NoError(s.wsDB.UpdateWorkoutScheduleCompleted(ws.ID))
+
+	//when
+	updateErr := s.wsDB.UpdateWorkoutSchedule(model.WorkoutSchedule{ID: ws.ID, ScheduledAt: ws.ScheduledAt.Add(time.Hour)}, &fieldmaskpb.FieldMask{Paths: []string{"schedule_at"}})
+	cancelErr := s.wsDB.CancelWorkoutSchedule(ws.ID, nil)
+
+	//then
+	s.Require().ErrorIs(updateErr, ErrWorkoutScheduleCompleted)
+	s.Require().ErrorIs(cancelErr, ErrWorkoutScheduleCompleted)
+}
+
+func (s *ScheduleSuite) TestCancelAndReopen() {
+	//given
+	ws := s.saveWorkoutSchedule()
+	reason := "injury"
+
+	//when
+	err := s.wsDB.CancelWorkoutSchedule(ws.ID, &reason)
+
+	//then
+	s.Require().NoError(err)
+	wss, err := s.wsDB.GetWorkoutSchedulesBetweenDates(ws.OwnerID, ws.ScheduledAt, ws.ScheduledAt)
+	s.Require().NoError(err)
+	s.Require().Len(wss, 1)
+	s.Require().NotNil(wss[0].CancelledAt)
+	s.Require().Equal(reason, *wss[0].CancelReason)
+
+	//when
+	err = s.wsDB.ReopenWorkoutSchedule(ws.ID)
+
+	//then
+	s.Require().NoError(err)
+	wss, err = s.wsDB.GetWorkoutSchedulesBetweenDates(ws.OwnerID, ws.ScheduledAt, ws.ScheduledAt)
+	s.Require().NoError(err)
+	s.Require().Nil(wss[0].CancelledAt)
+	s.Require().Nil(wss[0].CancelReason)
+}
+
+func (s *ScheduleSuite) TestUpdateNotFound() {
+	id := uuid.New().String()
+
+	s.Require().ErrorIs(s.wsDB.CancelWorkoutSchedule(id, nil), ErrWorkoutScheduleNotFound)
+	s.Require().ErrorIs(s.wsDB.ReopenWorkoutSchedule(id), ErrWorkoutScheduleNotFound)
+	s.Require().ErrorIs(s.wsDB.DeleteWorkoutSchedule(id), ErrWorkoutScheduleNotFound)
+}
+
+func (s *ScheduleSuite) TestDeleteDetachedOccurrenceExcludesIt() {
+	//given
+	series := s.saveDailySeries()
+	recurrenceId := series.StartsAt.AddDate(0, 0, 1)
+	id, err := s.wsDB.SaveScheduleOccurrence(series, recurrenceId, recurrenceId.Add(time.Hour))
+	s.Require().NoError(err)
+
+	//when
+	err = s.wsDB.DeleteWorkoutSchedule(id)
+
+	//then
+	s.Require().NoError(err)
+	saved, err := s.wsDB.GetWorkoutScheduleSeries(series.ID)
+	s.Require().NoError(err)
+	s.Require().Equal([]time.Time{recurrenceId}, saved.ExcludedDates)
+	s.Require().Empty(saved.DetachedDates)
+}
+
+func (s *ScheduleSuite) saveWorkoutSchedule() model.WorkoutSchedule {
+	ws := model.WorkoutSchedule{
+		OwnerID:     uuid.New().String(),
+		WorkoutID:   s.existingWorkoutId,
+		ScheduledAt: time.Now().UTC().Truncate(time.Microsecond),
+	}
+	id, err := s.wsDB.SaveWorkoutSchedule(ws)
+	s.Require().NoError(err)
+	ws.ID = id
+	return ws
+}
+
+func (s *ScheduleSuite) TestListWorkoutSchedulesByStatus() {
+	//given pending, missed, completed, cancelled and pending within grace period schedules
+	owner := uuid.New().String()
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	var ids []string
+	for _, scheduledAt := range []time.Time{now.Add(time.Hour), now.Add(-2 * time.Hour), now.Add(-3 * time.Hour), now.Add(-4 * time.Hour), now.Add(-30 * time.Minute)} {
+		id, err := s.wsDB.SaveWorkoutSchedule(model.WorkoutSchedule{OwnerID: owner, WorkoutID: s.existingWorkoutId, ScheduledAt: scheduledAt})
+		s.Require().NoError(err)
+		ids = append(ids, id)
+	}
+	s.Require().NoError(s.wsDB.UpdateWorkoutScheduleCompleted(ids[2]))
+	s.Require().NoError(s.wsDB.CancelWorkoutSchedule(ids[3], nil))
+
+	testCases := []struct {
+		name     string
+		statuses []model.WorkoutScheduleStatus
+		expected []string
+	}{
+		{"All", nil, []string{ids[3], ids[2], ids[1], ids[4], ids[0]}},
+		{"Pending", []model.WorkoutScheduleStatus{model.WorkoutScheduleStatusPending}, []string{ids[4], ids[0]}},
+		{"Missed", []model.WorkoutScheduleStatus{model.WorkoutScheduleStatusMissed}, []string{ids[1]}},
+		{"CompletedOrCancelled", []model.WorkoutScheduleStatus{model.WorkoutScheduleStatusCompleted, model.WorkoutScheduleStatusCancelled}, []string{ids[3], ids[2]}},
+	}
+	for _, testCase := range testCases {
+		s.T().Run(testCase.name, func(t *testing.T) {
+			//when
+			wss, err := s.wsDB.ListWorkoutSchedules(owner, model.WorkoutScheduleFilter{Statuses: testCase.statuses, Now: now})
+
+			//then
+			require.NoError(t, err)
+			var actual []string
+			for _, ws := range wss {
+				actual = append(actual, ws.ID)
+			}
+			require.Equal(t, testCase.expected, actual)
+		})
+	}
+}
+
+func (s *ScheduleSuite) TestGetCompletedWorkoutSchedules() {
+	//given two completed and one pending schedule
+	owner := uuid.New().String()
+	var ids []string
+	for i := range 3 {
+		id, err := s.wsDB.SaveWorkoutSchedule(model.WorkoutSchedule{OwnerID: owner, WorkoutID: s.existingWorkoutId, ScheduledAt: time.Now().UTC().Add(time.Duration(i) * time.Hour)})
+		s.Require().NoError(err)
+		ids = append(ids, id)
+	}
+	s.Require().NoError(s.wsDB.UpdateWorkoutScheduleCompleted(ids[0]))
+	s.Require().NoError(s.wsDB.UpdateWorkoutScheduleCompleted(ids[1]))
+
+	//when
+	completed, err := s.wsDB.GetCompletedWorkoutSchedules(owner, 1)
+
+	//then most recently completed is returned
+	s.Require().NoError(err)
+	s.Require().Len(completed, 1)
+	s.Require().Equal(ids[1], completed[0].ID)
+	s.Require().NotNil(completed[0].CompletedAt)
+}
+
+func (s *ScheduleSuite) TestListWorkoutSchedulesPagination() {
+	//given
+	owner := uuid.New().String()
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	for i := range 3 {
+		_, err := s.wsDB.SaveWorkoutSchedule(model.WorkoutSchedule{OwnerID: owner, WorkoutID: s.existingWorkoutId, ScheduledAt: now.Add(time.Duration(i) * time.Hour)})
+		s.Require().NoError(err)
+	}
+
+	//when
+	firstPage, err := s.wsDB.ListWorkoutSchedules(owner, model.WorkoutScheduleFilter{Now: now, Limit: 2})
+	s.Require().NoError(err)
+	cursor := firstPage[1].Cursor()
+	secondPage, err := s.wsDB.ListWorkoutSchedules(owner, model.WorkoutScheduleFilter{Now: now, Limit: 2, After: &cursor})
+
+	//then
+	s.Require().NoError(err)
+	s.Require().Len(firstPage, 2)
+	s.Require().Len(secondPage, 1)
+	s.Require().Equal(now.Add(2*time.Hour), secondPage[0].ScheduledAt)
+}
+
+func (s *ScheduleSuite) TestSaveGetSeries() {
+	//given
+	startsAt := time.Date(2024, 1, 15, 7, 0, 0, 0, time.UTC)
+	rule, err := rrule.Parse("FREQ=WEEKLY;BYDAY=MO,TH;COUNT=10")
+	s.Require().NoError(err)
+	series := model.WorkoutScheduleSeries{
+		OwnerID:       uuid.New().String(),
+		WorkoutID:     s.existingWorkoutId,
+		StartsAt:      startsAt,
+		Timezone:      "Europe/Warsaw",
+		Recurrence:    rule,
+		ExcludedDates: []time.Time{startsAt.AddDate(0, 0, 3)},
+	}
+
+	//when
+	id, err := s.wsDB.SaveWorkoutScheduleSeries(series)
+	s.Require().NoError(err)
+	saved, err := s.wsDB.GetWorkoutScheduleSeries(id)
+
+	//then
+	s.Require().NoError(err)
+	s.Require().Equal(id, saved.ID)
+	s.Require().Equal(series.OwnerID, saved.OwnerID)
+	s.Require().Equal(series.WorkoutID, saved.WorkoutID)
+	s.Require().Equal(startsAt, saved.StartsAt)
+	s.Require().Equal("Europe/Warsaw", saved.Timezone)
+	s.Require().Equal(rule, saved.Recurrence)
+	s.Require().Equal(series.ExcludedDates, saved.ExcludedDates)
+	s.Require().Empty(saved.DetachedDates)
+	s.Require().NotEmpty(saved.CreatedAt)
+}
+
+func (s *ScheduleSuite) TestGetSeriesNotFound() {
+	//when
+	_, err := s.wsDB.GetWorkoutScheduleSeries(uuid.New().String())
+
+	//then
+	s.Require().ErrorIs(err, ErrWorkoutScheduleSeriesNotFound)
+}
+
+func (s *ScheduleSuite) TestGetSeriesStartedBefore() {
+	//given
+	owner := uuid.New().String()
+	startsAt := time.Date(2024, 1, 15, 7, 0, 0, 0, time.UTC)
+	for _, start := range []time.Time{startsAt, startsAt.AddDate(0, 1, 0)} {
+		_, err := s.wsDB.SaveWorkoutScheduleSeries(model.WorkoutScheduleSeries{
+			OwnerID:    owner,
+			WorkoutID:  s.existingWorkoutId,
+			StartsAt:   start,
+			Recurrence: rrule.Rule{Freq: rrule.Daily, Interval: 1},
+		})
+		s.Require().NoError(err)
+	}
+
+	//when
+	seriesList, err := s.wsDB.GetWorkoutScheduleSeriesStartedBefore(owner, startsAt.AddDate(0, 0, 7))
+
+	//then
+	s.Require().NoError(err)
+	s.Require().Len(seriesList, 1)
+	s.Require().Equal(startsAt, seriesList[0].StartsAt)
+}
+
+func (s *ScheduleSuite) TestSaveAndCompleteOccurrence() {
+	//given
+	series := s.saveDailySeries()
+	recurrenceId := series.StartsAt.AddDate(0, 0, 1)
+	movedTo := recurrenceId.Add(2 * time.Hour)
+
+	//when occurrence is moved and later completed
+	id, err := s.wsDB.SaveScheduleOccurrence(series, recurrenceId, movedTo)
+	s.Require().NoError(err)
+	completedId, err := s.wsDB.CompleteScheduleOccurrence(series, recurrenceId)
+	s.Require().NoError(err)
+
+	//then single detached schedule exists
+	s.Require().Equal(id, completedId)
+	wss, err := s.wsDB.GetWorkoutSchedulesBetweenDates(series.OwnerID, recurrenceId, movedTo)
+	s.Require().NoError(err)
+	s.Require().Len(wss, 1)
+	s.Require().Equal(movedTo, wss[0].ScheduledAt)
+	s.Require().True(wss[0].Completed)
+	s.Require().Equal(series.ID, *wss[0].SeriesID)
+	s.Require().Equal(recurrenceId, *wss[0].RecurrenceID)
+
+	saved, err := s.wsDB.GetWorkoutScheduleSeries(series.ID)
+	s.Require().NoError(err)
+	s.Require().Equal([]time.Time{recurrenceId}, saved.DetachedDates)
+}
+
+func (s *ScheduleSuite) TestCancelOccurrence() {
+	//given detached occurrence
+	series := s.saveDailySeries()
+	recurrenceId := series.StartsAt.AddDate(0, 0, 1)
+	_, err := s.wsDB.SaveScheduleOccurrence(series, recurrenceId, recurrenceId.Add(time.Hour))
+	s.Require().NoError(err)
+
+	//when
+	err = s.wsDB.CancelScheduleOccurrence(series.ID, recurrenceId)
+
+	//then occurrence is excluded and detached schedule removed
+	s.Require().NoError(err)
+	saved, err := s.wsDB.GetWorkoutScheduleSeries(series.ID)
+	s.Require().NoError(err)
+	s.Require().Equal([]time.Time{recurrenceId}, saved.ExcludedDates)
+	s.Require().Empty(saved.DetachedDates)
+}
+
+func (s *ScheduleSuite) TestTruncateSeriesWithContinuation() {
+	//given series with completed and pending detached occurrences after truncation point
+	series := s.saveDailySeries()
+	at := series.StartsAt.AddDate(0, 0, 2)
+	_, err := s.wsDB.CompleteScheduleOccurrence(series, at)
+	s.Require().NoError(err)
+	_, err = s.wsDB.SaveScheduleOccurrence(series, at.AddDate(0, 0, 1), at.AddDate(0, 0, 1).Add(time.Hour))
+	s.Require().NoError(err)
+	continuation, err := series.ContinuationFrom(at, at.Add(time.Hour))
+	s.Require().NoError(err)
+
+	//when
+	continuationId, err := s.wsDB.TruncateWorkoutScheduleSeries(series, at, &continuation)
+
+	//then
+	s.Require().NoError(err)
+	truncated, err := s.wsDB.GetWorkoutScheduleSeries(series.ID)
+	s.Require().NoError(err)
+	s.Require().Equal(at.Add(-time.Second), truncated.Recurrence.Until)
+	s.Require().Equal([]time.Time{at}, truncated.DetachedDates)
+
+	saved, err := s.wsDB.GetWorkoutScheduleSeries(continuationId)
+	s.Require().NoError(err)
+	s.Require().Equal(at.Add(time.Hour), saved.StartsAt)
+}
+
+func (s *ScheduleSuite) TestTruncateSeriesAtFirstOccurrence() {
+	//given
+	series := s.saveDailySeries()
+
+	//when
+	_, err := s.wsDB.TruncateWorkoutScheduleSeries(series, series.StartsAt, nil)
+
+	//then
+	s.Require().NoError(err)
+	_, err = s.wsDB.GetWorkoutScheduleSeries(series.ID)
+	s.Require().ErrorIs(err, ErrWorkoutScheduleSeriesNotFound)
+}
+
+func (s *ScheduleSuite) saveDailySeries() model.WorkoutScheduleSeries {
+	series := model.WorkoutScheduleSeries{
+		OwnerID:    uuid.New().String(),
+		WorkoutID:  s.existingWorkoutId,
+		StartsAt:   time.Date(2024, 1, 15, 7, 0, 0, 0, time.UTC),
+		Recurrence: rrule.Rule{Freq: rrule.Daily, Interval: 1},
+	}
+	id, err := s.wsDB.SaveWorkoutScheduleSeries(series)
+	s.Require().NoError(err)
+	series.ID = id
+	return series
+}
@@ -0,0 +1,84 @@
+package db
+
+import (
+	"context"
+	"html"
+	"strings"
+	"workout-tracker-server/model"
+)
+
+const (
+	// matched words are marked with control characters, which are removed from the source text, and replaced with
+	// <b></b> only after the fragment is HTML-escaped
+	highlightStartSel = "\x01"
+	highlightStopSel  = "\x02"
+	headlineOptions   = "StartSel=" + highlightStartSel + ", StopSel=" + highlightStopSel + ", MaxWords=25, MinWords=10, MaxFragments=2"
+	headlineStart     = "ts_headline('english', translate("
+	headlineEnd       = ", chr(1) || chr(2), ''), q.query, '" + headlineOptions + "')"
+	// matches either full-text query or, to tolerate typos, word similarity of the whole query to the name or comment,
+	// templates and deleted workouts are not searched
+	searchQuery = `WITH q AS (SELECT websearch_to_tsquery('english', $2) AS query)
+		SELECT 'workout', w.id, w.id, w.name,
+			` + headlineStart + `concat_ws(' ', w.name, w.comment)` + headlineEnd + `,
+			ts_rank(w.search, q.query) + word_similarity($2, w.name) AS rank
+		FROM workout w, q
+		WHERE 'workout' = ANY($3::text[]) AND w.owner = $1 AND NOT w.template AND w.deleted_at IS NULL
+			AND (w.search @@ q.query OR $2 <% w.name)
+		UNION ALL
+		SELECT 'workout_exercise', we.workout_exercise_id, w.id, e.name,
+			` + headlineStart + `we.comment` + headlineEnd + `,
+			ts_rank(we.search, q.query) + word_similarity($2, we.comment)
+		FROM workout_exercise we JOIN workout w ON w.id = we.workout_id JOIN exercise e ON e.id = we.exercise_id, q
+		WHERE 'workout_exercise' = ANY($3::text[]) AND w.owner = $1 AND NOT w.template AND w.deleted_at IS NULL
+			AND (we.search @@ q.query OR $2 <% we.comment)
+		UNION ALL
+		SELECT 'exercise', e.id, NULL, e.name,
+			` + headlineStart + `concat_ws(' ', e.name, e.description)` + headlineEnd + `,
+			ts_rank(e.search, q.query) + word_similarity($2, e.name)
+		FROM exercise e, q
+		WHERE 'exercise' = ANY($3::text[]) AND (e.owner IS NULL OR e.owner = $1) AND (e.search @@ q.query OR $2 <% e.name)
+		ORDER BY rank DESC, 4, 2
+		LIMIT $4`
+)
+
+var highlightReplacer = strings.NewReplacer(highlightStartSel, "<b>", highlightStopSel, "</b>")
+
+var allSearchResultTypes = []model.SearchResultType{
+	model.SearchResultTypeWorkout,
+	model.SearchResultTypeWorkoutExercise,
+	model.SearchResultTypeExercise,
+}
+
+type SearchDb interface {
+	Search(userId string, filter model.SearchFilter) ([]model.SearchResult, error)
+}
+
+// Search returns user's workouts, comments of their exercises and global or user's custom exercises matching the query,
+// best ranked first.
+func (p *PostgresDb) Search(userId string, filter model.SearchFilter) ([]model.SearchResult, error) {
+	searchedTypes := filter.Types
+	if len(searchedTypes) == 0 {
+		searchedTypes = allSearchResultTypes
+	}
+	var types []string
+	for _, t := range searchedTypes {
+		types = append(types, string(t))
+	}
+	rows, err := p.db.Query(context.Background(), searchQuery, userId, filter.Query, types, filter.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var results []model.SearchResult
+	for rows.Next() {
+		var result model.SearchResult
+		var headline string
+		err := rows.Scan(&result.Type, &result.ID, &result.WorkoutID, &result.Title, &headline, &result.Rank)
+		if err != nil {
+			return nil, err
+		}
+		result.Highlight = highlightReplacer.Replace(html.EscapeString(headline))
+		results = append(results, result)
+	}
+	return results, rows.Err()
+}
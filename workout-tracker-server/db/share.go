@@ -0,0 +1,131 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"workout-tracker-server/model"
+)
+
+var (
+	ErrWorkoutShareNotFound   = fmt.Errorf("workout share not found")
+	ErrShareRecipientNotFound = fmt.Errorf("share recipient not found")
+)
+
+const (
+	workoutShareColumns = "s.id, s.workout_id, s.owner, s.recipient_email, s.recipient_id, s.permission, s.created_at, w.name"
+
+	insertWorkoutShare = `INSERT INTO workout_share (id, workout_id, owner, token_hash, recipient_email, recipient_id, permission)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING created_at`
+	selectUserIdByEmail    = `SELECT id FROM "user" WHERE lower(email) = lower($1)`
+	selectWorkoutShareById = "SELECT " + workoutShareColumns + " FROM workout_share s JOIN workout w ON w.id = s.workout_id WHERE s.id = $1"
+	selectWorkoutShares    = "SELECT " + workoutShareColumns + ` FROM workout_share s JOIN workout w ON w.id = s.workout_id
+		WHERE s.workout_id = $1 ORDER BY s.created_at`
+	selectWorkoutShareByToken = "SELECT " + workoutShareColumns + " FROM workout_share s JOIN workout w ON w.id = s.workout_id WHERE s.token_hash = $1"
+	// shares of deleted workouts are hidden until restore
+	selectReceivedWorkoutShares = "SELECT " + workoutShareColumns + ` FROM workout_share s JOIN workout w ON w.id = s.workout_id
+		WHERE s.recipient_id = $1 AND w.deleted_at IS NULL ORDER BY s.created_at DESC`
+	selectReceivedWorkoutShare = "SELECT " + workoutShareColumns + ` FROM workout_share s JOIN workout w ON w.id = s.workout_id
+		WHERE s.id = $1 AND s.recipient_id = $2`
+	deleteWorkoutShare = "DELETE FROM workout_share WHERE id = $1"
+)
+
+type WorkoutShareDb interface {
+	// SaveWorkoutShare stores share, token hash is set for link shares.
+	SaveWorkoutShare(share model.WorkoutShare, tokenHash *string) (model.WorkoutShare, error)
+	GetWorkoutShare(id string) (model.WorkoutShare, error)
+	GetWorkoutShares(workoutId string) ([]model.WorkoutShare, error)
+	GetWorkoutShareByToken(tokenHash string) (model.WorkoutShare, error)
+	GetReceivedWorkoutShares(userId string) ([]model.WorkoutShare, error)
+	GetReceivedWorkoutShare(id, userId string) (model.WorkoutShare, error)
+	DeleteWorkoutShare(id string) error
+}
+
+// SaveWorkoutShare resolves recipient of email share to registered user, returns ErrShareRecipientNotFound when there
+// is no user with the email. Users registered later don't receive the share.
+func (p *PostgresDb) SaveWorkoutShare(share model.WorkoutShare, tokenHash *string) (model.WorkoutShare, error) {
+	share.ID = uuid.New().String()
+	if share.RecipientEmail != nil {
+		var recipientId string
+		err := p.db.QueryRow(context.Background(), selectUserIdByEmail, *share.RecipientEmail).Scan(&recipientId)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.WorkoutShare{}, ErrShareRecipientNotFound
+		}
+		if err != nil {
+			return model.WorkoutShare{}, err
+		}
+		share.RecipientID = &recipientId
+	}
+	err := p.db.QueryRow(context.Background(), insertWorkoutShare,
+		share.ID, share.WorkoutID, share.OwnerID, tokenHash, share.RecipientEmail, share.RecipientID, share.Permission,
+	).Scan(&share.CreatedAt)
+	if err != nil {
+		return model.WorkoutShare{}, err
+	}
+	return share, nil
+}
+
+func (p *PostgresDb) GetWorkoutShare(id string) (model.WorkoutShare, error) {
+	return p.getWorkoutShare(selectWorkoutShareById, id)
+}
+
+// GetWorkoutShares returns all shares of the workout sorted by creation time.
+func (p *PostgresDb) GetWorkoutShares(workoutId string) ([]model.WorkoutShare, error) {
+	return p.getWorkoutShares(selectWorkoutShares, workoutId)
+}
+
+func (p *PostgresDb) GetWorkoutShareByToken(tokenHash string) (model.WorkoutShare, error) {
+	return p.getWorkoutShare(selectWorkoutShareByToken, tokenHash)
+}
+
+// GetReceivedWorkoutShares returns shares addressed to the user, the most recent first.
+func (p *PostgresDb) GetReceivedWorkoutShares(userId string) ([]model.WorkoutShare, error) {
+	return p.getWorkoutShares(selectReceivedWorkoutShares, userId)
+}
+
+// GetReceivedWorkoutShare returns share only when it's addressed to the user.
+func (p *PostgresDb) GetReceivedWorkoutShare(id, userId string) (model.WorkoutShare, error) {
+	return p.getWorkoutShare(selectReceivedWorkoutShare, id, userId)
+}
+
+func (p *PostgresDb) DeleteWorkoutShare(id string) error {
+	tag, err := p.db.Exec(context.Background(), deleteWorkoutShare, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrWorkoutShareNotFound
+	}
+	return nil
+}
+
+func (p *PostgresDb) getWorkoutShare(query string, args ...any) (model.WorkoutShare, error) {
+	shares, err := p.getWorkoutShares(query, args...)
+	if err != nil {
+		return model.WorkoutShare{}, err
+	}
+	if len(shares) == 0 {
+		return model.WorkoutShare{}, ErrWorkoutShareNotFound
+	}
+	return shares[0], nil
+}
+
+func (p *PostgresDb) getWorkoutShares(query string, args ...any) ([]model.WorkoutShare, error) {
+	rows, err := p.db.Query(context.Background(), query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var shares []model.WorkoutShare
+	for rows.Next() {
+		var share model.WorkoutShare
+		err := rows.Scan(&share.ID, &share.WorkoutID, &share.OwnerID, &share.RecipientEmail, &share.RecipientID, &share.Permission, &share.CreatedAt, &share.WorkoutName)
+		if err != nil {
+			return nil, err
+		}
+		shares = append(shares, share)
+	}
+	return shares, rows.Err()
+}
@@ -0,0 +1,121 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"workout-tracker-server/model"
+)
+
+var (
+	ErrTagNotFound = fmt.Errorf("tag not found")
+	ErrTagExists   = fmt.Errorf("tag already exists")
+)
+
+const (
+	tagColumns = `t.id, t.owner, t.name, (SELECT count(*) FROM workout_tag wt JOIN workout w ON w.id = wt.workout_id
+		WHERE wt.tag_id = t.id AND w.deleted_at IS NULL)`
+
+	selectTags = "SELECT " + tagColumns + " FROM tag t WHERE t.owner = $1 ORDER BY t.name"
+	selectTag  = "SELECT " + tagColumns + " FROM tag t WHERE t.id = $1"
+	// renaming to name of other tag of the owner is rejected by the unique constraint, it's checked upfront instead
+	updateTagName = `UPDATE tag t SET name = $2 WHERE t.id = $1
+		AND NOT EXISTS (SELECT 1 FROM tag o WHERE o.owner = t.owner AND o.name = $2 AND o.id <> t.id)`
+	// only source tags of the target tag owner are merged
+	insertMergedWorkoutTags = `INSERT INTO workout_tag (workout_id, tag_id) SELECT wt.workout_id, t.id FROM workout_tag wt
+		JOIN tag s ON s.id = wt.tag_id JOIN tag t ON t.owner = s.owner WHERE s.id = ANY($1) AND t.id = $2
+		ON CONFLICT DO NOTHING`
+	deleteMergedTags = `DELETE FROM tag s USING tag t WHERE s.id = ANY($1) AND s.id <> t.id AND t.id = $2 AND s.owner = t.owner`
+	deleteTag        = "DELETE FROM tag WHERE id = $1"
+)
+
+type TagDb interface {
+	GetTags(userId string) ([]model.Tag, error)
+	GetTag(id string) (model.Tag, error)
+	RenameTag(id, name string) (model.Tag, error)
+	MergeTags(sourceIds []string, targetId string) (model.Tag, error)
+	DeleteTag(id string) error
+}
+
+// GetTags returns user's tags with workout counts, sorted by name.
+func (p *PostgresDb) GetTags(userId string) ([]model.Tag, error) {
+	rows, err := p.db.Query(context.Background(), selectTags, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var tags []model.Tag
+	for rows.Next() {
+		tag, err := scanTag(rows)
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+func (p *PostgresDb) GetTag(id string) (model.Tag, error) {
+	return getTag(p.db, id)
+}
+
+// RenameTag renames tag of all its workouts, returns ErrTagExists when the owner has other tag with the name.
+func (p *PostgresDb) RenameTag(id, name string) (model.Tag, error) {
+	result, err := p.db.Exec(context.Background(), updateTagName, id, name)
+	if err != nil {
+		return model.Tag{}, err
+	}
+	if result.RowsAffected() == 0 {
+		if _, err := p.GetTag(id); err != nil {
+			return model.Tag{}, err
+		}
+		return model.Tag{}, ErrTagExists
+	}
+	return p.GetTag(id)
+}
+
+// MergeTags replaces source tags with target tag in all workouts and deletes source tags in single transaction.
+// Sources which are not tags of the target tag owner are ignored.
+func (p *PostgresDb) MergeTags(sourceIds []string, targetId string) (model.Tag, error) {
+	ctx := context.Background()
+	tx, err := p.db.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.ReadCommitted, AccessMode: pgx.ReadWrite})
+	if err != nil {
+		return model.Tag{}, err
+	}
+	defer tx.Rollback(ctx)
+	if _, err := tx.Exec(ctx, insertMergedWorkoutTags, sourceIds, targetId); err != nil {
+		return model.Tag{}, err
+	}
+	if _, err := tx.Exec(ctx, deleteMergedTags, sourceIds, targetId); err != nil {
+		return model.Tag{}, err
+	}
+	merged, err := getTag(tx, targetId)
+	if err != nil {
+		return model.Tag{}, err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return model.Tag{}, err
+	}
+	return merged, nil
+}
+
+// DeleteTag deletes tag and removes it from all workouts.
+func (p *PostgresDb) DeleteTag(id string) error {
+	_, err := p.db.Exec(context.Background(), deleteTag, id)
+	return err
+}
+
+func getTag(q querier, id string) (model.Tag, error) {
+	tag, err := scanTag(q.QueryRow(context.Background(), selectTag, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return model.Tag{}, ErrTagNotFound
+	}
+	return tag, err
+}
+
+func scanTag(row pgx.Row) (model.Tag, error) {
+	var tag model.Tag
+	err := row.Scan(&tag.ID, &tag.OwnerID, &tag.Name, &tag.WorkoutCount)
+	return tag, err
+}
@@ -0,0 +1,28 @@
+package db
+
+import (
+	"context"
+	"workout-tracker-server/model"
+)
+
+const (
+	selectWorkoutTemplatesQuery = `SELECT ` + workoutColumns + ` FROM workout
+		WHERE template AND owner = ANY($1) ORDER BY owner = $2 DESC, name`
+	// templates are not moved to trash, workouts instantiated from deleted template lose lineage
+	deleteWorkoutTemplateQuery = `DELETE FROM workout WHERE id = $1 AND template`
+)
+
+type WorkoutTemplateDb interface {
+	GetWorkoutTemplates(userId string) ([]model.Workout, error)
+	DeleteWorkoutTemplate(id string) error
+}
+
+// GetWorkoutTemplates returns system templates followed by user's own templates, sorted by name, without exercises.
+func (p *PostgresDb) GetWorkoutTemplates(userId string) ([]model.Workout, error) {
+	return p.getWorkouts(selectWorkoutTemplatesQuery, []string{model.SystemTemplateOwnerID, userId}, model.SystemTemplateOwnerID)
+}
+
+func (p *PostgresDb) DeleteWorkoutTemplate(id string) error {
+	_, err := p.db.Exec(context.Background(), deleteWorkoutTemplateQuery, id)
+	return err
+}
@@ -0,0 +1,278 @@
+package db
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+	"slices"
+	"time"
+	"workout-tracker-server/model"
+)
+
+var (
+	ErrWorkoutVersionNotFound        = fmt.Errorf("workout version not found")
+	ErrWorkoutVersionMismatch        = fmt.Errorf("workout version mismatch")
+	ErrWorkoutVersionExerciseDeleted = fmt.Errorf("workout version references deleted exercise")
+)
+
+const (
+	insertWorkoutVersion = "INSERT INTO workout_version (workout_id, version, snapshot) VALUES ($1, $2, $3)"
+	// expected version 0 skips the check
+	updateWorkoutVersion       = "UPDATE workout SET version = version + 1 WHERE id = $1 AND ($2 = 0 OR version = $2) RETURNING version"
+	selectWorkoutVersionNumber = "SELECT version FROM workout WHERE id = $1"
+	selectWorkoutVersions      = "SELECT version, created_at FROM workout_version WHERE workout_id = $1 ORDER BY version DESC"
+	selectWorkoutVersion       = "SELECT version, created_at, snapshot FROM workout_version WHERE workout_id = $1 AND version = $2"
+)
+
+// GetWorkoutVersions returns versions of the workout without snapshots, newest first.
+func (p *PostgresDb) GetWorkoutVersions(workoutId string) ([]model.WorkoutVersion, error) {
+	rows, err := p.db.Query(context.Background(), selectWorkoutVersions, workoutId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var versions []model.WorkoutVersion
+	for rows.Next() {
+		var version model.WorkoutVersion
+		if err := rows.Scan(&version.Version, &version.CreatedAt); err != nil {
+			return nil, err
+		}
+		versions = append(versions, version)
+	}
+	return versions, rows.Err()
+}
+
+func (p *PostgresDb) GetWorkoutVersion(workoutId string, version int32) (model.WorkoutVersion, error) {
+	return getWorkoutVersion(p.db, workoutId, version)
+}
+
+// RestoreWorkoutVersion updates workout to the content of given version, which creates new version. Snapshot is
+// applied as full update, exercises and groups deleted since the version are added back with new ids.
+func (p *PostgresDb) RestoreWorkoutVersion(workoutId string, version int32) (model.WorkoutVersion, error) {
+	ctx := context.Background()
+	tx, err := p.db.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.ReadCommitted, AccessMode: pgx.ReadWrite})
+	if err != nil {
+		return model.WorkoutVersion{}, err
+	}
+	defer tx.Rollback(ctx)
+	restored, err := getWorkoutVersion(tx, workoutId, version)
+	if err != nil {
+		return model.WorkoutVersion{}, err
+	}
+	workout := *restored.Workout
+	//custom exercises unused by workouts and history may be deleted since the version was taken
+	if err := validateExercisesVisible(tx, workout.Exercises, workout.OwnerID, &workoutId); errors.Is(err, ErrExerciseNotFound) {
+		return model.WorkoutVersion{}, ErrWorkoutVersionExerciseDeleted
+	} else if err != nil {
+		return model.WorkoutVersion{}, err
+	}
+	existingExercises, err := getExistingExercises(tx, workoutId)
+	if err != nil {
+		return model.WorkoutVersion{}, err
+	}
+	for i, ex := range workout.Exercises {
+		if !slices.Contains(existingExercises, ex.WorkoutExerciseID) {
+			workout.Exercises[i].WorkoutExerciseID = ""
+		}
+	}
+	existingGroups, err := getExerciseGroups(tx, workoutId)
+	if err != nil {
+		return model.WorkoutVersion{}, err
+	}
+	for i, group := range workout.Groups {
+		if !slices.ContainsFunc(existingGroups, func(g model.ExerciseGroup) bool { return g.GroupID == group.GroupID }) {
+			workout.Groups[i].GroupID = ""
+		}
+	}
+	newVersion, err := incrementWorkoutVersion(tx, workoutId, 0)
+	if err != nil {
+		return model.WorkoutVersion{}, err
+	}
+	mask := &fieldmaskpb.FieldMask{Paths: []string{"name", "comment", "groups", "exercises"}}
+	if err := updateWorkout(tx, workout, mask); err != nil {
+		return model.WorkoutVersion{}, err
+	}
+	if err := saveWorkoutVersion(tx, workoutId); err != nil {
+		return model.WorkoutVersion{}, err
+	}
+	current, err := getWorkoutVersion(tx, workoutId, newVersion)
+	if err != nil {
+		return model.WorkoutVersion{}, err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return model.WorkoutVersion{}, err
+	}
+	return current, nil
+}
+
+// incrementWorkoutVersion bumps version of workout before its update, when expected version is set it must match
+// the current one. Bump locks the workout row, so concurrent updates are serialized and get consecutive versions.
+func incrementWorkoutVersion(tx pgx.Tx, workoutId string, expectedVersion int32) (int32, error) {
+	var version int32
+	err := tx.QueryRow(context.Background(), updateWorkoutVersion, workoutId, expectedVersion).Scan(&version)
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = tx.QueryRow(context.Background(), selectWorkoutVersionNumber, workoutId).Scan(&version)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, ErrWorkoutNotFound
+		}
+		if err != nil {
+			return 0, err
+		}
+		return 0, ErrWorkoutVersionMismatch
+	}
+	return version, err
+}
+
+// saveWorkoutVersion stores snapshot of workout at its current version.
+func saveWorkoutVersion(tx pgx.Tx, workoutId string) error {
+	workout, err := getWorkout(tx, workoutId)
+	if err != nil {
+		return err
+	}
+	workout.Exercises = slices.SortedFunc(slices.Values(workout.Exercises), func(e1, e2 model.WorkoutExercise) int {
+		return cmp.Compare(e1.Order, e2.Order)
+	})
+	snapshot, err := json.Marshal(newWorkoutSnapshot(workout))
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(context.Background(), insertWorkoutVersion, workoutId, workout.Version, snapshot)
+	return err
+}
+
+func getWorkoutVersion(q querier, workoutId string, version int32) (model.WorkoutVersion, error) {
+	var workoutVersion model.WorkoutVersion
+	var snapshot []byte
+	err := q.QueryRow(context.Background(), selectWorkoutVersion, workoutId, version).Scan(&workoutVersion.Version, &workoutVersion.CreatedAt, &snapshot)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return model.WorkoutVersion{}, ErrWorkoutVersionNotFound
+	}
+	if err != nil {
+		return model.WorkoutVersion{}, err
+	}
+	var ws workoutSnapshot
+	if err := json.Unmarshal(snapshot, &ws); err != nil {
+		return model.WorkoutVersion{}, err
+	}
+	workout := ws.toWorkout()
+	workoutVersion.Workout = &workout
+	return workoutVersion, nil
+}
+
+// workoutSnapshot is stored format of workout version, it's decoupled from the model so renaming model fields
+// doesn't break stored versions.
+type workoutSnapshot struct {
+	ID               string                  `json:"id"`
+	OwnerID          string                  `json:"owner_id"`
+	Name             string                  `json:"name"`
+	Comment          *string                 `json:"comment,omitempty"`
+	Exercises        []exerciseSnapshot      `json:"exercises,omitempty"`
+	Groups           []exerciseGroupSnapshot `json:"groups,omitempty"`
+	Template         bool                    `json:"template"`
+	SourceTemplateID *string                 `json:"source_template_id,omitempty"`
+	Version          int32                   `json:"version"`
+	FolderID         *string                 `json:"folder_id,omitempty"`
+	Tags             []string                `json:"tags,omitempty"`
+}
+
+type exerciseSnapshot struct {
+	WorkoutExerciseID string  `json:"workout_exercise_id"`
+	ExerciseID        string  `json:"exercise_id"`
+	Order             int32   `json:"order"`
+	Repetitions       int32   `json:"repetitions"`
+	Sets              int32   `json:"sets"`
+	Weight            *int32  `json:"weight,omitempty"`
+	Comment           *string `json:"comment,omitempty"`
+	GroupLabel        *string `json:"group_label,omitempty"`
+	RestSeconds       *int32  `json:"rest_seconds,omitempty"`
+	SetRestSeconds    []int32 `json:"set_rest_seconds,omitempty"`
+	Tempo             *string `json:"tempo,omitempty"`
+}
+
+type exerciseGroupSnapshot struct {
+	GroupID                  string `json:"group_id"`
+	Label                    string `json:"label"`
+	Type                     string `json:"type"`
+	Rounds                   int32  `json:"rounds"`
+	RestBetweenRoundsSeconds int64  `json:"rest_between_rounds_seconds"`
+}
+
+func newWorkoutSnapshot(workout model.Workout) workoutSnapshot {
+	ws := workoutSnapshot{
+		ID:               workout.ID,
+		OwnerID:          workout.OwnerID,
+		Name:             workout.Name,
+		Comment:          workout.Comment,
+		Template:         workout.Template,
+		SourceTemplateID: workout.SourceTemplateID,
+		Version:          workout.Version,
+		FolderID:         workout.FolderID,
+		Tags:             workout.Tags,
+	}
+	for _, ex := range workout.Exercises {
+		ws.Exercises = append(ws.Exercises, exerciseSnapshot{
+			WorkoutExerciseID: ex.WorkoutExerciseID,
+			ExerciseID:        ex.ExerciseID,
+			Order:             ex.Order,
+			Repetitions:       ex.Repetitions,
+			Sets:              ex.Sets,
+			Weight:            ex.Weight,
+			Comment:           ex.Comment,
+			GroupLabel:        ex.GroupLabel,
+			RestSeconds:       ex.Rest.Seconds,
+			SetRestSeconds:    ex.Rest.SetSeconds,
+			Tempo:             ex.Rest.Tempo,
+		})
+	}
+	for _, group := range workout.Groups {
+		ws.Groups = append(ws.Groups, exerciseGroupSnapshot{
+			GroupID:                  group.GroupID,
+			Label:                    group.Label,
+			Type:                     string(group.Type),
+			Rounds:                   group.Rounds,
+			RestBetweenRoundsSeconds: int64(group.RestBetweenRounds / time.Second),
+		})
+	}
+	return ws
+}
+
+func (ws workoutSnapshot) toWorkout() model.Workout {
+	workout := model.Workout{
+		ID:               ws.ID,
+		OwnerID:          ws.OwnerID,
+		Name:             ws.Name,
+		Comment:          ws.Comment,
+		Template:         ws.Template,
+		SourceTemplateID: ws.SourceTemplateID,
+		Version:          ws.Version,
+		FolderID:         ws.FolderID,
+		Tags:             ws.Tags,
+	}
+	for _, ex := range ws.Exercises {
+		workout.Exercises = append(workout.Exercises, model.WorkoutExercise{
+			WorkoutExerciseID: ex.WorkoutExerciseID,
+			ExerciseID:        ex.ExerciseID,
+			Order:             ex.Order,
+			Repetitions:       ex.Repetitions,
+			Sets:              ex.Sets,
+			Weight:            ex.Weight,
+			Comment:           ex.Comment,
+			GroupLabel:        ex.GroupLabel,
+			Rest:              model.Rest{Seconds: ex.RestSeconds, SetSeconds: ex.SetRestSeconds, Tempo: ex.Tempo},
+		})
+	}
+	for _, group := range ws.Groups {
+		workout.Groups = append(workout.Groups, model.ExerciseGroup{
+			GroupID:           group.GroupID,
+			Label:             group.Label,
+			Type:              model.ExerciseGroupType(group.Type),
+			Rounds:            group.Rounds,
+			RestBetweenRounds: time.Duration(group.RestBetweenRoundsSeconds) * time.Second,
+		})
+	}
+	return workout
+}
@@ -10,63 +10,188 @@ import (
 	"slices"
 	"strconv"
 	"strings"
+	"time"
 	"workout-tracker-server/model"
 )
 
+const (
+	// workoutColumns are selected from unaliased workout table, tags are sorted by name
+	workoutColumns = `id, owner, name, comment, template, source_template_id, version, deleted_at, folder_id,
+		ARRAY(SELECT t.name FROM workout_tag wt JOIN tag t ON t.id = wt.tag_id WHERE wt.workout_id = workout.id ORDER BY t.name)`
+)
+
 var (
 	ErrWorkoutNotFound         = fmt.Errorf("workout not found")
 	ErrWorkoutExerciseNotFound = fmt.Errorf("workout exercise not found")
+	ErrExerciseGroupNotFound   = fmt.Errorf("exercise group not found")
+	ErrWorkoutCopyExercise     = fmt.Errorf("workout references custom exercise not visible to new owner")
 
-	insertWorkoutQuery         = `INSERT INTO workout (id, owner, name, comment) VALUES ($1, $2, $3, $4)`
-	insertWorkoutExerciseQuery = `INSERT INTO workout_exercise (workout_exercise_id, workout_id, exercise_id, "order", repetitions, sets, weight, comment) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+	insertWorkoutQuery = `INSERT INTO workout (id, owner, name, comment, template, source_template_id, folder_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`
+	insertWorkoutExerciseQuery = `INSERT INTO workout_exercise (workout_exercise_id, workout_id, exercise_id, "order", repetitions, sets, weight, comment, group_id, rest_seconds, set_rest_seconds, tempo)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`
+	insertExerciseGroupQuery = `INSERT INTO workout_exercise_group (id, workout_id, label, type, rounds, rest_between_rounds_seconds) VALUES ($1, $2, $3, $4, $5, $6)`
 
-	selectWorkoutExercisesByWorkoutId    = `SELECT workout_exercise_id, exercise_id, "order", repetitions, sets, weight, comment FROM workout_exercise WHERE workout_id = $1`
+	selectWorkoutExercisesByWorkoutId = `SELECT we.workout_exercise_id, we.exercise_id, we."order", we.repetitions, we.sets, we.weight, we.comment, g.label,
+		we.rest_seconds, we.set_rest_seconds, we.tempo FROM workout_exercise we LEFT JOIN workout_exercise_group g ON g.id = we.group_id WHERE we.workout_id = $1`
+	selectExerciseGroupsByWorkoutId      = `SELECT id, label, type, rounds, rest_between_rounds_seconds FROM workout_exercise_group WHERE workout_id = $1 ORDER BY label`
 	selectWorkoutExercisesIdsByWorkoutId = `SELECT workout_exercise_id FROM workout_exercise WHERE workout_id = $1`
-	selectWorkoutByIdQuery               = `SELECT id, owner, name, comment FROM workout WHERE id = $1`
-	selectWorkoutOwnerQuery              = `SELECT owner FROM workout WHERE id = $1`
-	selectWorkoutsByUserIdQuery          = `SELECT id, owner, name, comment FROM workout WHERE owner = $1`
+	selectWorkoutByIdQuery               = `SELECT ` + workoutColumns + ` FROM workout WHERE id = $1 AND deleted_at IS NULL`
+	selectWorkoutOwnerQuery              = `SELECT owner FROM workout WHERE id = $1 AND NOT template AND deleted_at IS NULL`
+	selectWorkoutOwnerForUpdateQuery     = `SELECT owner FROM workout WHERE id = $1`
+	selectWorkoutsByUserIdQuery          = `SELECT ` + workoutColumns + ` FROM workout WHERE owner = $1 AND NOT template AND deleted_at IS NULL`
+	// purged workouts are kept only as reference of completed schedules
+	selectDeletedWorkoutsByUserIdQuery = `SELECT ` + workoutColumns + ` FROM workout
+		WHERE owner = $1 AND deleted_at IS NOT NULL AND purged_at IS NULL`
+	selectWorkoutNamesByIds = `SELECT w.id, w.name, COALESCE(p.default_rest_seconds, $2), p.warm_up_rest_seconds
+		FROM workout w LEFT JOIN user_profile p ON p.user_id = w.owner WHERE w.id = ANY($1)`
+	selectExerciseSummariesByWorkoutIds = `SELECT we.workout_id, we.exercise_id, e.name, e.muscle_group, we.sets, we.repetitions, we.weight,
+		we.rest_seconds, we.set_rest_seconds, we.tempo FROM workout_exercise we JOIN exercise e ON e.id = we.exercise_id
+		WHERE we.workout_id = ANY($1) ORDER BY we."order"`
 
-	updateWorkoutExerciseQuery = `UPDATE workout_exercise SET exercise_id = $1, "order" = $2, repetitions = $3, sets = $4, weight = $5, comment = $6 WHERE workout_exercise_id = $7`
-	updateWorkoutQuery         = `UPDATE workout SET`
+	updateWorkoutExerciseQuery = `UPDATE workout_exercise SET exercise_id = $1, "order" = $2, repetitions = $3, sets = $4, weight = $5, comment = $6, group_id = $7,
+		rest_seconds = $8, set_rest_seconds = $9, tempo = $10 WHERE workout_exercise_id = $11`
+	updateExerciseGroupQuery = `UPDATE workout_exercise_group SET label = $1, type = $2, rounds = $3, rest_between_rounds_seconds = $4 WHERE id = $5`
+	updateWorkoutQuery       = `UPDATE workout SET`
 
-	deleteWorkoutQuery    = `DELETE FROM workout WHERE id = $1`
-	deleteWorkoutExercise = `DELETE FROM workout_exercise WHERE workout_exercise_id = $1`
+	softDeleteWorkoutQuery = `UPDATE workout SET deleted_at = now() WHERE id = $1 AND NOT template AND deleted_at IS NULL`
+	restoreWorkoutQuery    = `UPDATE workout SET deleted_at = NULL WHERE id = $1 AND owner = $2 AND deleted_at IS NOT NULL AND purged_at IS NULL`
+	// pending schedules of deleted workout are cancelled, past ones are kept as history
+	cancelPendingWorkoutSchedules = `UPDATE workout_schedule SET cancelled_at = now(), cancel_reason = $2
+		WHERE workout = $1 AND NOT completed AND cancelled_at IS NULL AND missed_at IS NULL`
+	deleteWorkoutScheduleSeriesByWorkout = `DELETE FROM workout_schedule_series WHERE workout = $1`
+	deleteWorkoutExercise                = `DELETE FROM workout_exercise WHERE workout_exercise_id = $1`
+	deleteWorkoutTags                    = `DELETE FROM workout_tag WHERE workout_id = $1`
+
+	// missing tags are created for the workout owner
+	insertTagsOfWorkout = `INSERT INTO tag (id, owner, name) SELECT gen_random_uuid(), w.owner, t.name FROM workout w, unnest($2::text[]) t(name)
+		WHERE w.id = $1 ON CONFLICT (owner, name) DO NOTHING`
+	insertWorkoutTags = `INSERT INTO workout_tag (workout_id, tag_id) SELECT w.id, t.id FROM workout w JOIN tag t ON t.owner = w.owner
+		WHERE w.id = $1 AND t.name = ANY($2)`
+	// exercises already used by the workout stay visible, e.g. custom exercises of other user in copy of shared workout
+	selectVisibleExerciseIds = `SELECT e.id FROM exercise e WHERE e.id = ANY($1) AND (e.owner IS NULL OR e.owner = $2
+		OR EXISTS (SELECT 1 FROM workout_exercise we WHERE we.workout_id = $3 AND we.exercise_id = e.id))`
+	selectWorkoutFolderOwned = `SELECT EXISTS (SELECT 1 FROM workout w JOIN folder f ON f.id = w.folder_id AND f.owner = w.owner WHERE w.id = $1)`
+	deleteExerciseGroup      = `DELETE FROM workout_exercise_group WHERE id = $1`
 )
 
 type WorkoutDb interface {
 	SaveWorkout(workout model.Workout) (string, error)
-	GetWorkouts(userId string) ([]model.Workout, error)
+	GetWorkouts(userId string, filter model.WorkoutFilter) ([]model.Workout, error)
 	GetWorkout(id string) (model.Workout, error)
 	IsWorkoutOwner(workoutId, userId string) (bool, error)
+	GetWorkoutOwner(workoutId string) (string, error)
 	UpdateWorkout(workout model.Workout, mask *fieldmaskpb.FieldMask) error
 	DeleteWorkout(id string) error
+	GetDeletedWorkouts(userId string, filter model.WorkoutFilter) ([]model.Workout, error)
+	RestoreWorkout(id, userId string) error
+	GetWorkoutSummaries(ids []string) (map[string]model.WorkoutSummary, error)
+	CopyWorkout(id string, workoutCopy model.WorkoutCopy) (string, error)
+	GetWorkoutVersions(workoutId string) ([]model.WorkoutVersion, error)
+	GetWorkoutVersion(workoutId string, version int32) (model.WorkoutVersion, error)
+	RestoreWorkoutVersion(workoutId string, version int32) (model.WorkoutVersion, error)
 }
 
 func (p *PostgresDb) SaveWorkout(workout model.Workout) (string, error) {
-	workoutId := uuid.New().String()
 	ctx := context.Background()
 	tx, err := p.db.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.ReadCommitted, AccessMode: pgx.ReadWrite})
 	if err != nil {
 		return "", err
 	}
 	defer tx.Rollback(ctx)
-	if _, err = tx.Exec(ctx, insertWorkoutQuery, workoutId, workout.OwnerID, workout.Name, workout.Comment); err != nil {
+	if err := validateExercisesVisible(tx, workout.Exercises, workout.OwnerID, nil); err != nil {
 		return "", err
 	}
-	for _, ex := range workout.Exercises {
-		workoutExerciseId := uuid.New().String()
-		if _, err = tx.Exec(ctx, insertWorkoutExerciseQuery, workoutExerciseId, workoutId, ex.ExerciseID, ex.Order, ex.Repetitions, ex.Sets, ex.Weight, ex.Comment); err != nil {
+	workoutId, err := saveWorkout(tx, workout)
+	if err != nil {
+		return "", err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return "", err
+	}
+	return workoutId, nil
+}
+
+// CopyWorkout deep copies workout with its groups and exercises in single transaction, copy gets new ids.
+// Source template of the original is kept unless the copy sets its own, tags are copied to tags of the new owner.
+// Copy for other user is rejected when the workout references custom exercises the new owner can't see.
+func (p *PostgresDb) CopyWorkout(id string, workoutCopy model.WorkoutCopy) (string, error) {
+	ctx := context.Background()
+	tx, err := p.db.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadWrite})
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback(ctx)
+	workout, err := getWorkout(tx, id)
+	if err != nil {
+		return "", err
+	}
+	if workout.OwnerID != workoutCopy.OwnerID {
+		err := validateExercisesVisible(tx, workout.Exercises, workoutCopy.OwnerID, nil)
+		if errors.Is(err, ErrExerciseNotFound) {
+			return "", ErrWorkoutCopyExercise
+		}
+		if err != nil {
 			return "", err
 		}
 	}
+	//folders are not shared between users and templates are not filed
+	if workout.OwnerID != workoutCopy.OwnerID || workoutCopy.Template {
+		workout.FolderID = nil
+	}
+	workout.OwnerID = workoutCopy.OwnerID
+	workout.Name = workoutCopy.Name
+	workout.Template = workoutCopy.Template
+	if workoutCopy.SourceTemplateID != nil {
+		workout.SourceTemplateID = workoutCopy.SourceTemplateID
+	}
+	copyId, err := saveWorkout(tx, workout)
+	if err != nil {
+		return "", err
+	}
 	if err = tx.Commit(ctx); err != nil {
 		return "", err
 	}
+	return copyId, nil
+}
+
+func saveWorkout(tx pgx.Tx, workout model.Workout) (string, error) {
+	workoutId := uuid.New().String()
+	_, err := tx.Exec(context.Background(), insertWorkoutQuery,
+		workoutId, workout.OwnerID, workout.Name, workout.Comment, workout.Template, workout.SourceTemplateID, workout.FolderID,
+	)
+	if err != nil {
+		return "", err
+	}
+	if workout.FolderID != nil {
+		if err := validateWorkoutFolder(tx, workoutId); err != nil {
+			return "", err
+		}
+	}
+	if err := setWorkoutTags(tx, workoutId, workout.Tags); err != nil {
+		return "", err
+	}
+	groupIds := make(map[string]string, len(workout.Groups))
+	for _, group := range workout.Groups {
+		if groupIds[group.Label], err = saveExerciseGroup(tx, workoutId, group); err != nil {
+			return "", err
+		}
+	}
+	for _, ex := range workout.Exercises {
+		if err := saveWorkoutExercise(tx, workoutId, ex, groupIds); err != nil {
+			return "", err
+		}
+	}
+	if err := saveWorkoutVersion(tx, workoutId); err != nil {
+		return "", err
+	}
 	return workoutId, nil
 }
 
 // UpdateWorkout updates workout with given mask. If mask contains "exercises" path, it updates exercises as well.
 // Update of exercises is done in PUT fashion: any existing exercise is updated, any new exercise is added, any missing exercise is deleted.
+// Groups path is applied the same way before exercises, so exercises can reference groups added within the same update.
+// Every update creates new workout version, version of the workout is checked against the current one when set.
 func (p *PostgresDb) UpdateWorkout(workout model.Workout, mask *fieldmaskpb.FieldMask) error {
 	//wrapping with tx to make whole operation atomic
 	tx, err := p.db.BeginTx(context.Background(), pgx.TxOptions{
@@ -78,6 +203,28 @@ func (p *PostgresDb) UpdateWorkout(workout model.Workout, mask *fieldmaskpb.Fiel
 	}
 	defer tx.Rollback(context.Background())
 
+	if _, err := incrementWorkoutVersion(tx, workout.ID, workout.Version); err != nil {
+		return err
+	}
+	if slices.Contains(mask.GetPaths(), "exercises") {
+		var ownerId string
+		if err := tx.QueryRow(context.Background(), selectWorkoutOwnerForUpdateQuery, workout.ID).Scan(&ownerId); err != nil {
+			return err
+		}
+		if err := validateExercisesVisible(tx, workout.Exercises, ownerId, &workout.ID); err != nil {
+			return err
+		}
+	}
+	if err := updateWorkout(tx, workout, mask); err != nil {
+		return err
+	}
+	if err := saveWorkoutVersion(tx, workout.ID); err != nil {
+		return err
+	}
+	return tx.Commit(context.Background())
+}
+
+func updateWorkout(tx pgx.Tx, workout model.Workout, mask *fieldmaskpb.FieldMask) error {
 	query, args := createUpdateWorkoutQuery(workout, mask)
 	if query != "" {
 		tag, err := tx.Exec(context.Background(), query, args...)
@@ -89,20 +236,45 @@ func (p *PostgresDb) UpdateWorkout(workout model.Workout, mask *fieldmaskpb.Fiel
 		}
 	}
 
+	if slices.Contains(mask.GetPaths(), "folder_id") && workout.FolderID != nil {
+		if err := validateWorkoutFolder(tx, workout.ID); err != nil {
+			return err
+		}
+	}
+
+	if slices.Contains(mask.GetPaths(), "tags") {
+		if _, err := tx.Exec(context.Background(), deleteWorkoutTags, workout.ID); err != nil {
+			return err
+		}
+		if err := setWorkoutTags(tx, workout.ID, workout.Tags); err != nil {
+			return err
+		}
+	}
+
+	if slices.Contains(mask.GetPaths(), "groups") {
+		if err := updateExerciseGroups(tx, workout); err != nil {
+			return err
+		}
+	}
+
 	if slices.Contains(mask.GetPaths(), "exercises") {
 		existingExercises, err := getExistingExercises(tx, workout.ID)
 		if err != nil {
 			return err
 		}
+		groupIds, err := getExerciseGroupIds(tx, workout.ID)
+		if err != nil {
+			return err
+		}
 		for _, ex := range workout.Exercises {
 			if ex.WorkoutExerciseID == "" {
-				if err := saveWorkoutExercise(tx, workout.ID, ex); err != nil {
+				if err := saveWorkoutExercise(tx, workout.ID, ex, groupIds); err != nil {
 					return err
 				}
 				continue
 			}
 			if slices.Contains(existingExercises, ex.WorkoutExerciseID) {
-				if err := updateWorkoutExercise(tx, ex); err != nil {
+				if err := updateWorkoutExercise(tx, ex, groupIds); err != nil {
 					return err
 				}
 				existingExercises = slices.DeleteFunc(existingExercises, func(id string) bool { return id == ex.WorkoutExerciseID })
@@ -116,9 +288,6 @@ func (p *PostgresDb) UpdateWorkout(workout model.Workout, mask *fieldmaskpb.Fiel
 			}
 		}
 	}
-	if err = tx.Commit(context.Background()); err != nil {
-		return err
-	}
 	return nil
 }
 
@@ -140,6 +309,10 @@ func createUpdateWorkoutQuery(workout model.Workout, mask *fieldmaskpb.FieldMask
 			modifications = append(modifications, fmt.Sprintf("comment = $%d", index))
 			args = append(args, workout.Comment)
 			index++
+		case "folder_id":
+			modifications = append(modifications, fmt.Sprintf("folder_id = $%d", index))
+			args = append(args, workout.FolderID)
+			index++
 		}
 	}
 	if len(modifications) == 0 {
@@ -150,6 +323,64 @@ func createUpdateWorkoutQuery(workout model.Workout, mask *fieldmaskpb.FieldMask
 	return query, args
 }
 
+// setWorkoutTags adds tags to the workout, tags not used by the owner yet are created.
+func setWorkoutTags(tx pgx.Tx, workoutId string, tags []string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+	if _, err := tx.Exec(context.Background(), insertTagsOfWorkout, workoutId, tags); err != nil {
+		return err
+	}
+	_, err := tx.Exec(context.Background(), insertWorkoutTags, workoutId, tags)
+	return err
+}
+
+// validateExercisesVisible checks that exercises exist and are global or custom exercises of the owner, exercises
+// already used by the updated workout are accepted as well.
+func validateExercisesVisible(tx pgx.Tx, exercises []model.WorkoutExercise, ownerId string, workoutId *string) error {
+	var exerciseIds []string
+	for _, ex := range exercises {
+		exerciseIds = append(exerciseIds, ex.ExerciseID)
+	}
+	if len(exerciseIds) == 0 {
+		return nil
+	}
+	rows, err := tx.Query(context.Background(), selectVisibleExerciseIds, exerciseIds, ownerId, workoutId)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	var visibleIds []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return err
+		}
+		visibleIds = append(visibleIds, id)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, id := range exerciseIds {
+		if !slices.Contains(visibleIds, id) {
+			return ErrExerciseNotFound
+		}
+	}
+	return nil
+}
+
+// validateWorkoutFolder checks that folder of the workout belongs to the workout owner.
+func validateWorkoutFolder(tx pgx.Tx, workoutId string) error {
+	var owned bool
+	if err := tx.QueryRow(context.Background(), selectWorkoutFolderOwned, workoutId).Scan(&owned); err != nil {
+		return err
+	}
+	if !owned {
+		return ErrFolderNotFound
+	}
+	return nil
+}
+
 func getExistingExercises(tx pgx.Tx, workoutId string) ([]string, error) {
 	rows, err := tx.Query(context.Background(), selectWorkoutExercisesIdsByWorkoutId, workoutId)
 	if err != nil {
@@ -168,61 +399,210 @@ func getExistingExercises(tx pgx.Tx, workoutId string) ([]string, error) {
 	return existingExercises, nil
 }
 
-func updateWorkoutExercise(tx pgx.Tx, ex model.WorkoutExercise) error {
-	if _, err := tx.Exec(context.Background(), updateWorkoutExerciseQuery, ex.ExerciseID, ex.Order, ex.Repetitions, ex.Sets, ex.Weight, ex.Comment, ex.WorkoutExerciseID); err != nil {
+func updateWorkoutExercise(tx pgx.Tx, ex model.WorkoutExercise, groupIds map[string]string) error {
+	groupId, err := exerciseGroupId(ex, groupIds)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(context.Background(), updateWorkoutExerciseQuery, ex.ExerciseID, ex.Order, ex.Repetitions, ex.Sets, ex.Weight, ex.Comment, groupId,
+		ex.Rest.Seconds, ex.Rest.SetSeconds, ex.Rest.Tempo, ex.WorkoutExerciseID,
+	); err != nil {
 		return err
 	}
 	return nil
 }
 
-func saveWorkoutExercise(tx pgx.Tx, workoutId string, ex model.WorkoutExercise) error {
+func saveWorkoutExercise(tx pgx.Tx, workoutId string, ex model.WorkoutExercise, groupIds map[string]string) error {
+	groupId, err := exerciseGroupId(ex, groupIds)
+	if err != nil {
+		return err
+	}
 	id := uuid.New().String()
-	if _, err := tx.Exec(context.Background(), insertWorkoutExerciseQuery, id, workoutId, ex.ExerciseID, ex.Order, ex.Repetitions, ex.Sets, ex.Weight, ex.Comment); err != nil {
+	if _, err := tx.Exec(context.Background(), insertWorkoutExerciseQuery, id, workoutId, ex.ExerciseID, ex.Order, ex.Repetitions, ex.Sets, ex.Weight, ex.Comment, groupId,
+		ex.Rest.Seconds, ex.Rest.SetSeconds, ex.Rest.Tempo,
+	); err != nil {
+		return err
+	}
+	return nil
+}
+
+// exerciseGroupId resolves group label of the exercise to group id, groupIds are keyed by label.
+func exerciseGroupId(ex model.WorkoutExercise, groupIds map[string]string) (*string, error) {
+	if ex.GroupLabel == nil {
+		return nil, nil
+	}
+	id, ok := groupIds[*ex.GroupLabel]
+	if !ok {
+		return nil, ErrExerciseGroupNotFound
+	}
+	return &id, nil
+}
+
+// updateExerciseGroups updates groups in PUT fashion, same as exercises. Missing groups are deleted first,
+// so their labels can be reused, exercises of deleted groups are left without group.
+func updateExerciseGroups(tx pgx.Tx, workout model.Workout) error {
+	existingGroups, err := getExerciseGroups(tx, workout.ID)
+	if err != nil {
 		return err
 	}
+	existing := make(map[string]bool, len(existingGroups))
+	for _, group := range existingGroups {
+		existing[group.GroupID] = true
+	}
+	for _, group := range existingGroups {
+		if slices.ContainsFunc(workout.Groups, func(g model.ExerciseGroup) bool { return g.GroupID == group.GroupID }) {
+			continue
+		}
+		if _, err := tx.Exec(context.Background(), deleteExerciseGroup, group.GroupID); err != nil {
+			return err
+		}
+	}
+	for _, group := range workout.Groups {
+		if group.GroupID == "" {
+			if _, err := saveExerciseGroup(tx, workout.ID, group); err != nil {
+				return err
+			}
+			continue
+		}
+		if !existing[group.GroupID] {
+			return ErrExerciseGroupNotFound
+		}
+		_, err := tx.Exec(context.Background(), updateExerciseGroupQuery,
+			group.Label, group.Type, group.Rounds, int(group.RestBetweenRounds.Seconds()), group.GroupID,
+		)
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+func saveExerciseGroup(tx pgx.Tx, workoutId string, group model.ExerciseGroup) (string, error) {
+	id := uuid.New().String()
+	_, err := tx.Exec(context.Background(), insertExerciseGroupQuery,
+		id, workoutId, group.Label, group.Type, group.Rounds, int(group.RestBetweenRounds.Seconds()),
+	)
+	return id, err
+}
+
+// getExerciseGroupIds returns ids of workout groups keyed by label.
+func getExerciseGroupIds(tx pgx.Tx, workoutId string) (map[string]string, error) {
+	groups, err := getExerciseGroups(tx, workoutId)
+	if err != nil {
+		return nil, err
+	}
+	ids := make(map[string]string, len(groups))
+	for _, group := range groups {
+		ids[group.Label] = group.GroupID
+	}
+	return ids, nil
+}
+
+func getExerciseGroups(q querier, workoutId string) ([]model.ExerciseGroup, error) {
+	rows, err := q.Query(context.Background(), selectExerciseGroupsByWorkoutId, workoutId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var groups []model.ExerciseGroup
+	for rows.Next() {
+		var group model.ExerciseGroup
+		var restSeconds int
+		if err := rows.Scan(&group.GroupID, &group.Label, &group.Type, &group.Rounds, &restSeconds); err != nil {
+			return nil, err
+		}
+		group.RestBetweenRounds = time.Duration(restSeconds) * time.Second
+		groups = append(groups, group)
+	}
+	return groups, rows.Err()
+}
+
 func (p *PostgresDb) IsWorkoutOwner(workoutId, userId string) (bool, error) {
+	owner, err := p.GetWorkoutOwner(workoutId)
+	if err != nil {
+		return false, err
+	}
+	return owner == userId, nil
+}
+
+func (p *PostgresDb) GetWorkoutOwner(workoutId string) (string, error) {
 	row := p.db.QueryRow(context.Background(), selectWorkoutOwnerQuery, workoutId)
 	var owner string
 	if err := row.Scan(&owner); err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return false, ErrWorkoutNotFound
+			return "", ErrWorkoutNotFound
 		}
-		return false, err
+		return "", err
 	}
-	return owner == userId, nil
+	return owner, nil
 }
 
+// GetWorkout returns workout or template with its exercises and groups.
 func (p *PostgresDb) GetWorkout(id string) (model.Workout, error) {
-	row := p.db.QueryRow(context.Background(), selectWorkoutByIdQuery, id)
+	return getWorkout(p.db, id)
+}
+
+func getWorkout(q querier, id string) (model.Workout, error) {
+	row := q.QueryRow(context.Background(), selectWorkoutByIdQuery, id)
 	var workout model.Workout
-	err := row.Scan(&workout.ID, &workout.OwnerID, &workout.Name, &workout.Comment)
+	err := row.Scan(&workout.ID, &workout.OwnerID, &workout.Name, &workout.Comment, &workout.Template, &workout.SourceTemplateID, &workout.Version,
+		&workout.DeletedAt, &workout.FolderID, &workout.Tags,
+	)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return model.Workout{}, ErrWorkoutNotFound
 		}
 		return model.Workout{}, err
 	}
-	rows, err := p.db.Query(context.Background(), selectWorkoutExercisesByWorkoutId, id)
+	rows, err := q.Query(context.Background(), selectWorkoutExercisesByWorkoutId, id)
 	if err != nil {
 		return model.Workout{}, err
 	}
 	defer rows.Close()
 	for rows.Next() {
 		var ex model.WorkoutExercise
-		err := rows.Scan(&ex.WorkoutExerciseID, &ex.ExerciseID, &ex.Order, &ex.Repetitions, &ex.Sets, &ex.Weight, &ex.Comment)
+		err := rows.Scan(&ex.WorkoutExerciseID, &ex.ExerciseID, &ex.Order, &ex.Repetitions, &ex.Sets, &ex.Weight, &ex.Comment, &ex.GroupLabel,
+			&ex.Rest.Seconds, &ex.Rest.SetSeconds, &ex.Rest.Tempo,
+		)
 		if err != nil {
 			return model.Workout{}, err
 		}
 		workout.Exercises = append(workout.Exercises, ex)
 	}
+	if err := rows.Err(); err != nil {
+		return model.Workout{}, err
+	}
+	if workout.Groups, err = getExerciseGroups(q, id); err != nil {
+		return model.Workout{}, err
+	}
 	return workout, nil
 }
 
-func (p *PostgresDb) GetWorkouts(userId string) ([]model.Workout, error) {
-	rows, err := p.db.Query(context.Background(), selectWorkoutsByUserIdQuery, userId)
+// GetWorkouts returns user's workouts matching the filter without exercises, templates are not included.
+func (p *PostgresDb) GetWorkouts(userId string, filter model.WorkoutFilter) ([]model.Workout, error) {
+	query, args := filterWorkouts(selectWorkoutsByUserIdQuery, userId, filter)
+	return p.getWorkouts(query, args...)
+}
+
+// filterWorkouts appends conditions of the filter to the query selecting workouts of the user.
+func filterWorkouts(query, userId string, filter model.WorkoutFilter) (string, []any) {
+	args := []any{userId}
+	arg := func(value any) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
+	}
+	if filter.FolderID != nil {
+		query += " AND folder_id = " + arg(*filter.FolderID)
+	}
+	if tags := model.NormalizeTags(filter.Tags); len(tags) > 0 {
+		query += ` AND (SELECT count(*) FROM workout_tag wt JOIN tag t ON t.id = wt.tag_id
+			WHERE wt.workout_id = workout.id AND t.name = ANY(` + arg(tags) + `)) = ` + arg(len(tags))
+	}
+	return query, args
+}
+
+func (p *PostgresDb) getWorkouts(query string, args ...any) ([]model.Workout, error) {
+	rows, err := p.db.Query(context.Background(), query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -230,19 +610,102 @@ func (p *PostgresDb) GetWorkouts(userId string) ([]model.Workout, error) {
 	var workouts []model.Workout
 	for rows.Next() {
 		var workout model.Workout
-		err := rows.Scan(&workout.ID, &workout.OwnerID, &workout.Name, &workout.Comment)
+		err := rows.Scan(&workout.ID, &workout.OwnerID, &workout.Name, &workout.Comment, &workout.Template, &workout.SourceTemplateID, &workout.Version,
+			&workout.DeletedAt, &workout.FolderID, &workout.Tags,
+		)
 		if err != nil {
 			return nil, err
 		}
 		workouts = append(workouts, workout)
 	}
-	return workouts, nil
+	return workouts, rows.Err()
+}
+
+// GetDeletedWorkouts returns user's workouts in trash matching the filter without exercises, most recently deleted first.
+func (p *PostgresDb) GetDeletedWorkouts(userId string, filter model.WorkoutFilter) ([]model.Workout, error) {
+	query, args := filterWorkouts(selectDeletedWorkoutsByUserIdQuery, userId, filter)
+	return p.getWorkouts(query+" ORDER BY deleted_at DESC", args...)
 }
 
+// DeleteWorkout moves workout to trash, it's purged after retention period. Pending schedules of the workout are
+// cancelled and its recurring series deleted, completed schedules keep referencing the workout.
 func (p *PostgresDb) DeleteWorkout(id string) error {
-	_, err := p.db.Exec(context.Background(), deleteWorkoutQuery, id)
+	ctx := context.Background()
+	tx, err := p.db.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.ReadCommitted, AccessMode: pgx.ReadWrite})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	tag, err := tx.Exec(ctx, softDeleteWorkoutQuery, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return nil
+	}
+	if _, err := tx.Exec(ctx, cancelPendingWorkoutSchedules, id, model.WorkoutDeletedCancelReason); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, deleteWorkoutScheduleSeriesByWorkout, id); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// RestoreWorkout moves user's workout back from trash. Schedules cancelled by the deletion stay cancelled.
+func (p *PostgresDb) RestoreWorkout(id, userId string) error {
+	tag, err := p.db.Exec(context.Background(), restoreWorkoutQuery, id, userId)
 	if err != nil {
 		return err
 	}
+	if tag.RowsAffected() == 0 {
+		return ErrWorkoutNotFound
+	}
 	return nil
 }
+
+// GetWorkoutSummaries returns summaries of given workouts keyed by workout id, exercises are sorted by order.
+// Rest defaults are taken from profiles of workout owners. Missing workouts are skipped.
+func (p *PostgresDb) GetWorkoutSummaries(ids []string) (map[string]model.WorkoutSummary, error) {
+	summaries := make(map[string]model.WorkoutSummary)
+	if len(ids) == 0 {
+		return summaries, nil
+	}
+	rows, err := p.db.Query(context.Background(), selectWorkoutNamesByIds, ids, int(model.DefaultRest/time.Second))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var summary model.WorkoutSummary
+		var restSeconds int
+		var warmUpRestSeconds *int
+		if err := rows.Scan(&summary.ID, &summary.Name, &restSeconds, &warmUpRestSeconds); err != nil {
+			return nil, err
+		}
+		summary.RestDefaults = restDefaults(restSeconds, warmUpRestSeconds)
+		summaries[summary.ID] = summary
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = p.db.Query(context.Background(), selectExerciseSummariesByWorkoutIds, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var workoutId string
+		var ex model.ExerciseSummary
+		if err := rows.Scan(&workoutId, &ex.ExerciseID, &ex.Name, &ex.MuscleGroup, &ex.Sets, &ex.Repetitions, &ex.Weight,
+			&ex.Rest.Seconds, &ex.Rest.SetSeconds, &ex.Rest.Tempo,
+		); err != nil {
+			return nil, err
+		}
+		summary := summaries[workoutId]
+		summary.Exercises = append(summary.Exercises, ex)
+		summaries[workoutId] = summary
+	}
+	return summaries, rows.Err()
+}
@@ -5,7 +5,9 @@ import (
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/suite"
 	"google.golang.org/protobuf/types/known/fieldmaskpb"
+	"slices"
 	"testing"
+	"time"
 	"workout-tracker-server/model"
 	"workout-tracker-server/test"
 )
@@ -124,7 +126,7 @@ func (s *WorkoutSuite) TestSaveIsTransactional() {
 	s.Require().Error(err)
 
 	//and when getting workout
-	workouts, err := s.workoutDb.GetWorkouts(userId)
+	workouts, err := s.workoutDb.GetWorkouts(userId, model.WorkoutFilter{})
 
 	//then partial workout data is not saved
 	s.Require().NoError(err)
@@ -136,7 +138,7 @@ func (s *WorkoutSuite) TestGetWorkoutsEmpty() {
 	userId := uuid.New().String()
 
 	//when
-	wrks, err := s.workoutDb.GetWorkouts(userId)
+	wrks, err := s.workoutDb.GetWorkouts(userId, model.WorkoutFilter{})
 
 	//then
 	s.Require().NoError(err)
@@ -160,7 +162,7 @@ func (s *WorkoutSuite) TestGetWorkoutsNonEmpty() {
 	s.Require().NoError(err)
 
 	//when
-	wrks, err := s.workoutDb.GetWorkouts(userid)
+	wrks, err := s.workoutDb.GetWorkouts(userid, model.WorkoutFilter{})
 
 	//then
 	s.Require().NoError(err)
@@ -199,7 +201,7 @@ func (s *WorkoutSuite) TestDeleteWorkoutExisting() {
 	s.Require().NoError(err)
 
 	//and when
-	wrks, err := s.workoutDb.GetWorkouts(userId)
+	wrks, err := s.workoutDb.GetWorkouts(userId, model.WorkoutFilter{})
 
 	//then
 	s.Require().NoError(err)
@@ -607,3 +609,114 @@ func (s *WorkoutSuite) TestUpdateWorkoutDeleteExistingWorkoutExercise() {
 
 	s.Require().Len(wrk2.Exercises, 0)
 }
+
+func (s *WorkoutSuite) TestGetWorkoutSummaries() {
+	//given
+	rest := int32(120)
+	tempo := "3-1-1-0"
+	workoutId, err := s.workoutDb.SaveWorkout(model.Workout{
+		OwnerID: uuid.New().String(),
+		Name:    "Push",
+		Exercises: []model.WorkoutExercise{
+			{ExerciseID: existingExerciseId2, Order: 2, Repetitions: 5, Sets: 2},
+			{ExerciseID: existingExerciseId, Order: 1, Repetitions: 10, Sets: 3, Rest: model.Rest{Seconds: &rest, SetSeconds: []int32{30}, Tempo: &tempo}},
+		},
+	})
+	s.Require().NoError(err)
+
+	//when
+	summaries, err := s.workoutDb.GetWorkoutSummaries([]string{workoutId, uuid.New().String()})
+
+	//then
+	s.Require().NoError(err)
+	s.Require().Len(summaries, 1)
+	s.Require().Equal("Push", summaries[workoutId].Name)
+	s.Require().Len(summaries[workoutId].Exercises, 2)
+	s.Require().Equal(existingExerciseId, summaries[workoutId].Exercises[0].ExerciseID)
+	s.Require().Equal("Bench Press", summaries[workoutId].Exercises[0].Name)
+	s.Require().Equal(int32(3), summaries[workoutId].Exercises[0].Sets)
+	s.Require().Equal(model.Rest{Seconds: &rest, SetSeconds: []int32{30}, Tempo: &tempo}, summaries[workoutId].Exercises[0].Rest)
+	s.Require().Equal(model.RestDefaults{Rest: model.DefaultRest}, summaries[workoutId].RestDefaults)
+}
+
+func (s *WorkoutSuite) TestUpdateWorkoutExerciseGroupsKeepsIds() {
+	//given
+	userId := uuid.New().String()
+	a, b := "A", "B"
+	workoutId, err := s.workoutDb.SaveWorkout(model.Workout{
+		Name:    "WRK",
+		OwnerID: userId,
+		Groups: []model.ExerciseGroup{
+			{Label: a, Type: model.ExerciseGroupTypeSuperset, Rounds: 3, RestBetweenRounds: 90 * time.Second},
+		},
+		Exercises: []model.WorkoutExercise{
+			{ExerciseID: existingExerciseId, Order: 1, GroupLabel: &a},
+			{ExerciseID: existingExerciseId2, Order: 2, GroupLabel: &a},
+		},
+	})
+	s.Require().NoError(err)
+
+	wrk, err := s.workoutDb.GetWorkout(workoutId)
+	s.Require().NoError(err)
+	s.Require().Len(wrk.Groups, 1)
+	s.Require().Equal(model.ExerciseGroupTypeSuperset, wrk.Groups[0].Type)
+	s.Require().Equal(int32(3), wrk.Groups[0].Rounds)
+	s.Require().Equal(90*time.Second, wrk.Groups[0].RestBetweenRounds)
+	s.Require().Len(wrk.Exercises, 2)
+	s.Require().Equal(&a, wrk.Exercises[0].GroupLabel)
+
+	//when - existing group is renamed, new circuit is added and second exercise is moved to it
+	groupId := wrk.Groups[0].GroupID
+	wrk.Groups[0].Label = b
+	wrk.Groups = append(wrk.Groups, model.ExerciseGroup{Label: a, Type: model.ExerciseGroupTypeCircuit, Rounds: 2})
+	slices.SortFunc(wrk.Exercises, func(e1, e2 model.WorkoutExercise) int { return int(e1.Order - e2.Order) })
+	wrk.Exercises[0].GroupLabel = &b
+	wrk.Exercises[1].GroupLabel = &a
+	err = s.workoutDb.UpdateWorkout(wrk, &fieldmaskpb.FieldMask{Paths: []string{"groups", "exercises"}})
+
+	//then
+	s.Require().NoError(err)
+
+	wrk2, err := s.workoutDb.GetWorkout(workoutId)
+	s.Require().NoError(err)
+	s.Require().Len(wrk2.Groups, 2)
+	s.Require().Equal(a, wrk2.Groups[0].Label)
+	s.Require().Equal(model.ExerciseGroupTypeCircuit, wrk2.Groups[0].Type)
+	s.Require().Equal(b, wrk2.Groups[1].Label)
+	s.Require().Equal(groupId, wrk2.Groups[1].GroupID)
+	for _, ex := range wrk2.Exercises {
+		s.Require().True(slices.ContainsFunc(wrk.Exercises, func(e model.WorkoutExercise) bool {
+			return e.WorkoutExerciseID == ex.WorkoutExerciseID && *e.GroupLabel == *ex.GroupLabel
+		}))
+	}
+
+	//when - groups are removed, exercises stay without group
+	err = s.workoutDb.UpdateWorkout(model.Workout{ID: workoutId}, &fieldmaskpb.FieldMask{Paths: []string{"groups"}})
+
+	//then
+	s.Require().NoError(err)
+
+	wrk3, err := s.workoutDb.GetWorkout(workoutId)
+	s.Require().NoError(err)
+	s.Require().Empty(wrk3.Groups)
+	s.Require().Len(wrk3.Exercises, 2)
+	s.Require().Nil(wrk3.Exercises[0].GroupLabel)
+	s.Require().Nil(wrk3.Exercises[1].GroupLabel)
+}
+
+func (s *WorkoutSuite) TestUpdateWorkoutReferencesNonExistingExerciseGroup() {
+	//given
+	userId := uuid.New().String()
+	workoutId, err := s.workoutDb.SaveWorkout(model.Workout{Name: "WRK", OwnerID: userId})
+	s.Require().NoError(err)
+
+	//when
+	label := "A"
+	err = s.workoutDb.UpdateWorkout(model.Workout{
+		ID:        workoutId,
+		Exercises: []model.WorkoutExercise{{ExerciseID: existingExerciseId, GroupLabel: &label}},
+	}, &fieldmaskpb.FieldMask{Paths: []string{"exercises"}})
+
+	//then
+	s.Require().ErrorIs(err, ErrExerciseGroupNotFound)
+}
@@ -15,11 +15,16 @@ import (
 	"os"
 	"os/signal"
 	workout "proto/workout/v1/generated"
+	"strconv"
 	"strings"
 	"syscall"
+	"time"
+	_ "time/tzdata" //timezone database is not available in runtime image
 	"workout-tracker-server/api"
 	"workout-tracker-server/auth"
 	"workout-tracker-server/db"
+	"workout-tracker-server/model"
+	"workout-tracker-server/scheduler"
 )
 
 func main() {
@@ -27,8 +32,19 @@ func main() {
 	database := db.NewPostgresDb(appConf.dbConnString)
 	authorization := auth.NewAuthorization(appConf.jwtSignKey)
 	exerciseAPI := api.NewExerciseAPI(database)
-	workoutAPI := api.NewWorkoutAPI(database)
-	workoutScheduleAPI := api.NewWorkoutScheduleAPI(database, database)
+	workoutAPI := api.NewWorkoutAPI(database, database, database, database)
+	workoutScheduleAPI := api.NewWorkoutScheduleAPI(database, database, database, database)
+	reportAPI := api.NewReportAPI(database, database)
+	profileAPI := api.NewProfileAPI(database)
+	calendarAPI := api.NewCalendarAPI(database, database, database)
+	programAPI := api.NewProgramAPI(database, database)
+	workoutTemplateAPI := api.NewWorkoutTemplateAPI(database, database)
+	workoutShareAPI := api.NewWorkoutShareAPI(database, database)
+	coachingAPI := api.NewCoachingAPI(database)
+	commentAPI := api.NewCommentAPI(database, database)
+	tagAPI := api.NewTagAPI(database)
+	folderAPI := api.NewFolderAPI(database)
+	searchAPI := api.NewSearchAPI(database)
 
 	lis, err := net.Listen("tcp", appConf.listenAddr)
 	if err != nil {
@@ -39,10 +55,22 @@ func main() {
 	s := grpc.NewServer(grpc.ChainUnaryInterceptor(
 		logging.UnaryServerInterceptor(accessLogger(), logging.WithLogOnEvents(logging.FinishCall)),
 		selector.UnaryServerInterceptor(grpcAuth.UnaryServerInterceptor(authorization.Auth), selector.MatchFunc(auth.Secured)),
+		selector.UnaryServerInterceptor(grpcAuth.UnaryServerInterceptor(authorization.OptionalAuth), selector.MatchFunc(auth.OptionallySecured)),
 	))
 	workout.RegisterExerciseServiceServer(s, exerciseAPI)
 	workout.RegisterWorkoutServiceServer(s, workoutAPI)
 	workout.RegisterWorkoutScheduleServiceServer(s, workoutScheduleAPI)
+	workout.RegisterReportServiceServer(s, reportAPI)
+	workout.RegisterProfileServiceServer(s, profileAPI)
+	workout.RegisterCalendarServiceServer(s, calendarAPI)
+	workout.RegisterProgramServiceServer(s, programAPI)
+	workout.RegisterWorkoutTemplateServiceServer(s, workoutTemplateAPI)
+	workout.RegisterWorkoutShareServiceServer(s, workoutShareAPI)
+	workout.RegisterCoachingServiceServer(s, coachingAPI)
+	workout.RegisterCommentServiceServer(s, commentAPI)
+	workout.RegisterTagServiceServer(s, tagAPI)
+	workout.RegisterFolderServiceServer(s, folderAPI)
+	workout.RegisterSearchServiceServer(s, searchAPI)
 
 	//for debugging purposes, reflection allows (generic) clients to query for available services, types etc.
 	reflection.Register(s)
@@ -54,27 +82,95 @@ func main() {
 		}
 	}()
 
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	reminderWorker := scheduler.NewReminderWorker(database, reminderChannels(appConf), appConf.reminderInterval)
+	go reminderWorker.Run(workerCtx)
+	missedScheduleWorker := scheduler.NewMissedScheduleWorker(database, appConf.missedScheduleInterval)
+	go missedScheduleWorker.Run(workerCtx)
+	workoutPurgeWorker := scheduler.NewWorkoutPurgeWorker(database, appConf.workoutPurgeInterval, appConf.workoutRetention)
+	go workoutPurgeWorker.Run(workerCtx)
+	idempotencyKeyCleanupWorker := scheduler.NewIdempotencyKeyCleanupWorker(database, appConf.idempotencyKeyCleanupInterval)
+	go idempotencyKeyCleanupWorker.Run(workerCtx)
+
 	shutDown := make(chan os.Signal, 1)
 	signal.Notify(shutDown, syscall.SIGINT, syscall.SIGTERM)
 	select {
 	case <-shutDown:
 		log.Println("shutting down server")
+		stopWorkers()
 		s.GracefulStop()
 	}
 }
 
+// reminderChannels returns available reminder channels, email channel is available only when SMTP is configured.
+func reminderChannels(conf appConf) map[model.ReminderChannel]scheduler.Channel {
+	channels := map[model.ReminderChannel]scheduler.Channel{
+		model.ReminderChannelLog:     scheduler.LogChannel{},
+		model.ReminderChannelWebhook: scheduler.NewWebhookChannel(10 * time.Second),
+	}
+	if conf.smtp.Host != "" {
+		channels[model.ReminderChannelEmail] = scheduler.NewEmailChannel(conf.smtp)
+	}
+	return channels
+}
+
 type appConf struct {
-	jwtSignKey   string
-	dbConnString string
-	listenAddr   string
+	jwtSignKey                    string
+	dbConnString                  string
+	listenAddr                    string
+	reminderInterval              time.Duration
+	missedScheduleInterval        time.Duration
+	workoutPurgeInterval          time.Duration
+	workoutRetention              time.Duration
+	idempotencyKeyCleanupInterval time.Duration
+	smtp                          scheduler.SMTPConf
 }
 
 func loadAppConf() appConf {
+	reminderInterval, err := time.ParseDuration(loadPropertyOrDefault("REMINDER_INTERVAL", "1m"))
+	if err != nil {
+		log.Fatalf("invalid REMINDER_INTERVAL: %v", err)
+	}
+	missedScheduleInterval, err := time.ParseDuration(loadPropertyOrDefault("MISSED_SCHEDULE_INTERVAL", "5m"))
+	if err != nil {
+		log.Fatalf("invalid MISSED_SCHEDULE_INTERVAL: %v", err)
+	}
+	workoutPurgeInterval, err := time.ParseDuration(loadPropertyOrDefault("WORKOUT_PURGE_INTERVAL", "1h"))
+	if err != nil {
+		log.Fatalf("invalid WORKOUT_PURGE_INTERVAL: %v", err)
+	}
+	workoutRetentionDays, err := strconv.Atoi(loadPropertyOrDefault("WORKOUT_RETENTION_DAYS", "30"))
+	if err != nil || workoutRetentionDays < 0 {
+		log.Fatalf("invalid WORKOUT_RETENTION_DAYS: %s", os.Getenv("WORKOUT_RETENTION_DAYS"))
+	}
+	idempotencyKeyCleanupInterval, err := time.ParseDuration(loadPropertyOrDefault("IDEMPOTENCY_KEY_CLEANUP_INTERVAL", "1h"))
+	if err != nil {
+		log.Fatalf("invalid IDEMPOTENCY_KEY_CLEANUP_INTERVAL: %v", err)
+	}
 	return appConf{
-		jwtSignKey:   loadPropertyOrFail("JWT_SIGNING_KEY"),
-		dbConnString: loadPropertyOrFail("DB_CONN_STRING"),
-		listenAddr:   loadPropertyOrFail("LISTEN_ADDR"),
+		jwtSignKey:                    loadPropertyOrFail("JWT_SIGNING_KEY"),
+		dbConnString:                  loadPropertyOrFail("DB_CONN_STRING"),
+		listenAddr:                    loadPropertyOrFail("LISTEN_ADDR"),
+		reminderInterval:              reminderInterval,
+		missedScheduleInterval:        missedScheduleInterval,
+		workoutPurgeInterval:          workoutPurgeInterval,
+		workoutRetention:              time.Duration(workoutRetentionDays) * 24 * time.Hour,
+		idempotencyKeyCleanupInterval: idempotencyKeyCleanupInterval,
+		smtp: scheduler.SMTPConf{
+			Host:     os.Getenv("SMTP_HOST"),
+			Port:     loadPropertyOrDefault("SMTP_PORT", "587"),
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     loadPropertyOrDefault("SMTP_FROM", "reminders@workout-tracker.local"),
+		},
+	}
+}
+
+func loadPropertyOrDefault(propName, defaultVal string) string {
+	if propVal := os.Getenv(propName); propVal != "" {
+		return propVal
 	}
+	return defaultVal
 }
 
 func loadPropertyOrFail(propName string) string {
@@ -0,0 +1,100 @@
+package model
+
+import (
+	"google.golang.org/protobuf/types/known/durationpb"
+	workout "proto/workout/v1/generated"
+	"strings"
+	"time"
+)
+
+type AdherenceStats struct {
+	CompletedCount         int32
+	MissedCount            int32
+	CurrentWeeklyStreak    int32
+	LongestWeeklyStreak    int32
+	AverageCompletionDelay time.Duration
+	MissedByWeekday        map[time.Weekday]int32
+}
+
+// CalculateAdherenceStats derives adherence statistics from given schedules. Week and weekday boundaries
+// are computed in provided location, weeks start on Monday. Pending schedules are counted neither as completed nor
+// missed, same as cancelled ones.
+func CalculateAdherenceStats(schedules []WorkoutSchedule, now time.Time, loc *time.Location) AdherenceStats {
+	stats := AdherenceStats{MissedByWeekday: make(map[time.Weekday]int32)}
+	completedWeeks := make(map[time.Time]bool)
+	var totalDelay time.Duration
+	var delayCount int64
+	for _, ws := range schedules {
+		scheduledAt := ws.ScheduledAt.In(loc)
+		switch ws.Status(now) {
+		case WorkoutScheduleStatusCompleted:
+			stats.CompletedCount++
+			completedWeeks[weekStart(scheduledAt)] = true
+			if ws.CompletedAt != nil {
+				totalDelay += ws.CompletedAt.Sub(ws.ScheduledAt)
+				delayCount++
+			}
+		case WorkoutScheduleStatusMissed:
+			stats.MissedCount++
+			stats.MissedByWeekday[scheduledAt.Weekday()]++
+		}
+	}
+	if delayCount > 0 {
+		stats.AverageCompletionDelay = totalDelay / time.Duration(delayCount)
+	}
+	stats.CurrentWeeklyStreak = currentWeeklyStreak(completedWeeks, weekStart(now.In(loc)))
+	stats.LongestWeeklyStreak = longestWeeklyStreak(completedWeeks)
+	return stats
+}
+
+// currentWeeklyStreak counts consecutive completed weeks back from current week, current week without
+// completed workout does not break the streak yet since it is still in progress.
+func currentWeeklyStreak(completedWeeks map[time.Time]bool, currentWeek time.Time) int32 {
+	week := currentWeek
+	if !completedWeeks[week] {
+		week = week.AddDate(0, 0, -7)
+	}
+	var streak int32
+	for completedWeeks[week] {
+		streak++
+		week = week.AddDate(0, 0, -7)
+	}
+	return streak
+}
+
+func longestWeeklyStreak(completedWeeks map[time.Time]bool) int32 {
+	var longest int32
+	for week := range completedWeeks {
+		//only start counting from the first week of a streak
+		if completedWeeks[week.AddDate(0, 0, -7)] {
+			continue
+		}
+		var streak int32
+		for w := week; completedWeeks[w]; w = w.AddDate(0, 0, 7) {
+			streak++
+		}
+		longest = max(longest, streak)
+	}
+	return longest
+}
+
+// weekStart returns midnight of the Monday starting the week of given time, in time's location.
+func weekStart(t time.Time) time.Time {
+	daysSinceMonday := (int(t.Weekday()) + 6) % 7
+	return time.Date(t.Year(), t.Month(), t.Day()-daysSinceMonday, 0, 0, 0, 0, t.Location())
+}
+
+func (s AdherenceStats) ToProto() *workout.GetAdherenceStatsResponse {
+	missedByWeekday := make(map[string]int32, len(s.MissedByWeekday))
+	for weekday, count := range s.MissedByWeekday {
+		missedByWeekday[strings.ToUpper(weekday.String())] = count
+	}
+	return &workout.GetAdherenceStatsResponse{
+		CompletedCount:         s.CompletedCount,
+		MissedCount:            s.MissedCount,
+		CurrentWeeklyStreak:    s.CurrentWeeklyStreak,
+		LongestWeeklyStreak:    s.LongestWeeklyStreak,
+		AverageCompletionDelay: durationpb.New(s.AverageCompletionDelay),
+		MissedByWeekday:        missedByWeekday,
+	}
+}
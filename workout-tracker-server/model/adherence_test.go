@@ -0,0 +1,101 @@
+package model
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestCalculateAdherenceStatsCounts(t *testing.T) {
+	//given now is wednesday
+	now := time.Date(2024, 1, 17, 12, 0, 0, 0, time.UTC)
+	completedAt := time.Date(2024, 1, 15, 11, 0, 0, 0, time.UTC)
+	schedules := []WorkoutSchedule{
+		{ScheduledAt: time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC), Completed: true, CompletedAt: &completedAt},
+		{ScheduledAt: time.Date(2024, 1, 16, 10, 0, 0, 0, time.UTC)},
+		{ScheduledAt: time.Date(2024, 1, 9, 10, 0, 0, 0, time.UTC)},
+		{ScheduledAt: time.Date(2024, 1, 18, 10, 0, 0, 0, time.UTC)}, //future, neither missed nor completed
+	}
+
+	//when
+	stats := CalculateAdherenceStats(schedules, now, time.UTC)
+
+	//then
+	require.Equal(t, int32(1), stats.CompletedCount)
+	require.Equal(t, int32(2), stats.MissedCount)
+	require.Equal(t, time.Hour, stats.AverageCompletionDelay)
+	require.Equal(t, map[time.Weekday]int32{time.Tuesday: 2}, stats.MissedByWeekday)
+}
+
+func TestCalculateAdherenceStatsStreaks(t *testing.T) {
+	now := time.Date(2024, 2, 7, 12, 0, 0, 0, time.UTC) //wednesday
+	completedOn := func(days ...time.Time) []WorkoutSchedule {
+		var schedules []WorkoutSchedule
+		for _, d := range days {
+			schedules = append(schedules, WorkoutSchedule{ScheduledAt: d, Completed: true})
+		}
+		return schedules
+	}
+	testCases := []struct {
+		name            string
+		schedules       []WorkoutSchedule
+		expectedCurrent int32
+		expectedLongest int32
+	}{
+		{
+			name:            "NoCompletedWorkouts",
+			expectedCurrent: 0,
+			expectedLongest: 0,
+		},
+		{
+			name: "CurrentWeekNotCompletedYetKeepsStreak",
+			schedules: completedOn(
+				time.Date(2024, 1, 22, 10, 0, 0, 0, time.UTC),
+				time.Date(2024, 1, 29, 10, 0, 0, 0, time.UTC),
+			),
+			expectedCurrent: 2,
+			expectedLongest: 2,
+		},
+		{
+			name: "GapBreaksStreak",
+			schedules: completedOn(
+				time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC),
+				time.Date(2024, 1, 8, 10, 0, 0, 0, time.UTC),
+				time.Date(2024, 1, 14, 10, 0, 0, 0, time.UTC), //sunday, same week as above
+				time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC),
+				time.Date(2024, 2, 6, 10, 0, 0, 0, time.UTC),
+			),
+			expectedCurrent: 1,
+			expectedLongest: 3,
+		},
+		{
+			name: "LastWeekMissedEndsCurrentStreak",
+			schedules: completedOn(
+				time.Date(2024, 1, 22, 10, 0, 0, 0, time.UTC),
+			),
+			expectedCurrent: 0,
+			expectedLongest: 1,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			stats := CalculateAdherenceStats(tc.schedules, now, time.UTC)
+
+			require.Equal(t, tc.expectedCurrent, stats.CurrentWeeklyStreak)
+			require.Equal(t, tc.expectedLongest, stats.LongestWeeklyStreak)
+		})
+	}
+}
+
+func TestCalculateAdherenceStatsUsesLocation(t *testing.T) {
+	//given missed workout on monday 23:30 UTC which is already tuesday in Warsaw
+	warsaw, err := time.LoadLocation("Europe/Warsaw")
+	require.NoError(t, err)
+	schedules := []WorkoutSchedule{{ScheduledAt: time.Date(2024, 1, 15, 23, 30, 0, 0, time.UTC)}}
+
+	//when
+	stats := CalculateAdherenceStats(schedules, time.Date(2024, 1, 20, 0, 0, 0, 0, time.UTC), warsaw)
+
+	//then
+	require.Equal(t, map[time.Weekday]int32{time.Tuesday: 1}, stats.MissedByWeekday)
+}
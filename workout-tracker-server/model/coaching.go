@@ -0,0 +1,37 @@
+package model
+
+import (
+	"google.golang.org/protobuf/types/known/timestamppb"
+	workout "proto/workout/v1/generated"
+	"time"
+)
+
+// CoachingRelationship links coach with client account resolved from the invited email, the invite is pending until
+// the client accepts it.
+type CoachingRelationship struct {
+	ID          string
+	CoachID     string
+	ClientEmail string
+	ClientID    *string
+	CreatedAt   time.Time
+	AcceptedAt  *time.Time
+}
+
+// IsParticipant reports whether user is coach or client of the relationship, invited client can decline pending invite.
+func (c CoachingRelationship) IsParticipant(userId string) bool {
+	return c.CoachID == userId || (c.ClientID != nil && *c.ClientID == userId)
+}
+
+func (c CoachingRelationship) ToProto() *workout.CoachingRelationship {
+	proto := &workout.CoachingRelationship{
+		Id:          c.ID,
+		CoachId:     c.CoachID,
+		ClientEmail: c.ClientEmail,
+		ClientId:    c.ClientID,
+		CreatedAt:   timestamppb.New(c.CreatedAt),
+	}
+	if c.AcceptedAt != nil {
+		proto.AcceptedAt = timestamppb.New(*c.AcceptedAt)
+	}
+	return proto
+}
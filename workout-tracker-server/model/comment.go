@@ -0,0 +1,138 @@
+package model
+
+import (
+	"google.golang.org/protobuf/types/known/timestamppb"
+	workout "proto/workout/v1/generated"
+	"time"
+)
+
+// CommentTarget identifies commented workout, workout exercise or session, exactly one of them is set.
+type CommentTarget struct {
+	WorkoutID         *string
+	WorkoutExerciseID *string
+	ScheduleID        *string
+}
+
+func CommentTargetFromProto(proto *workout.CommentTarget) CommentTarget {
+	var target CommentTarget
+	switch t := proto.GetTarget().(type) {
+	case *workout.CommentTarget_WorkoutId:
+		target.WorkoutID = &t.WorkoutId
+	case *workout.CommentTarget_WorkoutExerciseId:
+		target.WorkoutExerciseID = &t.WorkoutExerciseId
+	case *workout.CommentTarget_ScheduleId:
+		target.ScheduleID = &t.ScheduleId
+	}
+	return target
+}
+
+func (t CommentTarget) ToProto() *workout.CommentTarget {
+	switch {
+	case t.WorkoutID != nil:
+		return &workout.CommentTarget{Target: &workout.CommentTarget_WorkoutId{WorkoutId: *t.WorkoutID}}
+	case t.WorkoutExerciseID != nil:
+		return &workout.CommentTarget{Target: &workout.CommentTarget_WorkoutExerciseId{WorkoutExerciseId: *t.WorkoutExerciseID}}
+	case t.ScheduleID != nil:
+		return &workout.CommentTarget{Target: &workout.CommentTarget_ScheduleId{ScheduleId: *t.ScheduleID}}
+	}
+	return nil
+}
+
+// CommentTargetAccess describes who can comment the target, that is owner of the target, active coach of the owner
+// and users the workout is shared with by email.
+type CommentTargetAccess struct {
+	OwnerID string
+	// SharedWithUser reports whether target's workout is shared with email of the user.
+	SharedWithUser bool
+}
+
+type Comment struct {
+	ID       string
+	AuthorID string
+	Target   CommentTarget
+	ParentID *string
+	Body     string
+	// ReplyCount is number of direct replies.
+	ReplyCount int32
+	CreatedAt  time.Time
+	UpdatedAt  *time.Time
+}
+
+func (c Comment) Cursor() CreationCursor {
+	return CreationCursor{CreatedAt: c.CreatedAt, ID: c.ID}
+}
+
+func (c Comment) ToProto() *workout.Comment {
+	proto := &workout.Comment{
+		Id:         c.ID,
+		AuthorId:   c.AuthorID,
+		Target:     c.Target.ToProto(),
+		ParentId:   c.ParentID,
+		Body:       c.Body,
+		ReplyCount: c.ReplyCount,
+		CreatedAt:  timestamppb.New(c.CreatedAt),
+	}
+	if c.UpdatedAt != nil {
+		proto.UpdatedAt = timestamppb.New(*c.UpdatedAt)
+	}
+	return proto
+}
+
+// CreationCursor is position within comments or notifications sorted by creation time and id.
+type CreationCursor struct {
+	CreatedAt time.Time
+	ID        string
+}
+
+// CommentFilter selects either top level comments of the target or replies to the parent comment.
+type CommentFilter struct {
+	Target   *CommentTarget
+	ParentID *string
+	After    *CreationCursor
+	Limit    int
+}
+
+type NotificationType string
+
+const (
+	NotificationCommentReply NotificationType = "comment_reply"
+)
+
+var notificationTypeProto = map[NotificationType]workout.NotificationType{
+	NotificationCommentReply: workout.NotificationType_NOTIFICATION_TYPE_COMMENT_REPLY,
+}
+
+// Notification informs the user about activity of others, e.g. reply to their comment.
+type Notification struct {
+	ID        string
+	UserID    string
+	Type      NotificationType
+	Comment   Comment
+	CreatedAt time.Time
+	ReadAt    *time.Time
+}
+
+func (n Notification) Cursor() CreationCursor {
+	return CreationCursor{CreatedAt: n.CreatedAt, ID: n.ID}
+}
+
+func (n Notification) ToProto() *workout.Notification {
+	proto := &workout.Notification{
+		Id:        n.ID,
+		Type:      notificationTypeProto[n.Type],
+		Comment:   n.Comment.ToProto(),
+		CreatedAt: timestamppb.New(n.CreatedAt),
+	}
+	if n.ReadAt != nil {
+		proto.ReadAt = timestamppb.New(*n.ReadAt)
+	}
+	return proto
+}
+
+// NotificationFilter selects notifications of the user, newest first.
+type NotificationFilter struct {
+	UnreadOnly bool
+	// Before is position of the last notification of previous page.
+	Before *CreationCursor
+	Limit  int
+}
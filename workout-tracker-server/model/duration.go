@@ -0,0 +1,161 @@
+package model
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	// repetitionDuration is average time of single repetition.
+	repetitionDuration = 4 * time.Second
+	// DefaultRest is rest between sets for users who haven't set their own default.
+	DefaultRest = 90 * time.Second
+	// explosivePhase is duration of repetition phase marked as X in tempo.
+	explosivePhase = time.Second
+	// exerciseTransition is time to set up the next exercise.
+	exerciseTransition = time.Minute
+	// minWorkoutDuration is used for workouts without exercises.
+	minWorkoutDuration = 15 * time.Minute
+	// MaxWorkoutDuration caps estimates, no workout is expected to last longer.
+	MaxWorkoutDuration = 4 * time.Hour
+
+	// minCalibrationSessions is number of past sessions needed before estimates are calibrated.
+	minCalibrationSessions = 3
+	// sessions which took less or more than given multiple of the estimate are treated as outliers, e.g. workout
+	// marked as complete next day.
+	minSessionRatio = 0.5
+	maxSessionRatio = 3
+)
+
+// RestDefaults are user's rest preferences used for workout exercises without own rest.
+type RestDefaults struct {
+	Rest time.Duration
+	// WarmUpRest is rest after the first set of exercise, Rest is used when nil.
+	WarmUpRest *time.Duration
+}
+
+// AfterSet returns rest after given set, counted from 1. Rest of the set has precedence over rest of the exercise
+// which has precedence over defaults.
+func (r Rest) AfterSet(set int, defaults RestDefaults) time.Duration {
+	if set <= len(r.SetSeconds) {
+		return time.Duration(r.SetSeconds[set-1]) * time.Second
+	}
+	if r.Seconds != nil {
+		return time.Duration(*r.Seconds) * time.Second
+	}
+	if set == 1 && defaults.WarmUpRest != nil {
+		return *defaults.WarmUpRest
+	}
+	return defaults.Rest
+}
+
+// ParseTempo parses tempo in eccentric-pause-concentric-pause notation and returns duration of single repetition.
+func ParseTempo(tempo string) (time.Duration, error) {
+	phases := strings.Split(tempo, "-")
+	if len(phases) != 4 {
+		return 0, fmt.Errorf("invalid tempo %s, expected 4 phases", tempo)
+	}
+	var duration time.Duration
+	for _, phase := range phases {
+		switch {
+		case phase == "X":
+			duration += explosivePhase
+		case len(phase) == 1 && phase[0] >= '0' && phase[0] <= '9':
+			duration += time.Duration(phase[0]-'0') * time.Second
+		default:
+			return 0, fmt.Errorf("invalid tempo %s, phases must be single digit or X", tempo)
+		}
+	}
+	return duration, nil
+}
+
+// EstimateDuration estimates workout duration from sets, repetitions, tempo and rests of its exercises.
+func (s WorkoutSummary) EstimateDuration() time.Duration {
+	var duration time.Duration
+	for i, ex := range s.Exercises {
+		repetition := repetitionDuration
+		if ex.Rest.Tempo != nil {
+			if tempo, err := ParseTempo(*ex.Rest.Tempo); err == nil && tempo > 0 {
+				repetition = tempo
+			}
+		}
+		sets := int(max(ex.Sets, 1))
+		duration += time.Duration(sets) * time.Duration(max(ex.Repetitions, 1)) * repetition
+		for set := 1; set < sets; set++ {
+			duration += ex.Rest.AfterSet(set, s.RestDefaults)
+		}
+		if i > 0 {
+			duration += exerciseTransition
+		}
+	}
+	return min(max(duration, minWorkoutDuration), MaxWorkoutDuration)
+}
+
+// CompletedSession is past workout with its estimated and actual duration, actual duration is time from schedule
+// until marking the workout as completed.
+type CompletedSession struct {
+	Estimated time.Duration
+	Actual    time.Duration
+}
+
+// DurationCalibration scales workout duration estimates to the user's pace.
+type DurationCalibration float64
+
+// NewDurationCalibration derives calibration from user's past sessions as median ratio of actual and estimated
+// duration. Outliers are skipped and without enough sessions estimates are not scaled.
+func NewDurationCalibration(sessions []CompletedSession) DurationCalibration {
+	var ratios []float64
+	for _, session := range sessions {
+		if session.Estimated <= 0 {
+			continue
+		}
+		ratio := float64(session.Actual) / float64(session.Estimated)
+		if ratio >= minSessionRatio && ratio <= maxSessionRatio {
+			ratios = append(ratios, ratio)
+		}
+	}
+	if len(ratios) < minCalibrationSessions {
+		return 1
+	}
+	slices.Sort(ratios)
+	middle := len(ratios) / 2
+	if len(ratios)%2 == 0 {
+		return DurationCalibration((ratios[middle-1] + ratios[middle]) / 2)
+	}
+	return DurationCalibration(ratios[middle])
+}
+
+func (c DurationCalibration) Apply(estimate time.Duration) time.Duration {
+	return min(time.Duration(float64(estimate)*float64(c)).Round(time.Minute), MaxWorkoutDuration)
+}
+
+// ScheduleConflict is pending workout schedule overlapping with newly scheduled workout.
+type ScheduleConflict struct {
+	Schedule          WorkoutSchedule
+	EstimatedDuration time.Duration
+}
+
+// FindScheduleConflicts returns pending schedules overlapping with any of given slots. Duration of each schedule
+// is looked up by its workout id.
+func FindScheduleConflicts(slots []WorkoutSchedule, existing []WorkoutSchedule, durations map[string]time.Duration, now time.Time) []ScheduleConflict {
+	var conflicts []ScheduleConflict
+	for _, ws := range existing {
+		if ws.Status(now) != WorkoutScheduleStatusPending {
+			continue
+		}
+		duration := durations[ws.WorkoutID]
+		for _, slot := range slots {
+			if overlaps(slot.ScheduledAt, durations[slot.WorkoutID], ws.ScheduledAt, duration) {
+				conflicts = append(conflicts, ScheduleConflict{Schedule: ws, EstimatedDuration: duration})
+				break
+			}
+		}
+	}
+	return conflicts
+}
+
+func overlaps(start1 time.Time, duration1 time.Duration, start2 time.Time, duration2 time.Duration) bool {
+	return start1.Before(start2.Add(duration2)) && start2.Before(start1.Add(duration1))
+}
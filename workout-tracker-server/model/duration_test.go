@@ -0,0 +1,124 @@
+package model
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestEstimateDuration(t *testing.T) {
+	tempo := "3-1-1-0"
+	rest := int32(120)
+	warmUpRest := 30 * time.Second
+	testCases := []struct {
+		name     string
+		summary  WorkoutSummary
+		expected time.Duration
+	}{
+		{"NoExercises", WorkoutSummary{}, minWorkoutDuration},
+		{
+			//4x10: 160s of repetitions + 3 rests, 5x8: 160s of repetitions + 4 rests + transition
+			"Exercises",
+			WorkoutSummary{
+				Exercises:    []ExerciseSummary{{Sets: 4, Repetitions: 10}, {Sets: 5, Repetitions: 8}},
+				RestDefaults: RestDefaults{Rest: DefaultRest},
+			},
+			160*time.Second + 3*DefaultRest + 160*time.Second + 4*DefaultRest + exerciseTransition,
+		},
+		{
+			//3x10 with 3-1-1-0 tempo: 150s of repetitions, rests 30s (warm-up), 60s (set), 120s (exercise) + 120s
+			"RestAndTempo",
+			WorkoutSummary{
+				Exercises: []ExerciseSummary{
+					{Sets: 3, Repetitions: 10, Rest: Rest{Tempo: &tempo}},
+					{Sets: 3, Repetitions: 10, Rest: Rest{Seconds: &rest, SetSeconds: []int32{60}}},
+				},
+				RestDefaults: RestDefaults{Rest: DefaultRest, WarmUpRest: &warmUpRest},
+			},
+			150*time.Second + 30*time.Second + DefaultRest + 120*time.Second + time.Minute + 120*time.Second + exerciseTransition,
+		},
+		{"Capped", WorkoutSummary{Exercises: []ExerciseSummary{{Sets: 200, Repetitions: 10}}}, MaxWorkoutDuration},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, tc.summary.EstimateDuration())
+		})
+	}
+}
+
+func TestParseTempo(t *testing.T) {
+	testCases := []struct {
+		tempo    string
+		expected time.Duration
+		valid    bool
+	}{
+		{"3-1-1-0", 5 * time.Second, true},
+		{"2-0-X-0", 3 * time.Second, true},
+		{"3-1-1", 0, false},
+		{"3-1-10-0", 0, false},
+		{"a-1-1-0", 0, false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.tempo, func(t *testing.T) {
+			duration, err := ParseTempo(tc.tempo)
+			if !tc.valid {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, duration)
+		})
+	}
+}
+
+func TestNewDurationCalibration(t *testing.T) {
+	hour := time.Hour
+	testCases := []struct {
+		name     string
+		sessions []CompletedSession
+		expected DurationCalibration
+	}{
+		{"NotEnoughSessions", []CompletedSession{{hour, 2 * hour}, {hour, 2 * hour}}, 1},
+		{"Median", []CompletedSession{{hour, 2 * hour}, {hour, hour}, {hour, 90 * time.Minute}}, 1.5},
+		{"EvenMedian", []CompletedSession{{hour, 2 * hour}, {hour, hour}, {hour, 90 * time.Minute}, {hour, 60 * time.Minute}}, 1.25},
+		{"OutliersSkipped", []CompletedSession{{hour, 2 * hour}, {hour, hour}, {hour, 24 * hour}, {hour, time.Minute}}, 1},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, NewDurationCalibration(tc.sessions))
+		})
+	}
+}
+
+func TestDurationCalibrationApply(t *testing.T) {
+	require.Equal(t, 45*time.Minute, DurationCalibration(1.5).Apply(30*time.Minute))
+	require.Equal(t, MaxWorkoutDuration, DurationCalibration(3).Apply(3*time.Hour))
+}
+
+func TestFindScheduleConflicts(t *testing.T) {
+	//given new 1 hour workout at 10:00
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC)
+	durations := map[string]time.Duration{"new": time.Hour, "short": 30 * time.Minute, "long": 2 * time.Hour}
+	slots := []WorkoutSchedule{{WorkoutID: "new", ScheduledAt: at}}
+	cancelledAt := now
+	existing := []WorkoutSchedule{
+		{ID: "endsBefore", WorkoutID: "short", ScheduledAt: at.Add(-30 * time.Minute)},
+		{ID: "overlapsStart", WorkoutID: "long", ScheduledAt: at.Add(-time.Hour)},
+		{ID: "sameMinute", WorkoutID: "short", ScheduledAt: at},
+		{ID: "startsAfter", WorkoutID: "short", ScheduledAt: at.Add(time.Hour)},
+		{ID: "completed", WorkoutID: "short", ScheduledAt: at, Completed: true},
+		{ID: "cancelled", WorkoutID: "short", ScheduledAt: at, CancelledAt: &cancelledAt},
+	}
+
+	//when
+	conflicts := FindScheduleConflicts(slots, existing, durations, now)
+
+	//then
+	var ids []string
+	for _, conflict := range conflicts {
+		ids = append(ids, conflict.Schedule.ID)
+	}
+	require.Equal(t, []string{"overlapsStart", "sameMinute"}, ids)
+	require.Equal(t, 2*time.Hour, conflicts[0].EstimatedDuration)
+}
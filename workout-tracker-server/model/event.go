@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+type WorkoutScheduleEventType string
+
+const (
+	WorkoutScheduleEventMissed WorkoutScheduleEventType = "missed"
+)
+
+// WorkoutScheduleEvent records change of workout schedule state made by the system, not by the user.
+type WorkoutScheduleEvent struct {
+	ID         string
+	ScheduleID string
+	OwnerID    string
+	Type       WorkoutScheduleEventType
+	CreatedAt  time.Time
+}
@@ -8,6 +8,8 @@ type Exercise struct {
 	Description string
 	MuscleGroup string
 	Category    string
+	// OwnerID is set for custom exercises of users, global exercises have no owner.
+	OwnerID *string
 }
 
 func (e Exercise) ToProto() *workout.Exercise {
@@ -17,5 +19,6 @@ func (e Exercise) ToProto() *workout.Exercise {
 		MuscleGroup: e.MuscleGroup,
 		Category:    e.Category,
 		Description: e.Description,
+		Custom:      e.OwnerID != nil,
 	}
 }
@@ -0,0 +1,22 @@
+package model
+
+import "time"
+
+// IdempotencyKeyTTL is how long response of create request is kept for replay of retries with the same key.
+const IdempotencyKeyTTL = 24 * time.Hour
+
+// IdempotencyKeyLease is how long key reserved by request without stored response blocks retries, after that
+// the request is considered abandoned and retry takes the key over.
+const IdempotencyKeyLease = time.Minute
+
+// IdempotencyKey is idempotency key of user's create request, key is unique per user and method.
+type IdempotencyKey struct {
+	UserID string
+	Method string
+	Key    string
+	// RequestHash is hash of the request without its key, retry must send the same request.
+	RequestHash []byte
+	// Response is marshalled response of the request, nil while the request is processed.
+	Response  []byte
+	CreatedAt time.Time
+}
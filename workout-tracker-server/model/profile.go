@@ -0,0 +1,79 @@
+package model
+
+import (
+	"google.golang.org/protobuf/types/known/durationpb"
+	workout "proto/workout/v1/generated"
+	"time"
+)
+
+const DefaultReminderLeadTime = time.Hour
+
+type ReminderChannel string
+
+const (
+	ReminderChannelEmail   ReminderChannel = "email"
+	ReminderChannelWebhook ReminderChannel = "webhook"
+	ReminderChannelLog     ReminderChannel = "log"
+)
+
+var reminderChannelProto = map[ReminderChannel]workout.ReminderChannel{
+	ReminderChannelEmail:   workout.ReminderChannel_REMINDER_CHANNEL_EMAIL,
+	ReminderChannelWebhook: workout.ReminderChannel_REMINDER_CHANNEL_WEBHOOK,
+	ReminderChannelLog:     workout.ReminderChannel_REMINDER_CHANNEL_LOG,
+}
+
+func ReminderChannelFromProto(proto workout.ReminderChannel) ReminderChannel {
+	for channel, p := range reminderChannelProto {
+		if p == proto {
+			return channel
+		}
+	}
+	return ""
+}
+
+// Profile holds user preferences, users without stored profile get DefaultProfile.
+type Profile struct {
+	UserID           string
+	ReminderLeadTime time.Duration
+	ReminderChannels []ReminderChannel
+	WebhookURL       *string
+	RestDefaults     RestDefaults
+}
+
+func DefaultProfile(userId string) Profile {
+	return Profile{UserID: userId, ReminderLeadTime: DefaultReminderLeadTime, RestDefaults: RestDefaults{Rest: DefaultRest}}
+}
+
+func (p Profile) ToProto() *workout.Profile {
+	proto := &workout.Profile{
+		ReminderLeadTime: durationpb.New(p.ReminderLeadTime),
+		WebhookUrl:       p.WebhookURL,
+		DefaultRest:      durationpb.New(p.RestDefaults.Rest),
+	}
+	if p.RestDefaults.WarmUpRest != nil {
+		proto.WarmUpRest = durationpb.New(*p.RestDefaults.WarmUpRest)
+	}
+	for _, channel := range p.ReminderChannels {
+		proto.ReminderChannels = append(proto.ReminderChannels, reminderChannelProto[channel])
+	}
+	return proto
+}
+
+// Reminder is notification about upcoming workout schedule.
+type Reminder struct {
+	ScheduleID  string
+	OwnerID     string
+	Email       string
+	WorkoutName string
+	ScheduledAt time.Time
+	// Timezone is IANA timezone the workout was scheduled in.
+	Timezone   string
+	Channels   []ReminderChannel
+	WebhookURL *string
+}
+
+// ReminderSeries is recurring series of user with reminders enabled.
+type ReminderSeries struct {
+	Series   WorkoutScheduleSeries
+	LeadTime time.Duration
+}
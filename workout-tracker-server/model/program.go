@@ -0,0 +1,249 @@
+package model
+
+import (
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"math"
+	workout "proto/workout/v1/generated"
+	"slices"
+	"time"
+)
+
+const (
+	// deloadWeightFactor and deloadSetsFactor scale targets of the week before deload.
+	deloadWeightFactor = 0.6
+	deloadSetsFactor   = 0.5
+	// ProgramSkippedReason is cancel reason of sessions skipped after being missed.
+	ProgramSkippedReason = "skipped in program"
+)
+
+// Program is multi-week training plan, its weeks are made of days referencing workouts.
+type Program struct {
+	ID          string
+	OwnerID     string
+	Name        string
+	Description *string
+	Weeks       []ProgramWeek
+	CreatedAt   time.Time
+}
+
+type ProgramWeek struct {
+	Days []ProgramDay
+	// WeightIncreasePercent is weight change against the previous week, not applied in deload week.
+	WeightIncreasePercent float64
+	Deload                bool
+}
+
+type ProgramDay struct {
+	WorkoutID string
+	// Day within the week starting at 1.
+	Day int32
+}
+
+// Progression scales exercise targets of workouts within program week.
+type Progression struct {
+	WeightFactor float64
+	SetsFactor   float64
+}
+
+// Progressions returns progression of each program week. Weight increases compound week over week, deload week
+// lowers weight of the previous week and following weeks progress as if deload week was not there.
+func (p Program) Progressions() []Progression {
+	progressions := make([]Progression, 0, len(p.Weeks))
+	weightFactor := 1.0
+	for i, week := range p.Weeks {
+		if week.Deload {
+			progressions = append(progressions, Progression{WeightFactor: weightFactor * deloadWeightFactor, SetsFactor: deloadSetsFactor})
+			continue
+		}
+		if i > 0 {
+			weightFactor *= 1 + week.WeightIncreasePercent/100
+		}
+		progressions = append(progressions, Progression{WeightFactor: weightFactor, SetsFactor: 1})
+	}
+	return progressions
+}
+
+// Apply scales weight and sets of the exercise, weight is rounded to whole units and both stay positive.
+func (pr Progression) Apply(ex ExerciseSummary) ExerciseSummary {
+	ex.Sets = max(int32(math.Ceil(float64(ex.Sets)*pr.SetsFactor)), 1)
+	if ex.Weight != nil {
+		weight := max(int32(math.Round(float64(*ex.Weight)*pr.WeightFactor)), 1)
+		ex.Weight = &weight
+	}
+	return ex
+}
+
+// Sessions schedules program days from given start date, sessions keep local time of day of the start date
+// in given location. Sessions are sorted by week and day.
+func (p Program) Sessions(ownerId string, startDate time.Time, loc *time.Location) []ProgramSession {
+	var sessions []ProgramSession
+	for i, week := range p.Weeks {
+		days := slices.SortedFunc(slices.Values(week.Days), func(d1, d2 ProgramDay) int { return int(d1.Day - d2.Day) })
+		for _, day := range days {
+			sessions = append(sessions, ProgramSession{
+				Week: int32(i + 1),
+				Day:  day.Day,
+				Schedule: WorkoutSchedule{
+					OwnerID:     ownerId,
+					WorkoutID:   day.WorkoutID,
+					ScheduledAt: shiftWallClock(startDate, i*7+int(day.Day)-1, 0, loc),
+					Timezone:    loc.String(),
+				},
+			})
+		}
+	}
+	return sessions
+}
+
+// WorkoutIDs returns distinct workouts referenced by program days.
+func (p Program) WorkoutIDs() []string {
+	var ids []string
+	for _, week := range p.Weeks {
+		for _, day := range week.Days {
+			if !slices.Contains(ids, day.WorkoutID) {
+				ids = append(ids, day.WorkoutID)
+			}
+		}
+	}
+	return ids
+}
+
+// ProgramEnrollment is user's run of the program, its sessions are stored as workout schedules.
+type ProgramEnrollment struct {
+	ID        string
+	ProgramID string
+	OwnerID   string
+	StartDate time.Time
+	Timezone  string
+	CreatedAt time.Time
+	// Sessions are sorted by week and day.
+	Sessions []ProgramSession
+}
+
+// ProgramSession is workout schedule of program day.
+type ProgramSession struct {
+	Week     int32
+	Day      int32
+	Schedule WorkoutSchedule
+}
+
+// CurrentSession returns position of the user in the program, the first session which is neither completed nor
+// cancelled. Returns nil when program is finished.
+func (e ProgramEnrollment) CurrentSession(now time.Time) *ProgramSession {
+	for _, session := range e.Sessions {
+		if st := session.Schedule.Status(now); st == WorkoutScheduleStatusPending || st == WorkoutScheduleStatusMissed {
+			return &session
+		}
+	}
+	return nil
+}
+
+// MissedSessions returns sessions with missed status.
+func (e ProgramEnrollment) MissedSessions(now time.Time) []ProgramSession {
+	var missed []ProgramSession
+	for _, session := range e.Sessions {
+		if session.Schedule.Status(now) == WorkoutScheduleStatusMissed {
+			missed = append(missed, session)
+		}
+	}
+	return missed
+}
+
+// Reschedule moves the first missed session to resumeAt and all following sessions, which are neither completed
+// nor cancelled, by the same number of days and wall-clock offset in enrollment timezone. Returns moved schedules.
+func (e ProgramEnrollment) Reschedule(resumeAt, now time.Time) ([]WorkoutSchedule, error) {
+	missed := e.MissedSessions(now)
+	if len(missed) == 0 {
+		return nil, nil
+	}
+	loc, err := Location(e.Timezone)
+	if err != nil {
+		return nil, err
+	}
+	first := missed[0].Schedule.ScheduledAt.In(loc)
+	resumeAt = resumeAt.In(loc)
+	days := daysBetween(first, resumeAt)
+	clockOffset := timeOfDay(resumeAt) - timeOfDay(first)
+	var moved []WorkoutSchedule
+	for _, session := range e.Sessions {
+		ws := session.Schedule
+		if st := ws.Status(now); ws.ScheduledAt.Before(first) || (st != WorkoutScheduleStatusPending && st != WorkoutScheduleStatusMissed) {
+			continue
+		}
+		ws.ScheduledAt = shiftWallClock(ws.ScheduledAt, days, clockOffset, loc)
+		ws.MissedAt = nil
+		moved = append(moved, ws)
+	}
+	return moved, nil
+}
+
+// ToProto converts enrollment, exercise targets of sessions are workout summaries scaled by program progressions.
+func (e ProgramEnrollment) ToProto(now time.Time, program Program, summaries map[string]WorkoutSummary) *workout.ProgramEnrollment {
+	progressions := program.Progressions()
+	proto := &workout.ProgramEnrollment{
+		Id:        e.ID,
+		ProgramId: e.ProgramID,
+		StartDate: timestamppb.New(e.StartDate),
+		Timezone:  TimezoneName(e.Timezone),
+	}
+	for _, session := range e.Sessions {
+		sessionProto := &workout.ProgramSession{
+			Week:            session.Week,
+			Day:             session.Day,
+			WorkoutSchedule: session.Schedule.ToProto(now),
+		}
+		for _, ex := range summaries[session.Schedule.WorkoutID].Exercises {
+			if int(session.Week) <= len(progressions) {
+				ex = progressions[session.Week-1].Apply(ex)
+			}
+			sessionProto.Exercises = append(sessionProto.Exercises, ex.ToProto())
+		}
+		proto.Sessions = append(proto.Sessions, sessionProto)
+		switch session.Schedule.Status(now) {
+		case WorkoutScheduleStatusCompleted:
+			proto.CompletedCount++
+		case WorkoutScheduleStatusMissed:
+			proto.MissedCount++
+		}
+	}
+	if current := e.CurrentSession(now); current != nil {
+		for i, session := range e.Sessions {
+			if session.Week == current.Week && session.Day == current.Day {
+				proto.CurrentSession = proto.Sessions[i]
+			}
+		}
+	}
+	return proto
+}
+
+func FromProgramProto(proto *workout.Program) Program {
+	program := Program{
+		ID:          proto.Id,
+		Name:        proto.Name,
+		Description: proto.Description,
+	}
+	for _, week := range proto.Weeks {
+		w := ProgramWeek{WeightIncreasePercent: week.WeightIncreasePercent, Deload: week.Deload}
+		for _, day := range week.Days {
+			w.Days = append(w.Days, ProgramDay{WorkoutID: day.WorkoutId, Day: day.Day})
+		}
+		program.Weeks = append(program.Weeks, w)
+	}
+	return program
+}
+
+func (p Program) ToProto() *workout.Program {
+	proto := &workout.Program{
+		Id:          p.ID,
+		Name:        p.Name,
+		Description: p.Description,
+	}
+	for _, week := range p.Weeks {
+		w := &workout.ProgramWeek{WeightIncreasePercent: week.WeightIncreasePercent, Deload: week.Deload}
+		for _, day := range week.Days {
+			w.Days = append(w.Days, &workout.ProgramDay{WorkoutId: day.WorkoutID, Day: day.Day})
+		}
+		proto.Weeks = append(proto.Weeks, w)
+	}
+	return proto
+}
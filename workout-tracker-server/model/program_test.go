@@ -0,0 +1,87 @@
+package model
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestProgressions(t *testing.T) {
+	//given 10% increase each week with deload in the third week
+	program := Program{Weeks: []ProgramWeek{
+		{WeightIncreasePercent: 10},
+		{WeightIncreasePercent: 10},
+		{Deload: true},
+		{WeightIncreasePercent: 10},
+	}}
+
+	//when
+	progressions := program.Progressions()
+
+	//then first week uses workout targets and week after deload progresses from the week before it
+	require.Len(t, progressions, 4)
+	require.InDelta(t, 1, progressions[0].WeightFactor, 1e-9)
+	require.InDelta(t, 1.1, progressions[1].WeightFactor, 1e-9)
+	require.InDelta(t, 0.66, progressions[2].WeightFactor, 1e-9)
+	require.InDelta(t, 0.5, progressions[2].SetsFactor, 1e-9)
+	require.InDelta(t, 1.21, progressions[3].WeightFactor, 1e-9)
+}
+
+func TestProgressionApply(t *testing.T) {
+	//given
+	weight := int32(60)
+	ex := ExerciseSummary{Sets: 3, Repetitions: 8, Weight: &weight}
+
+	//when
+	deload := Progression{WeightFactor: 0.6, SetsFactor: 0.5}.Apply(ex)
+
+	//then
+	require.Equal(t, int32(2), deload.Sets)
+	require.Equal(t, int32(36), *deload.Weight)
+	require.Equal(t, int32(60), weight)
+}
+
+func TestProgramSessionsKeepLocalTime(t *testing.T) {
+	//given program with two weeks started week before DST starts in Warsaw on 2026-03-29
+	warsaw, err := time.LoadLocation("Europe/Warsaw")
+	require.NoError(t, err)
+	program := Program{Weeks: []ProgramWeek{
+		{Days: []ProgramDay{{WorkoutID: "b", Day: 3}, {WorkoutID: "a", Day: 1}}},
+		{Days: []ProgramDay{{WorkoutID: "a", Day: 1}}},
+	}}
+	start := time.Date(2026, 3, 23, 18, 0, 0, 0, warsaw)
+
+	//when
+	sessions := program.Sessions("owner", start.UTC(), warsaw)
+
+	//then
+	require.Len(t, sessions, 3)
+	require.Equal(t, []int32{1, 3, 1}, []int32{sessions[0].Day, sessions[1].Day, sessions[2].Day})
+	require.Equal(t, start.UTC(), sessions[0].Schedule.ScheduledAt)
+	require.Equal(t, time.Date(2026, 3, 25, 18, 0, 0, 0, warsaw).UTC(), sessions[1].Schedule.ScheduledAt)
+	require.Equal(t, int32(2), sessions[2].Week)
+	require.Equal(t, time.Date(2026, 3, 30, 18, 0, 0, 0, warsaw).UTC(), sessions[2].Schedule.ScheduledAt)
+}
+
+func TestEnrollmentReschedule(t *testing.T) {
+	//given completed, missed and pending sessions
+	now := time.Date(2026, 3, 9, 12, 0, 0, 0, time.UTC)
+	start := time.Date(2026, 3, 2, 18, 0, 0, 0, time.UTC)
+	enrollment := ProgramEnrollment{Sessions: []ProgramSession{
+		{Week: 1, Day: 1, Schedule: WorkoutSchedule{ID: "completed", ScheduledAt: start, Completed: true}},
+		{Week: 1, Day: 3, Schedule: WorkoutSchedule{ID: "missed", ScheduledAt: start.AddDate(0, 0, 2)}},
+		{Week: 2, Day: 1, Schedule: WorkoutSchedule{ID: "pending", ScheduledAt: start.AddDate(0, 0, 7)}},
+	}}
+
+	//when missed session is resumed next day at 19:00
+	moved, err := enrollment.Reschedule(time.Date(2026, 3, 10, 19, 0, 0, 0, time.UTC), now)
+
+	//then following session keeps 5 days spacing
+	require.NoError(t, err)
+	require.Len(t, moved, 2)
+	require.Equal(t, "missed", moved[0].ID)
+	require.Equal(t, time.Date(2026, 3, 10, 19, 0, 0, 0, time.UTC), moved[0].ScheduledAt)
+	require.Equal(t, "pending", moved[1].ID)
+	require.Equal(t, time.Date(2026, 3, 15, 19, 0, 0, 0, time.UTC), moved[1].ScheduledAt)
+	require.Equal(t, "missed", enrollment.CurrentSession(now).Schedule.ID)
+}
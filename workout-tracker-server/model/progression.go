@@ -0,0 +1,295 @@
+package model
+
+import (
+	"fmt"
+	"math"
+	workout "proto/workout/v1/generated"
+	"strconv"
+	"time"
+)
+
+const (
+	// weightIncrement is the smallest weight change, suggested weights are rounded to it.
+	weightIncrement = 2.5
+	// linearSessionsToProgress is number of consecutive sessions hitting targets before weight increases.
+	linearSessionsToProgress = 2
+	// linearMissesToDeload is number of consecutive sessions missing targets before weight decreases.
+	linearMissesToDeload = 2
+	linearDeloadFactor   = 0.9
+	// doubleRepRange is number of repetitions above the prescribed ones which are gained before weight increases.
+	doubleRepRange = 4
+	// targetRPE is effort of the top set aimed for, every point of RPE below or above changes weight by rpeStep.
+	targetRPE = 8
+	rpeStep   = 0.025
+)
+
+// ExerciseSet is set performed in completed workout schedule.
+type ExerciseSet struct {
+	ExerciseID  string
+	Set         int32
+	Repetitions int32
+	Weight      *float64
+	RPE         *float64
+}
+
+// ExerciseSession is sets of single exercise performed in completed workout schedule, sorted by set.
+type ExerciseSession struct {
+	ScheduleID  string
+	CompletedAt time.Time
+	Sets        []ExerciseSet
+}
+
+// TopWeight returns the heaviest weight used in the session, 0 when no weight was recorded.
+func (s ExerciseSession) TopWeight() float64 {
+	var top float64
+	for _, set := range s.Sets {
+		if set.Weight != nil {
+			top = max(top, *set.Weight)
+		}
+	}
+	return top
+}
+
+// MinRepetitions returns the lowest repetitions among sets performed at given weight, first sets number of them
+// are taken into account. Returns 0 when fewer sets were performed.
+func (s ExerciseSession) MinRepetitions(sets int32, weight float64) int32 {
+	var count int32
+	minReps := int32(math.MaxInt32)
+	for _, set := range s.Sets {
+		if count == sets {
+			break
+		}
+		if setWeight(set) < weight {
+			continue
+		}
+		minReps = min(minReps, set.Repetitions)
+		count++
+	}
+	if count < sets {
+		return 0
+	}
+	return minReps
+}
+
+// TopRPE returns the highest RPE reported in the session, nil when not reported.
+func (s ExerciseSession) TopRPE() *float64 {
+	var top *float64
+	for _, set := range s.Sets {
+		if set.RPE != nil && (top == nil || *set.RPE > *top) {
+			top = set.RPE
+		}
+	}
+	return top
+}
+
+func setWeight(set ExerciseSet) float64 {
+	if set.Weight == nil {
+		return 0
+	}
+	return *set.Weight
+}
+
+// ExercisePlan is suggested target of workout exercise for the next session.
+type ExercisePlan struct {
+	WorkoutExerciseID string
+	ExerciseID        string
+	Sets              int32
+	Repetitions       int32
+	Weight            *float64
+	Explanation       string
+}
+
+// ProgressionStrategy suggests next session targets of workout exercise from the exercise history, sessions
+// are sorted from the most recent.
+type ProgressionStrategy interface {
+	Suggest(target WorkoutExercise, history []ExerciseSession) ExercisePlan
+}
+
+var progressionStrategies = map[workout.ProgressionStrategy]ProgressionStrategy{
+	workout.ProgressionStrategy_PROGRESSION_STRATEGY_LINEAR: LinearProgression{},
+	workout.ProgressionStrategy_PROGRESSION_STRATEGY_DOUBLE: DoubleProgression{},
+	workout.ProgressionStrategy_PROGRESSION_STRATEGY_RPE:    RPEProgression{},
+}
+
+// NewProgressionStrategy returns strategy of given kind, linear progression when not specified.
+func NewProgressionStrategy(kind workout.ProgressionStrategy) ProgressionStrategy {
+	if strategy, ok := progressionStrategies[kind]; ok {
+		return strategy
+	}
+	return LinearProgression{}
+}
+
+// LinearProgression increases weight after prescribed sets and repetitions are hit in consecutive sessions at the
+// same weight and decreases it after consecutive misses. Exercises without weight progress by repetitions.
+type LinearProgression struct{}
+
+func (LinearProgression) Suggest(target WorkoutExercise, history []ExerciseSession) ExercisePlan {
+	plan := newExercisePlan(target, history)
+	if len(history) == 0 {
+		plan.Explanation = "no history, starting at prescribed targets"
+		return plan
+	}
+	weight := weightOf(plan)
+	hits, misses := 0, 0
+	for _, session := range history {
+		if session.TopWeight() != weight {
+			break
+		}
+		hit := session.MinRepetitions(target.Sets, weight) >= target.Repetitions
+		if (hit && misses > 0) || (!hit && hits > 0) {
+			break
+		}
+		if hit {
+			hits++
+		} else {
+			misses++
+		}
+	}
+	done := fmt.Sprintf("%dx%d", target.Sets, target.Repetitions)
+	at := atWeight(plan.Weight)
+	switch {
+	case hits >= linearSessionsToProgress && plan.Weight == nil:
+		plan.Repetitions++
+		plan.Explanation = fmt.Sprintf("hit %s %s → %dx%d", done, times(hits), plan.Sets, plan.Repetitions)
+	case hits >= linearSessionsToProgress:
+		plan.Weight = roundWeight(weight + weightIncrement)
+		plan.Explanation = fmt.Sprintf("hit %s %s%s → %s", done, times(hits), at, formatWeight(*plan.Weight))
+	case misses >= linearMissesToDeload && plan.Weight != nil:
+		plan.Weight = roundWeight(weight * linearDeloadFactor)
+		plan.Explanation = fmt.Sprintf("missed %s %s%s → %s", done, times(misses), at, formatWeight(*plan.Weight))
+	case hits > 0:
+		plan.Explanation = fmt.Sprintf("hit %s %s%s, repeat", done, times(hits), at)
+	case misses > 0:
+		plan.Explanation = fmt.Sprintf("missed %s %s%s, repeat", done, times(misses), at)
+	default:
+		plan.Explanation = fmt.Sprintf("no sets recorded%s, repeat", at)
+	}
+	return plan
+}
+
+// DoubleProgression increases repetitions by one each session all sets are hit, until the top of the range
+// above prescribed repetitions is reached. Weight increases then and repetitions start over.
+type DoubleProgression struct{}
+
+func (DoubleProgression) Suggest(target WorkoutExercise, history []ExerciseSession) ExercisePlan {
+	plan := newExercisePlan(target, history)
+	if len(history) == 0 {
+		plan.Explanation = "no history, starting at prescribed targets"
+		return plan
+	}
+	weight := weightOf(plan)
+	top := target.Repetitions + doubleRepRange
+	reps := history[0].MinRepetitions(target.Sets, weight)
+	switch {
+	case reps >= top && plan.Weight != nil:
+		plan.Weight = roundWeight(weight + weightIncrement)
+		plan.Explanation = fmt.Sprintf("hit %dx%d%s, top of %d-%d range → %s %dx%d",
+			target.Sets, reps, atWeight(&weight), target.Repetitions, top, formatWeight(*plan.Weight), plan.Sets, plan.Repetitions)
+	case reps >= target.Repetitions:
+		plan.Repetitions = min(reps+1, top)
+		plan.Explanation = fmt.Sprintf("hit %dx%d%s → %dx%d", target.Sets, reps, atWeight(plan.Weight), plan.Sets, plan.Repetitions)
+	default:
+		plan.Explanation = fmt.Sprintf("missed %dx%d%s, repeat", target.Sets, target.Repetitions, atWeight(plan.Weight))
+	}
+	return plan
+}
+
+// RPEProgression changes weight by difference between the highest RPE reported in the last session and target RPE.
+type RPEProgression struct{}
+
+func (RPEProgression) Suggest(target WorkoutExercise, history []ExerciseSession) ExercisePlan {
+	plan := newExercisePlan(target, history)
+	if len(history) == 0 {
+		plan.Explanation = "no history, starting at prescribed targets"
+		return plan
+	}
+	rpe := history[0].TopRPE()
+	if rpe == nil || plan.Weight == nil {
+		plan.Explanation = fmt.Sprintf("no RPE reported%s, repeat", atWeight(plan.Weight))
+		return plan
+	}
+	weight := *plan.Weight
+	plan.Weight = roundWeight(weight * (1 + (targetRPE-*rpe)*rpeStep))
+	rpeText := strconv.FormatFloat(*rpe, 'f', -1, 64)
+	if *plan.Weight == weight {
+		plan.Explanation = fmt.Sprintf("RPE %s%s on target %d, repeat", rpeText, atWeight(&weight), targetRPE)
+		return plan
+	}
+	plan.Explanation = fmt.Sprintf("RPE %s%s against target %d → %s", rpeText, atWeight(&weight), targetRPE, formatWeight(*plan.Weight))
+	return plan
+}
+
+// newExercisePlan starts with prescribed targets and weight of the last session, when it was recorded.
+func newExercisePlan(target WorkoutExercise, history []ExerciseSession) ExercisePlan {
+	plan := ExercisePlan{
+		WorkoutExerciseID: target.WorkoutExerciseID,
+		ExerciseID:        target.ExerciseID,
+		Sets:              target.Sets,
+		Repetitions:       target.Repetitions,
+	}
+	if target.Weight != nil {
+		weight := float64(*target.Weight)
+		plan.Weight = &weight
+	}
+	if len(history) > 0 {
+		if weight := history[0].TopWeight(); weight > 0 {
+			plan.Weight = &weight
+		}
+	}
+	return plan
+}
+
+func weightOf(plan ExercisePlan) float64 {
+	if plan.Weight == nil {
+		return 0
+	}
+	return *plan.Weight
+}
+
+func roundWeight(weight float64) *float64 {
+	rounded := max(math.Round(weight/weightIncrement)*weightIncrement, weightIncrement)
+	return &rounded
+}
+
+func formatWeight(weight float64) string {
+	return strconv.FormatFloat(weight, 'f', -1, 64) + "kg"
+}
+
+func atWeight(weight *float64) string {
+	if weight == nil {
+		return ""
+	}
+	return " at " + formatWeight(*weight)
+}
+
+func times(n int) string {
+	switch n {
+	case 1:
+		return "once"
+	case 2:
+		return "twice"
+	default:
+		return fmt.Sprintf("%d times", n)
+	}
+}
+
+func FromExerciseSetResultProto(proto *workout.ExerciseSetResult) ExerciseSet {
+	return ExerciseSet{
+		ExerciseID:  proto.ExerciseId,
+		Set:         proto.Set,
+		Repetitions: proto.Repetitions,
+		Weight:      proto.Weight,
+		RPE:         proto.Rpe,
+	}
+}
+
+func (p ExercisePlan) ToProto() *workout.ExercisePlan {
+	return &workout.ExercisePlan{
+		WorkoutExerciseId: p.WorkoutExerciseID,
+		ExerciseId:        p.ExerciseID,
+		Sets:              p.Sets,
+		Repetitions:       p.Repetitions,
+		Weight:            p.Weight,
+		Explanation:       p.Explanation,
+	}
+}
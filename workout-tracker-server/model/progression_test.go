@@ -0,0 +1,94 @@
+package model
+
+import (
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"testing"
+)
+
+func TestLinearProgression(t *testing.T) {
+	target := WorkoutExercise{ExerciseID: "ex", Sets: 3, Repetitions: 8, Weight: proto.Int32(55)}
+	tests := []struct {
+		name        string
+		history     []ExerciseSession
+		weight      float64
+		explanation string
+	}{
+		{"no history", nil, 55, "no history, starting at prescribed targets"},
+		{"hit twice", []ExerciseSession{session(60, 8, 8, 8), session(60, 8, 8, 8)}, 62.5, "hit 3x8 twice at 60kg → 62.5kg"},
+		{"hit once", []ExerciseSession{session(60, 8, 8, 8), session(57.5, 8, 8, 8)}, 60, "hit 3x8 once at 60kg, repeat"},
+		{"missed twice", []ExerciseSession{session(60, 8, 7, 6), session(60, 8, 8)}, 55, "missed 3x8 twice at 60kg → 55kg"},
+		{"missed after hit", []ExerciseSession{session(60, 8, 7, 6), session(60, 8, 8, 8)}, 60, "missed 3x8 once at 60kg, repeat"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			//when
+			plan := LinearProgression{}.Suggest(target, tt.history)
+
+			//then
+			require.Equal(t, tt.weight, *plan.Weight)
+			require.Equal(t, int32(8), plan.Repetitions)
+			require.Equal(t, tt.explanation, plan.Explanation)
+		})
+	}
+}
+
+func TestLinearProgressionWithoutWeight(t *testing.T) {
+	target := WorkoutExercise{ExerciseID: "ex", Sets: 3, Repetitions: 10}
+	history := []ExerciseSession{session(0, 10, 10, 10), session(0, 10, 11, 10)}
+
+	plan := LinearProgression{}.Suggest(target, history)
+
+	require.Nil(t, plan.Weight)
+	require.Equal(t, int32(11), plan.Repetitions)
+	require.Equal(t, "hit 3x10 twice → 3x11", plan.Explanation)
+}
+
+func TestDoubleProgression(t *testing.T) {
+	target := WorkoutExercise{ExerciseID: "ex", Sets: 3, Repetitions: 8, Weight: proto.Int32(40)}
+	tests := []struct {
+		name        string
+		history     []ExerciseSession
+		weight      float64
+		reps        int32
+		explanation string
+	}{
+		{"within range", []ExerciseSession{session(40, 10, 9, 9)}, 40, 10, "hit 3x9 at 40kg → 3x10"},
+		{"top of range", []ExerciseSession{session(40, 12, 12, 12)}, 42.5, 8, "hit 3x12 at 40kg, top of 8-12 range → 42.5kg 3x8"},
+		{"missed", []ExerciseSession{session(40, 8, 6, 5)}, 40, 8, "missed 3x8 at 40kg, repeat"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			//when
+			plan := DoubleProgression{}.Suggest(target, tt.history)
+
+			//then
+			require.Equal(t, tt.weight, *plan.Weight)
+			require.Equal(t, tt.reps, plan.Repetitions)
+			require.Equal(t, tt.explanation, plan.Explanation)
+		})
+	}
+}
+
+func TestRPEProgression(t *testing.T) {
+	target := WorkoutExercise{ExerciseID: "ex", Sets: 3, Repetitions: 5, Weight: proto.Int32(100)}
+	easy, hard, noRPE := session(100, 5, 5, 5), session(100, 5, 5, 5), session(100, 5, 5, 5)
+	easy.Sets[2].RPE = proto.Float64(6)
+	hard.Sets[2].RPE = proto.Float64(10)
+
+	require.Equal(t, "RPE 6 at 100kg against target 8 → 105kg", RPEProgression{}.Suggest(target, []ExerciseSession{easy}).Explanation)
+	require.Equal(t, "RPE 10 at 100kg against target 8 → 95kg", RPEProgression{}.Suggest(target, []ExerciseSession{hard}).Explanation)
+	require.Equal(t, "no RPE reported at 100kg, repeat", RPEProgression{}.Suggest(target, []ExerciseSession{noRPE}).Explanation)
+}
+
+func session(weight float64, reps ...int32) ExerciseSession {
+	var s ExerciseSession
+	for i, r := range reps {
+		set := ExerciseSet{ExerciseID: "ex", Set: int32(i + 1), Repetitions: r}
+		if weight > 0 {
+			set.Weight = proto.Float64(weight)
+		}
+		s.Sets = append(s.Sets, set)
+	}
+	return s
+}
@@ -0,0 +1,53 @@
+package model
+
+import (
+	"google.golang.org/protobuf/types/known/timestamppb"
+	workout "proto/workout/v1/generated"
+	"time"
+)
+
+type ReportBucket string
+
+const (
+	ReportBucketDay   ReportBucket = "day"
+	ReportBucketWeek  ReportBucket = "week"
+	ReportBucketMonth ReportBucket = "month"
+)
+
+func ReportBucketFromProto(bucket workout.ReportBucket) ReportBucket {
+	switch bucket {
+	case workout.ReportBucket_REPORT_BUCKET_WEEK:
+		return ReportBucketWeek
+	case workout.ReportBucket_REPORT_BUCKET_MONTH:
+		return ReportBucketMonth
+	default:
+		return ReportBucketDay
+	}
+}
+
+type ProgressReportEntry struct {
+	PeriodStart        time.Time
+	TotalVolume        float64
+	SetsPerMuscleGroup map[string]int32
+	SessionCount       int32
+	ScheduledCount     int32
+}
+
+// CompletionRate is the ratio of completed to scheduled workouts within the period.
+func (e ProgressReportEntry) CompletionRate() float64 {
+	if e.ScheduledCount == 0 {
+		return 0
+	}
+	return float64(e.SessionCount) / float64(e.ScheduledCount)
+}
+
+func (e ProgressReportEntry) ToProto() *workout.ProgressReportEntry {
+	return &workout.ProgressReportEntry{
+		PeriodStart:        timestamppb.New(e.PeriodStart),
+		TotalVolume:        e.TotalVolume,
+		SetsPerMuscleGroup: e.SetsPerMuscleGroup,
+		SessionCount:       e.SessionCount,
+		ScheduledCount:     e.ScheduledCount,
+		CompletionRate:     e.CompletionRate(),
+	}
+}
@@ -1,7 +1,14 @@
 package model
 
 import (
+	"fmt"
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	workout "proto/workout/v1/generated"
+	"slices"
+	"strings"
 	"time"
+	"workout-tracker-server/rrule"
 )
 
 type WorkoutSchedule struct {
@@ -9,6 +16,297 @@ type WorkoutSchedule struct {
 	OwnerID     string
 	WorkoutID   string
 	ScheduledAt time.Time
+	// Timezone is IANA timezone the workout was scheduled in, empty means UTC.
+	Timezone    string
 	CreatedAt   time.Time
 	Completed   bool
+	CompletedAt *time.Time
+	// SeriesID and RecurrenceID are set for occurrences of recurring series.
+	SeriesID     *string
+	RecurrenceID *time.Time
+	CancelledAt  *time.Time
+	CancelReason *string
+	MissedAt     *time.Time
+	// WorkoutVersion is version of the workout the schedule is based on, nil for occurrences not stored yet.
+	WorkoutVersion *int32
+}
+
+// MissedGracePeriod is time after schedule time in which workout can be still completed before it's considered missed.
+const MissedGracePeriod = time.Hour
+
+type WorkoutScheduleStatus string
+
+const (
+	WorkoutScheduleStatusPending   WorkoutScheduleStatus = "pending"
+	WorkoutScheduleStatusCompleted WorkoutScheduleStatus = "completed"
+	WorkoutScheduleStatusMissed    WorkoutScheduleStatus = "missed"
+	WorkoutScheduleStatusCancelled WorkoutScheduleStatus = "cancelled"
+)
+
+var workoutScheduleStatusProto = map[WorkoutScheduleStatus]workout.WorkoutScheduleStatus{
+	WorkoutScheduleStatusPending:   workout.WorkoutScheduleStatus_WORKOUT_SCHEDULE_STATUS_PENDING,
+	WorkoutScheduleStatusCompleted: workout.WorkoutScheduleStatus_WORKOUT_SCHEDULE_STATUS_COMPLETED,
+	WorkoutScheduleStatusMissed:    workout.WorkoutScheduleStatus_WORKOUT_SCHEDULE_STATUS_MISSED,
+	WorkoutScheduleStatusCancelled: workout.WorkoutScheduleStatus_WORKOUT_SCHEDULE_STATUS_CANCELLED,
+}
+
+func WorkoutScheduleStatusFromProto(proto workout.WorkoutScheduleStatus) WorkoutScheduleStatus {
+	for status, p := range workoutScheduleStatusProto {
+		if p == proto {
+			return status
+		}
+	}
+	return ""
+}
+
+func (s WorkoutScheduleStatus) ToProto() workout.WorkoutScheduleStatus {
+	return workoutScheduleStatusProto[s]
+}
+
+// WorkoutScheduleFilter narrows listed workout schedules, empty statuses and nil bounds are not applied.
+type WorkoutScheduleFilter struct {
+	Statuses []WorkoutScheduleStatus
+	From     *time.Time
+	To       *time.Time
+	// After is exclusive position to continue listing from.
+	After *WorkoutScheduleCursor
+	// Now separates pending schedules from missed ones.
+	Now   time.Time
+	Limit int
+}
+
+// MissedBefore returns time before which not completed workout schedules are missed.
+func (f WorkoutScheduleFilter) MissedBefore() time.Time {
+	return f.Now.Add(-MissedGracePeriod)
+}
+
+// WorkoutScheduleCursor is position within workout schedules sorted by schedule time and sort key.
+type WorkoutScheduleCursor struct {
+	ScheduledAt time.Time
+	Key         string
+}
+
+// Status derives workout schedule status, cancellation and completion are exclusive. Schedule is missed once it's
+// marked by background job or grace period has passed, whichever comes first.
+func (ws WorkoutSchedule) Status(now time.Time) WorkoutScheduleStatus {
+	switch {
+	case ws.CancelledAt != nil:
+		return WorkoutScheduleStatusCancelled
+	case ws.Completed:
+		return WorkoutScheduleStatusCompleted
+	case ws.MissedAt != nil || ws.ScheduledAt.Before(now.Add(-MissedGracePeriod)):
+		return WorkoutScheduleStatusMissed
+	default:
+		return WorkoutScheduleStatusPending
+	}
+}
+
+// SortKey breaks ties between workout schedules at the same time. Occurrences of series that are not stored yet
+// get key derived from series id and recurrence id, so it's stable between calls.
+func (ws WorkoutSchedule) SortKey() string {
+	if ws.ID != "" || ws.SeriesID == nil || ws.RecurrenceID == nil {
+		return ws.ID
+	}
+	return uuid.NewSHA1(uuid.Nil, []byte(*ws.SeriesID+"/"+ws.RecurrenceID.UTC().Format(time.RFC3339Nano))).String()
+}
+
+// IsAfter checks whether workout schedule is positioned after given cursor.
+func (ws WorkoutSchedule) IsAfter(cursor WorkoutScheduleCursor) bool {
+	if !ws.ScheduledAt.Equal(cursor.ScheduledAt) {
+		return ws.ScheduledAt.After(cursor.ScheduledAt)
+	}
+	return ws.SortKey() > cursor.Key
+}
+
+func (ws WorkoutSchedule) Cursor() WorkoutScheduleCursor {
+	return WorkoutScheduleCursor{ScheduledAt: ws.ScheduledAt, Key: ws.SortKey()}
+}
+
+// CompareWorkoutSchedules orders workout schedules by schedule time and sort key.
+func CompareWorkoutSchedules(ws1, ws2 WorkoutSchedule) int {
+	if c := ws1.ScheduledAt.Compare(ws2.ScheduledAt); c != 0 {
+		return c
+	}
+	return strings.Compare(ws1.SortKey(), ws2.SortKey())
+}
+
+// WorkoutScheduleSeries is recurring workout schedule. Its occurrences are expanded on read, occurrence gets stored
+// as standalone WorkoutSchedule (detached) only when it's edited or completed.
+type WorkoutScheduleSeries struct {
+	ID        string
+	OwnerID   string
+	WorkoutID string
+	StartsAt  time.Time
+	// Timezone is IANA timezone in which occurrences keep wall-clock time of StartsAt, empty means UTC.
+	Timezone      string
+	Recurrence    rrule.Rule
+	ExcludedDates []time.Time
+	CreatedAt     time.Time
+	// DetachedDates are recurrence ids of occurrences stored as standalone workout schedules.
+	DetachedDates []time.Time
+}
+
+// LocalStartsAt returns series start in its timezone, recurrence is expanded from it so occurrences keep the same
+// wall-clock time across DST transitions.
+func (s WorkoutScheduleSeries) LocalStartsAt() (time.Time, error) {
+	loc, err := Location(s.Timezone)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return s.StartsAt.In(loc), nil
+}
+
+// Location resolves IANA timezone, empty timezone resolves to UTC. Unknown timezone is an error, times are never
+// silently moved to UTC.
+func Location(timezone string) (*time.Location, error) {
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("unknown timezone %q", timezone)
+	}
+	return loc, nil
+}
+
+// TimezoneName returns name of IANA timezone, empty timezone is UTC.
+func TimezoneName(timezone string) string {
+	if timezone == "" {
+		return time.UTC.String()
+	}
+	return timezone
+}
+
+// OccurrencesBetween expands series into workout schedules within [from, to] range. Excluded and detached
+// occurrences are skipped, returned schedules have no ID.
+func (s WorkoutScheduleSeries) OccurrencesBetween(from, to time.Time) ([]WorkoutSchedule, error) {
+	return s.Occurrences(from, to, nil, 0)
+}
+
+// Occurrences expands series into workout schedules within [from, to] range, positioned after given cursor
+// when it's not nil. Zero to means no upper bound, expansion stops once limit is reached (zero means no limit).
+func (s WorkoutScheduleSeries) Occurrences(from, to time.Time, after *WorkoutScheduleCursor, limit int) ([]WorkoutSchedule, error) {
+	startsAt, err := s.LocalStartsAt()
+	if err != nil {
+		return nil, err
+	}
+	var schedules []WorkoutSchedule
+	for occurrence := range s.Recurrence.All(startsAt) {
+		occurrence = occurrence.UTC()
+		if (!to.IsZero() && occurrence.After(to)) || (limit > 0 && len(schedules) >= limit) {
+			break
+		}
+		if occurrence.Before(from) || slices.ContainsFunc(s.ExcludedDates, occurrence.Equal) ||
+			slices.ContainsFunc(s.DetachedDates, occurrence.Equal) {
+			continue
+		}
+		recurrenceId := occurrence
+		ws := WorkoutSchedule{
+			OwnerID:      s.OwnerID,
+			WorkoutID:    s.WorkoutID,
+			ScheduledAt:  occurrence,
+			Timezone:     s.Timezone,
+			CreatedAt:    s.CreatedAt,
+			SeriesID:     &s.ID,
+			RecurrenceID: &recurrenceId,
+		}
+		if after != nil && !ws.IsAfter(*after) {
+			continue
+		}
+		schedules = append(schedules, ws)
+	}
+	return schedules, nil
+}
+
+// ContinuationFrom returns new series continuing this one from occurrence at recurrenceId, moved to scheduleAt.
+// All following occurrences, weekdays and month days of the rule are moved by the same number of days and the same
+// wall-clock offset in the series timezone.
+func (s WorkoutScheduleSeries) ContinuationFrom(recurrenceId, scheduleAt time.Time) (WorkoutScheduleSeries, error) {
+	loc, err := Location(s.Timezone)
+	if err != nil {
+		return WorkoutScheduleSeries{}, err
+	}
+	recurrenceId, scheduleAt = recurrenceId.In(loc), scheduleAt.In(loc)
+	days := daysBetween(recurrenceId, scheduleAt)
+	clockOffset := timeOfDay(scheduleAt) - timeOfDay(recurrenceId)
+	rule := s.Recurrence.From(s.Recurrence.Index(s.StartsAt.In(loc), recurrenceId)).ShiftDays(days)
+	if !rule.Until.IsZero() {
+		rule.Until = shiftWallClock(rule.Until, days, clockOffset, loc)
+	}
+	var excludedDates []time.Time
+	for _, excluded := range s.ExcludedDates {
+		if excluded.After(recurrenceId) {
+			excludedDates = append(excludedDates, shiftWallClock(excluded, days, clockOffset, loc))
+		}
+	}
+	return WorkoutScheduleSeries{
+		OwnerID:       s.OwnerID,
+		WorkoutID:     s.WorkoutID,
+		StartsAt:      scheduleAt.UTC(),
+		Timezone:      s.Timezone,
+		Recurrence:    rule,
+		ExcludedDates: excludedDates,
+	}, nil
+}
+
+// daysBetween returns number of calendar days between dates of given times, in location of the first one.
+func daysBetween(from, to time.Time) int {
+	to = to.In(from.Location())
+	fromDate := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	toDate := time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+	return int(toDate.Sub(fromDate).Hours() / 24)
+}
+
+// shiftWallClock moves time by number of calendar days and by offset of wall-clock time in given location, so the
+// result keeps local time of day across DST transitions.
+func shiftWallClock(t time.Time, days int, clockOffset time.Duration, loc *time.Location) time.Time {
+	t = t.In(loc)
+	return time.Date(t.Year(), t.Month(), t.Day()+days, 0, 0, 0, int(timeOfDay(t)+clockOffset), loc).UTC()
+}
+
+// timeOfDay returns wall-clock time elapsed since midnight in location of given time.
+func timeOfDay(t time.Time) time.Duration {
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute +
+		time.Duration(t.Second())*time.Second + time.Duration(t.Nanosecond())
+}
+
+func (ws WorkoutSchedule) ToProto(now time.Time) *workout.WorkoutSchedule {
+	timezone := TimezoneName(ws.Timezone)
+	proto := &workout.WorkoutSchedule{
+		Id:         ws.ID,
+		WorkoutId:  ws.WorkoutID,
+		ScheduleAt: timestamppb.New(ws.ScheduledAt),
+		Timezone:   &timezone,
+		CreatedAt:  timestamppb.New(ws.CreatedAt),
+		Completed:  ws.Completed,
+		Status:     ws.Status(now).ToProto(),
+	}
+	if ws.CompletedAt != nil {
+		proto.CompletedAt = timestamppb.New(*ws.CompletedAt)
+	}
+	if ws.SeriesID != nil {
+		proto.SeriesId = *ws.SeriesID
+	}
+	if ws.RecurrenceID != nil {
+		proto.RecurrenceId = timestamppb.New(*ws.RecurrenceID)
+	}
+	if ws.CancelledAt != nil {
+		proto.CancelledAt = timestamppb.New(*ws.CancelledAt)
+	}
+	proto.CancelReason = ws.CancelReason
+	if ws.MissedAt != nil {
+		proto.MissedAt = timestamppb.New(*ws.MissedAt)
+	}
+	proto.WorkoutVersion = ws.WorkoutVersion
+	return proto
+}
+
+// ToListItemProto converts workout schedule into listing item with embedded workout summary.
+func (ws WorkoutSchedule) ToListItemProto(now time.Time, summary WorkoutSummary) *workout.WorkoutScheduleListItem {
+	item := &workout.WorkoutScheduleListItem{
+		WorkoutSchedule: ws.ToProto(now),
+		Status:          ws.Status(now).ToProto(),
+		WorkoutName:     summary.Name,
+	}
+	for _, ex := range summary.Exercises {
+		item.Exercises = append(item.Exercises, ex.ToProto())
+	}
+	return item
 }
@@ -0,0 +1,163 @@
+package model
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+	"workout-tracker-server/rrule"
+)
+
+func TestOccurrencesBetweenSkipsExcludedAndDetached(t *testing.T) {
+	//given series every day starting monday with tuesday excluded and wednesday detached
+	monday := time.Date(2024, 1, 15, 7, 0, 0, 0, time.UTC)
+	series := WorkoutScheduleSeries{
+		ID:            "series",
+		WorkoutID:     "workout",
+		StartsAt:      monday,
+		Recurrence:    rrule.Rule{Freq: rrule.Daily, Interval: 1},
+		ExcludedDates: []time.Time{monday.AddDate(0, 0, 1)},
+		DetachedDates: []time.Time{monday.AddDate(0, 0, 2)},
+	}
+
+	//when
+	schedules, err := series.OccurrencesBetween(monday, monday.AddDate(0, 0, 3))
+
+	//then
+	require.NoError(t, err)
+	require.Len(t, schedules, 2)
+	require.Equal(t, monday, schedules[0].ScheduledAt)
+	require.Equal(t, monday.AddDate(0, 0, 3), schedules[1].ScheduledAt)
+	require.Equal(t, "series", *schedules[1].SeriesID)
+	require.Equal(t, monday.AddDate(0, 0, 3), *schedules[1].RecurrenceID)
+	require.Empty(t, schedules[1].ID)
+}
+
+func TestContinuationFromShiftsFollowingOccurrences(t *testing.T) {
+	//given series every monday and thursday, limited to 6 occurrences, with second thursday excluded
+	monday := time.Date(2024, 1, 15, 7, 0, 0, 0, time.UTC)
+	series := WorkoutScheduleSeries{
+		StartsAt:      monday,
+		Recurrence:    rrule.Rule{Freq: rrule.Weekly, Interval: 1, ByDay: []time.Weekday{time.Monday, time.Thursday}, Count: 6},
+		ExcludedDates: []time.Time{monday.AddDate(0, 0, 10)},
+	}
+
+	//when first thursday (second occurrence) is moved to friday 8:00
+	thursday := monday.AddDate(0, 0, 3)
+	continuation, err := series.ContinuationFrom(thursday, thursday.Add(25*time.Hour))
+
+	//then
+	require.NoError(t, err)
+	require.Equal(t, thursday.Add(25*time.Hour), continuation.StartsAt)
+	require.Equal(t, []time.Weekday{time.Tuesday, time.Friday}, continuation.Recurrence.ByDay)
+	require.Equal(t, 5, continuation.Recurrence.Count)
+	require.Equal(t, []time.Time{monday.AddDate(0, 0, 11).Add(time.Hour)}, continuation.ExcludedDates)
+}
+
+func TestOccurrencesKeepWallClockTimeAcrossDST(t *testing.T) {
+	//given weekly series at 7:00 in Warsaw, DST starts on 2026-03-29
+	warsaw, err := time.LoadLocation("Europe/Warsaw")
+	require.NoError(t, err)
+	monday := time.Date(2026, 3, 23, 7, 0, 0, 0, warsaw)
+	series := WorkoutScheduleSeries{
+		StartsAt:   monday.UTC(),
+		Timezone:   "Europe/Warsaw",
+		Recurrence: rrule.Rule{Freq: rrule.Weekly, Interval: 1},
+	}
+
+	//when
+	schedules, err := series.OccurrencesBetween(monday, monday.AddDate(0, 0, 7))
+
+	//then
+	require.NoError(t, err)
+	require.Len(t, schedules, 2)
+	require.Equal(t, time.Date(2026, 3, 23, 6, 0, 0, 0, time.UTC), schedules[0].ScheduledAt)
+	require.Equal(t, time.Date(2026, 3, 30, 5, 0, 0, 0, time.UTC), schedules[1].ScheduledAt)
+	require.Equal(t, "Europe/Warsaw", schedules[1].Timezone)
+}
+
+func TestContinuationFromKeepsWallClockTimeAcrossDST(t *testing.T) {
+	//given daily series at 7:00 in Warsaw with an occurrence excluded after DST starts on 2026-03-29
+	warsaw, err := time.LoadLocation("Europe/Warsaw")
+	require.NoError(t, err)
+	start := time.Date(2026, 3, 27, 7, 0, 0, 0, warsaw)
+	series := WorkoutScheduleSeries{
+		StartsAt:      start.UTC(),
+		Timezone:      "Europe/Warsaw",
+		Recurrence:    rrule.Rule{Freq: rrule.Daily, Interval: 1},
+		ExcludedDates: []time.Time{time.Date(2026, 3, 30, 7, 0, 0, 0, warsaw).UTC()},
+	}
+
+	//when second occurrence is moved to 8:00
+	recurrenceId := start.AddDate(0, 0, 1)
+	continuation, err := series.ContinuationFrom(recurrenceId, recurrenceId.Add(time.Hour))
+
+	//then excluded occurrence is moved to 8:00 local time as well
+	require.NoError(t, err)
+	require.Equal(t, "Europe/Warsaw", continuation.Timezone)
+	require.Equal(t, []time.Time{time.Date(2026, 3, 30, 8, 0, 0, 0, warsaw).UTC()}, continuation.ExcludedDates)
+}
+
+func TestOccurrencesWithUnknownTimezone(t *testing.T) {
+	//given
+	monday := time.Date(2024, 1, 15, 7, 0, 0, 0, time.UTC)
+	series := WorkoutScheduleSeries{
+		StartsAt:   monday,
+		Timezone:   "Mars/Olympus",
+		Recurrence: rrule.Rule{Freq: rrule.Daily, Interval: 1},
+	}
+
+	//when
+	schedules, err := series.OccurrencesBetween(monday, monday.AddDate(0, 0, 3))
+
+	//then
+	require.Error(t, err)
+	require.Nil(t, schedules)
+}
+
+func TestStatus(t *testing.T) {
+	now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	cancelledAt := now
+	missedAt := now
+	testCases := []struct {
+		name     string
+		schedule WorkoutSchedule
+		expected WorkoutScheduleStatus
+	}{
+		{"Pending", WorkoutSchedule{ScheduledAt: now.Add(time.Hour)}, WorkoutScheduleStatusPending},
+		{"PendingWithinGracePeriod", WorkoutSchedule{ScheduledAt: now.Add(-30 * time.Minute)}, WorkoutScheduleStatusPending},
+		{"Missed", WorkoutSchedule{ScheduledAt: now.Add(-2 * time.Hour)}, WorkoutScheduleStatusMissed},
+		{"MarkedMissed", WorkoutSchedule{ScheduledAt: now.Add(-30 * time.Minute), MissedAt: &missedAt}, WorkoutScheduleStatusMissed},
+		{"CompletedAfterMissed", WorkoutSchedule{ScheduledAt: now.Add(-2 * time.Hour), MissedAt: &missedAt, Completed: true}, WorkoutScheduleStatusCompleted},
+		{"Completed", WorkoutSchedule{ScheduledAt: now.Add(-time.Hour), Completed: true}, WorkoutScheduleStatusCompleted},
+		{"Cancelled", WorkoutSchedule{ScheduledAt: now.Add(-time.Hour), CancelledAt: &cancelledAt}, WorkoutScheduleStatusCancelled},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			require.Equal(t, testCase.expected, testCase.schedule.Status(now))
+		})
+	}
+}
+
+func TestOccurrencesAfterCursorWithLimit(t *testing.T) {
+	//given
+	monday := time.Date(2024, 1, 15, 7, 0, 0, 0, time.UTC)
+	series := WorkoutScheduleSeries{
+		ID:         "series",
+		StartsAt:   monday,
+		Recurrence: rrule.Rule{Freq: rrule.Daily, Interval: 1},
+	}
+	first, err := series.Occurrences(monday, time.Time{}, nil, 1)
+	require.NoError(t, err)
+	require.Len(t, first, 1)
+	cursor := first[0].Cursor()
+
+	//when
+	schedules, err := series.Occurrences(cursor.ScheduledAt, time.Time{}, &cursor, 2)
+
+	//then
+	require.NoError(t, err)
+	require.Len(t, schedules, 2)
+	require.Equal(t, monday.AddDate(0, 0, 1), schedules[0].ScheduledAt)
+	require.Equal(t, monday.AddDate(0, 0, 2), schedules[1].ScheduledAt)
+	require.Equal(t, cursor.Key, first[0].SortKey(), "sort key must be stable")
+}
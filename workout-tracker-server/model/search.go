@@ -0,0 +1,61 @@
+package model
+
+import workout "proto/workout/v1/generated"
+
+type SearchResultType string
+
+const (
+	SearchResultTypeWorkout         SearchResultType = "workout"
+	SearchResultTypeWorkoutExercise SearchResultType = "workout_exercise"
+	SearchResultTypeExercise        SearchResultType = "exercise"
+)
+
+var searchResultTypeProto = map[SearchResultType]workout.SearchResultType{
+	SearchResultTypeWorkout:         workout.SearchResultType_SEARCH_RESULT_TYPE_WORKOUT,
+	SearchResultTypeWorkoutExercise: workout.SearchResultType_SEARCH_RESULT_TYPE_WORKOUT_EXERCISE,
+	SearchResultTypeExercise:        workout.SearchResultType_SEARCH_RESULT_TYPE_EXERCISE,
+}
+
+func SearchResultTypeFromProto(proto workout.SearchResultType) SearchResultType {
+	for resultType, p := range searchResultTypeProto {
+		if p == proto {
+			return resultType
+		}
+	}
+	return ""
+}
+
+func (t SearchResultType) ToProto() workout.SearchResultType {
+	return searchResultTypeProto[t]
+}
+
+// SearchFilter narrows search results, empty types search all of them.
+type SearchFilter struct {
+	Query string
+	Types []SearchResultType
+	Limit int
+}
+
+type SearchResult struct {
+	Type SearchResultType
+	// ID is id of workout, workout exercise or exercise depending on the type.
+	ID string
+	// WorkoutID is set for workouts and workout exercises.
+	WorkoutID *string
+	// Title is name of the workout or of the exercise.
+	Title string
+	// Highlight is HTML-escaped fragment of the matched text with matched words enclosed in <b></b>.
+	Highlight string
+	Rank      float32
+}
+
+func (r SearchResult) ToProto() *workout.SearchResult {
+	return &workout.SearchResult{
+		Type:      r.Type.ToProto(),
+		Id:        r.ID,
+		WorkoutId: r.WorkoutID,
+		Title:     r.Title,
+		Highlight: r.Highlight,
+		Rank:      r.Rank,
+	}
+}
@@ -0,0 +1,58 @@
+package model
+
+import (
+	"google.golang.org/protobuf/types/known/timestamppb"
+	workout "proto/workout/v1/generated"
+	"time"
+)
+
+type SharePermission string
+
+const (
+	SharePermissionView SharePermission = "view"
+	SharePermissionCopy SharePermission = "copy"
+)
+
+var sharePermissionProto = map[SharePermission]workout.SharePermission{
+	SharePermissionView: workout.SharePermission_SHARE_PERMISSION_VIEW,
+	SharePermissionCopy: workout.SharePermission_SHARE_PERMISSION_COPY,
+}
+
+func SharePermissionFromProto(proto workout.SharePermission) SharePermission {
+	for permission, p := range sharePermissionProto {
+		if p == proto {
+			return permission
+		}
+	}
+	return ""
+}
+
+// WorkoutShare grants read-only access to workout, either to user of RecipientEmail or to anyone with link token.
+type WorkoutShare struct {
+	ID             string
+	WorkoutID      string
+	OwnerID        string
+	RecipientEmail *string
+	// RecipientID is user the email resolved to when the share was created.
+	RecipientID *string
+	Permission  SharePermission
+	CreatedAt   time.Time
+	// WorkoutName is loaded for received shares.
+	WorkoutName string
+}
+
+// AllowsCopy reports whether recipient can import the workout as their own.
+func (s WorkoutShare) AllowsCopy() bool {
+	return s.Permission == SharePermissionCopy
+}
+
+func (s WorkoutShare) ToProto() *workout.WorkoutShare {
+	return &workout.WorkoutShare{
+		Id:             s.ID,
+		WorkoutId:      s.WorkoutID,
+		RecipientEmail: s.RecipientEmail,
+		Permission:     sharePermissionProto[s.Permission],
+		CreatedAt:      timestamppb.New(s.CreatedAt),
+		WorkoutName:    s.WorkoutName,
+	}
+}
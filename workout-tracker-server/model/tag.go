@@ -0,0 +1,61 @@
+package model
+
+import (
+	"google.golang.org/protobuf/types/known/timestamppb"
+	workout "proto/workout/v1/generated"
+	"slices"
+	"strings"
+	"time"
+)
+
+type Tag struct {
+	ID      string
+	OwnerID string
+	Name    string
+	// WorkoutCount is number of workouts with the tag, deleted workouts are not counted.
+	WorkoutCount int32
+}
+
+type Folder struct {
+	ID        string
+	OwnerID   string
+	Name      string
+	CreatedAt time.Time
+	// WorkoutCount is number of workouts in the folder, deleted workouts are not counted.
+	WorkoutCount int32
+}
+
+// WorkoutFilter narrows listed workouts, workout must have all the tags.
+type WorkoutFilter struct {
+	Tags     []string
+	FolderID *string
+}
+
+// NormalizeTags trims tag names and returns them sorted without duplicates and blank names.
+func NormalizeTags(tags []string) []string {
+	var normalized []string
+	for _, tag := range tags {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			normalized = append(normalized, tag)
+		}
+	}
+	slices.Sort(normalized)
+	return slices.Compact(normalized)
+}
+
+func (t Tag) ToProto() *workout.Tag {
+	return &workout.Tag{
+		Id:           t.ID,
+		Name:         t.Name,
+		WorkoutCount: t.WorkoutCount,
+	}
+}
+
+func (f Folder) ToProto() *workout.Folder {
+	return &workout.Folder{
+		Id:           f.ID,
+		Name:         f.Name,
+		CreatedAt:    timestamppb.New(f.CreatedAt),
+		WorkoutCount: f.WorkoutCount,
+	}
+}
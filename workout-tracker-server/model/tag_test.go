@@ -0,0 +1,11 @@
+package model
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	require.Equal(t, []string{"heavy", "push"}, NormalizeTags([]string{" push", "heavy", "push ", " "}))
+	require.Nil(t, NormalizeTags(nil))
+}
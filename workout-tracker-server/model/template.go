@@ -0,0 +1,32 @@
+package model
+
+import workout "proto/workout/v1/generated"
+
+// SystemTemplateOwnerID owns system-provided templates, they are available to all users.
+const SystemTemplateOwnerID = "00000000-0000-0000-0000-000000000000"
+
+// WorkoutCopy describes workout created by deep copy of another workout or template.
+type WorkoutCopy struct {
+	OwnerID  string
+	Name     string
+	Template bool
+	// SourceTemplateID overrides source template of the original when set.
+	SourceTemplateID *string
+}
+
+// IsSystemTemplate reports whether the workout is system-provided template.
+func (w Workout) IsSystemTemplate() bool {
+	return w.Template && w.OwnerID == SystemTemplateOwnerID
+}
+
+// IsVisibleTemplate reports whether the workout is template available to given user.
+func (w Workout) IsVisibleTemplate(userId string) bool {
+	return w.Template && (w.OwnerID == userId || w.OwnerID == SystemTemplateOwnerID)
+}
+
+func (w Workout) ToTemplateProto() *workout.WorkoutTemplate {
+	return &workout.WorkoutTemplate{
+		Workout: w.ToProto(),
+		System:  w.IsSystemTemplate(),
+	}
+}
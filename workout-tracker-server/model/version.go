@@ -0,0 +1,26 @@
+package model
+
+import (
+	"google.golang.org/protobuf/types/known/timestamppb"
+	workout "proto/workout/v1/generated"
+	"time"
+)
+
+// WorkoutVersion is snapshot of workout taken after its creation or update.
+type WorkoutVersion struct {
+	Version   int32
+	CreatedAt time.Time
+	// Workout is nil when versions are listed.
+	Workout *Workout
+}
+
+func (v WorkoutVersion) ToProto() *workout.WorkoutVersion {
+	proto := &workout.WorkoutVersion{
+		Version:   v.Version,
+		CreatedAt: timestamppb.New(v.CreatedAt),
+	}
+	if v.Workout != nil {
+		proto.Workout = v.Workout.ToProto()
+	}
+	return proto
+}
@@ -0,0 +1,50 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+)
+
+// sharedAddressSpace is carrier-grade NAT range, some clouds serve instance metadata from it.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// ParseWebhookURL accepts only absolute https urls, reminders are never posted over plain http.
+func ParseWebhookURL(rawURL string) (*url.URL, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid webhook url")
+	}
+	if u.Scheme != "https" || u.Hostname() == "" {
+		return nil, fmt.Errorf("webhook url must be absolute https url")
+	}
+	return u, nil
+}
+
+// ValidateWebhookURL checks that webhook url is https url which host resolves only to public addresses.
+// Addresses are checked again when reminder is posted, since DNS records may change in the meantime.
+func ValidateWebhookURL(ctx context.Context, rawURL string) error {
+	u, err := ParseWebhookURL(rawURL)
+	if err != nil {
+		return err
+	}
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", u.Hostname())
+	if err != nil {
+		return fmt.Errorf("webhook host %s can't be resolved", u.Hostname())
+	}
+	for _, addr := range addrs {
+		if !IsPublicAddress(addr) {
+			return fmt.Errorf("webhook host %s must not resolve to loopback, private or link-local address", u.Hostname())
+		}
+	}
+	return nil
+}
+
+// IsPublicAddress reports whether webhooks may be posted to the address. Loopback, private, link-local (including
+// cloud metadata endpoint 169.254.169.254), shared, multicast and unspecified addresses are not public.
+func IsPublicAddress(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsGlobalUnicast() && !addr.IsPrivate() && !sharedAddressSpace.Contains(addr)
+}
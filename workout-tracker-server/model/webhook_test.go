@@ -0,0 +1,45 @@
+package model
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"net/netip"
+	"testing"
+)
+
+func TestIsPublicAddress(t *testing.T) {
+	for addr, public := range map[string]bool{
+		"93.184.216.34":        true,
+		"2606:2800:220:1::":    true,
+		"127.0.0.1":            false,
+		"::1":                  false,
+		"10.1.2.3":             false,
+		"172.16.0.1":           false,
+		"192.168.1.1":          false,
+		"169.254.169.254":      false,
+		"100.100.100.200":      false,
+		"fd00:ec2::254":        false,
+		"fe80::1":              false,
+		"0.0.0.0":              false,
+		"::ffff:127.0.0.1":     false,
+		"::ffff:93.184.216.34": true,
+		"224.0.0.1":            false,
+	} {
+		require.Equal(t, public, IsPublicAddress(netip.MustParseAddr(addr)), addr)
+	}
+}
+
+func TestParseWebhookURL(t *testing.T) {
+	_, err := ParseWebhookURL("https://hooks.example.com/reminders")
+	require.NoError(t, err)
+	_, err = ParseWebhookURL("http://hooks.example.com/reminders")
+	require.Error(t, err)
+	_, err = ParseWebhookURL("https:///reminders")
+	require.Error(t, err)
+}
+
+func TestValidateWebhookURLRejectsPrivateAddress(t *testing.T) {
+	require.Error(t, ValidateWebhookURL(context.Background(), "https://127.0.0.1/reminders"))
+	require.Error(t, ValidateWebhookURL(context.Background(), "https://[::1]:8443/reminders"))
+	require.Error(t, ValidateWebhookURL(context.Background(), "https://169.254.169.254/latest/meta-data"))
+}
@@ -1,7 +1,13 @@
 package model
 
 import (
+	"fmt"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
 	workout "proto/workout/v1/generated"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type Workout struct {
@@ -10,8 +16,24 @@ type Workout struct {
 	Name      string
 	Comment   *string
 	Exercises []WorkoutExercise
+	Groups    []ExerciseGroup
+	// Template workouts are instantiated into workouts of users, they are not listed nor scheduled.
+	Template bool
+	// SourceTemplateID is template the workout was instantiated from, kept for lineage.
+	SourceTemplateID *string
+	// Version is incremented with every update, each version is kept in workout history.
+	Version int32
+	// DeletedAt is set when workout is in trash.
+	DeletedAt *time.Time
+	// FolderID is folder of the owner the workout is filed in.
+	FolderID *string
+	// Tags are names of owner's tags, sorted by name.
+	Tags []string
 }
 
+// WorkoutDeletedCancelReason is cancel reason of pending schedules cancelled by deletion of their workout.
+const WorkoutDeletedCancelReason = "workout deleted"
+
 type WorkoutExercise struct {
 	WorkoutExerciseID string
 	ExerciseID        string
@@ -20,6 +42,106 @@ type WorkoutExercise struct {
 	Sets              int32
 	Weight            *int32
 	Comment           *string
+	// GroupLabel is label of the exercise group within workout, nil for exercises outside of groups.
+	GroupLabel *string
+	Rest       Rest
+}
+
+// Rest is rest prescription of workout exercise, rest not set by the exercise is taken from the owner's profile.
+type Rest struct {
+	// Seconds is rest between sets.
+	Seconds *int32
+	// SetSeconds overrides rest after individual sets, n-th item is rest after n-th set.
+	SetSeconds []int32
+	// Tempo is in eccentric-pause-concentric-pause notation, e.g. 3-1-1-0.
+	Tempo *string
+}
+
+type ExerciseGroupType string
+
+const (
+	ExerciseGroupTypeStraight ExerciseGroupType = "straight"
+	ExerciseGroupTypeSuperset ExerciseGroupType = "superset"
+	ExerciseGroupTypeCircuit  ExerciseGroupType = "circuit"
+	ExerciseGroupTypeEMOM     ExerciseGroupType = "emom"
+	ExerciseGroupTypeAMRAP    ExerciseGroupType = "amrap"
+)
+
+var exerciseGroupTypeProto = map[ExerciseGroupType]workout.ExerciseGroupType{
+	ExerciseGroupTypeStraight: workout.ExerciseGroupType_EXERCISE_GROUP_TYPE_STRAIGHT,
+	ExerciseGroupTypeSuperset: workout.ExerciseGroupType_EXERCISE_GROUP_TYPE_SUPERSET,
+	ExerciseGroupTypeCircuit:  workout.ExerciseGroupType_EXERCISE_GROUP_TYPE_CIRCUIT,
+	ExerciseGroupTypeEMOM:     workout.ExerciseGroupType_EXERCISE_GROUP_TYPE_EMOM,
+	ExerciseGroupTypeAMRAP:    workout.ExerciseGroupType_EXERCISE_GROUP_TYPE_AMRAP,
+}
+
+func ExerciseGroupTypeFromProto(proto workout.ExerciseGroupType) ExerciseGroupType {
+	for groupType, p := range exerciseGroupTypeProto {
+		if p == proto {
+			return groupType
+		}
+	}
+	return ""
+}
+
+// ExerciseGroup is group of workout exercises performed together, e.g. superset or circuit.
+type ExerciseGroup struct {
+	GroupID           string
+	Label             string
+	Type              ExerciseGroupType
+	Rounds            int32
+	RestBetweenRounds time.Duration
+}
+
+// ValidateGroups checks that group labels are unique and that exercises reference only groups of the workout.
+func (w Workout) ValidateGroups() error {
+	labels := make(map[string]bool, len(w.Groups))
+	for _, group := range w.Groups {
+		if labels[group.Label] {
+			return fmt.Errorf("duplicate group label %s", group.Label)
+		}
+		labels[group.Label] = true
+	}
+	for _, ex := range w.Exercises {
+		if ex.GroupLabel != nil && !labels[*ex.GroupLabel] {
+			return fmt.Errorf("unknown group label %s", *ex.GroupLabel)
+		}
+	}
+	return nil
+}
+
+// ValidateExercises checks rest and tempo prescriptions of workout exercises.
+func (w Workout) ValidateExercises() error {
+	for _, ex := range w.Exercises {
+		if len(ex.Rest.SetSeconds) >= int(max(ex.Sets, 1)) {
+			return fmt.Errorf("set rests of exercise %d must have at most %d items", ex.Order, max(ex.Sets, 1)-1)
+		}
+		if ex.Rest.Tempo != nil {
+			if _, err := ParseTempo(*ex.Rest.Tempo); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// WorkoutSummary is lightweight workout view embedded in workout schedule listing.
+type WorkoutSummary struct {
+	ID        string
+	Name      string
+	Exercises []ExerciseSummary
+	// RestDefaults are taken from the workout owner's profile.
+	RestDefaults RestDefaults
+}
+
+type ExerciseSummary struct {
+	ExerciseID  string
+	Name        string
+	MuscleGroup string
+	Sets        int32
+	Repetitions int32
+	Weight      *int32
+	Rest        Rest
 }
 
 func FromWorkoutExerciseProto(proto *workout.WorkoutExercise) WorkoutExercise {
@@ -31,6 +153,22 @@ func FromWorkoutExerciseProto(proto *workout.WorkoutExercise) WorkoutExercise {
 		Sets:              proto.Sets,
 		Weight:            proto.Weight,
 		Comment:           proto.Comment,
+		GroupLabel:        proto.GroupLabel,
+		Rest: Rest{
+			Seconds:    proto.RestSeconds,
+			SetSeconds: proto.SetRestSeconds,
+			Tempo:      proto.Tempo,
+		},
+	}
+}
+
+func FromExerciseGroupProto(proto *workout.ExerciseGroup) ExerciseGroup {
+	return ExerciseGroup{
+		GroupID:           proto.GroupId,
+		Label:             proto.Label,
+		Type:              ExerciseGroupTypeFromProto(proto.Type),
+		Rounds:            proto.Rounds,
+		RestBetweenRounds: proto.RestBetweenRounds.AsDuration(),
 	}
 }
 
@@ -39,11 +177,18 @@ func FromWorkoutProto(proto *workout.Workout) Workout {
 	for _, ex := range proto.Exercises {
 		exercises = append(exercises, FromWorkoutExerciseProto(ex))
 	}
+	var groups []ExerciseGroup
+	for _, group := range proto.Groups {
+		groups = append(groups, FromExerciseGroupProto(group))
+	}
 	return Workout{
 		ID:        proto.Id,
 		Name:      proto.Name,
 		Comment:   proto.Comment,
 		Exercises: exercises,
+		Groups:    groups,
+		FolderID:  proto.FolderId,
+		Tags:      NormalizeTags(proto.Tags),
 	}
 }
 
@@ -52,11 +197,51 @@ func (w Workout) ToProto() *workout.Workout {
 	for _, ex := range w.Exercises {
 		exercises = append(exercises, ex.toProto())
 	}
-	return &workout.Workout{
-		Id:        w.ID,
-		Name:      w.Name,
-		Comment:   w.Comment,
-		Exercises: exercises,
+	var groups []*workout.ExerciseGroup
+	for _, group := range w.Groups {
+		groups = append(groups, group.toProto())
+	}
+	proto := &workout.Workout{
+		Id:               w.ID,
+		Name:             w.Name,
+		Comment:          w.Comment,
+		Exercises:        exercises,
+		Groups:           groups,
+		SourceTemplateId: w.SourceTemplateID,
+		Version:          w.Version,
+		Etag:             WorkoutETag(w.Version),
+		FolderId:         w.FolderID,
+		Tags:             w.Tags,
+	}
+	if w.DeletedAt != nil {
+		proto.DeletedAt = timestamppb.New(*w.DeletedAt)
+	}
+	return proto
+}
+
+// WorkoutETag formats workout version as strong HTTP entity tag.
+func WorkoutETag(version int32) string {
+	return strconv.Quote(strconv.Itoa(int(version)))
+}
+
+// ParseWorkoutETag parses workout version from entity tag, quotes and weak prefix are optional as they are often
+// dropped by HTTP clients.
+func ParseWorkoutETag(etag string) (int32, error) {
+	value := strings.Trim(strings.TrimPrefix(strings.TrimSpace(etag), "W/"), `"`)
+	version, err := strconv.ParseInt(value, 10, 32)
+	if err != nil || version <= 0 {
+		return 0, fmt.Errorf("invalid etag: %s", etag)
+	}
+	return int32(version), nil
+}
+
+func (g ExerciseGroup) toProto() *workout.ExerciseGroup {
+	return &workout.ExerciseGroup{
+		GroupId:           g.GroupID,
+		Label:             g.Label,
+		Type:              exerciseGroupTypeProto[g.Type],
+		Rounds:            g.Rounds,
+		RestBetweenRounds: durationpb.New(g.RestBetweenRounds),
 	}
 }
 
@@ -69,5 +254,23 @@ func (w WorkoutExercise) toProto() *workout.WorkoutExercise {
 		Sets:              w.Sets,
 		Weight:            w.Weight,
 		Comment:           w.Comment,
+		GroupLabel:        w.GroupLabel,
+		RestSeconds:       w.Rest.Seconds,
+		SetRestSeconds:    w.Rest.SetSeconds,
+		Tempo:             w.Rest.Tempo,
+	}
+}
+
+func (e ExerciseSummary) ToProto() *workout.WorkoutExerciseSummary {
+	return &workout.WorkoutExerciseSummary{
+		ExerciseId:     e.ExerciseID,
+		Name:           e.Name,
+		MuscleGroup:    e.MuscleGroup,
+		Sets:           e.Sets,
+		Repetitions:    e.Repetitions,
+		Weight:         e.Weight,
+		RestSeconds:    e.Rest.Seconds,
+		SetRestSeconds: e.Rest.SetSeconds,
+		Tempo:          e.Rest.Tempo,
 	}
 }
@@ -0,0 +1,91 @@
+package model
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestValidateGroups(t *testing.T) {
+	a, b := "A", "B"
+	testCases := []struct {
+		name    string
+		workout Workout
+		valid   bool
+	}{
+		{"NoGroups", Workout{Exercises: []WorkoutExercise{{}}}, true},
+		{
+			"ExercisesReferenceGroups",
+			Workout{
+				Groups:    []ExerciseGroup{{Label: a}, {Label: b}},
+				Exercises: []WorkoutExercise{{GroupLabel: &a}, {GroupLabel: &a}, {}},
+			},
+			true,
+		},
+		{"DuplicateLabel", Workout{Groups: []ExerciseGroup{{Label: a}, {Label: a}}}, false},
+		{
+			"UnknownLabel",
+			Workout{Groups: []ExerciseGroup{{Label: a}}, Exercises: []WorkoutExercise{{GroupLabel: &b}}},
+			false,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.workout.ValidateGroups()
+			if tc.valid {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestValidateExercises(t *testing.T) {
+	tempo, invalidTempo := "3-1-1-0", "3-1-10-0"
+	testCases := []struct {
+		name     string
+		exercise WorkoutExercise
+		valid    bool
+	}{
+		{"NoRest", WorkoutExercise{Sets: 3}, true},
+		{"SetRests", WorkoutExercise{Sets: 3, Rest: Rest{SetSeconds: []int32{60, 90}, Tempo: &tempo}}, true},
+		{"TooManySetRests", WorkoutExercise{Sets: 2, Rest: Rest{SetSeconds: []int32{60, 90}}}, false},
+		{"InvalidTempo", WorkoutExercise{Sets: 3, Rest: Rest{Tempo: &invalidTempo}}, false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := Workout{Exercises: []WorkoutExercise{tc.exercise}}.ValidateExercises()
+			if tc.valid {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestParseWorkoutETag(t *testing.T) {
+	testCases := []struct {
+		etag    string
+		version int32
+		valid   bool
+	}{
+		{WorkoutETag(3), 3, true},
+		{"7", 7, true},
+		{`W/"12"`, 12, true},
+		{"", 0, false},
+		{`"0"`, 0, false},
+		{`"abc"`, 0, false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.etag, func(t *testing.T) {
+			version, err := ParseWorkoutETag(tc.etag)
+			if tc.valid {
+				require.NoError(t, err)
+				require.Equal(t, tc.version, version)
+			} else {
+				require.Error(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,326 @@
+// Package rrule implements subset of RFC 5545 recurrence rules used for recurring workout schedules.
+//
+// Supported parts are FREQ (DAILY, WEEKLY, MONTHLY), INTERVAL, BYDAY (plain weekdays, no ordinals),
+// BYMONTHDAY (positive days only), COUNT, UNTIL and WKST is assumed to be MO.
+// Occurrences are generated in wall clock time of series start location, so they keep local time across DST changes.
+package rrule
+
+import (
+	"fmt"
+	"iter"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Frequency string
+
+const (
+	Daily   Frequency = "DAILY"
+	Weekly  Frequency = "WEEKLY"
+	Monthly Frequency = "MONTHLY"
+)
+
+const (
+	untilLayout     = "20060102T150405Z"
+	untilDateLayout = "20060102"
+	// guards against rules that never produce occurrence, e.g. BYMONTHDAY=31 with INTERVAL=12 starting in February
+	maxEmptyPeriods = 1000
+)
+
+var weekdays = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+type Rule struct {
+	Freq       Frequency
+	Interval   int
+	ByDay      []time.Weekday
+	ByMonthDay []int
+	// Count is total number of occurrences including the first one, 0 means not limited.
+	Count int
+	// Until is inclusive upper bound for occurrences, zero means not limited.
+	Until time.Time
+}
+
+// Parse parses recurrence rule, "RRULE:" prefix is optional.
+func Parse(value string) (Rule, error) {
+	rule := Rule{Interval: 1}
+	value = strings.TrimPrefix(strings.TrimSpace(value), "RRULE:")
+	if value == "" {
+		return Rule{}, fmt.Errorf("empty recurrence rule")
+	}
+	for _, part := range strings.Split(value, ";") {
+		name, val, ok := strings.Cut(part, "=")
+		if !ok || val == "" {
+			return Rule{}, fmt.Errorf("invalid rule part %q", part)
+		}
+		var err error
+		switch strings.ToUpper(name) {
+		case "FREQ":
+			rule.Freq = Frequency(strings.ToUpper(val))
+			if rule.Freq != Daily && rule.Freq != Weekly && rule.Freq != Monthly {
+				return Rule{}, fmt.Errorf("unsupported frequency %q", val)
+			}
+		case "INTERVAL":
+			rule.Interval, err = strconv.Atoi(val)
+			if err != nil || rule.Interval < 1 {
+				return Rule{}, fmt.Errorf("invalid interval %q", val)
+			}
+		case "COUNT":
+			rule.Count, err = strconv.Atoi(val)
+			if err != nil || rule.Count < 1 {
+				return Rule{}, fmt.Errorf("invalid count %q", val)
+			}
+		case "UNTIL":
+			rule.Until, err = parseUntil(val)
+			if err != nil {
+				return Rule{}, fmt.Errorf("invalid until %q", val)
+			}
+		case "BYDAY":
+			for _, day := range strings.Split(val, ",") {
+				weekday, ok := weekdays[strings.ToUpper(day)]
+				if !ok {
+					return Rule{}, fmt.Errorf("unsupported weekday %q", day)
+				}
+				if !slices.Contains(rule.ByDay, weekday) {
+					rule.ByDay = append(rule.ByDay, weekday)
+				}
+			}
+		case "BYMONTHDAY":
+			for _, day := range strings.Split(val, ",") {
+				monthDay, err := strconv.Atoi(day)
+				if err != nil || monthDay < 1 || monthDay > 31 {
+					return Rule{}, fmt.Errorf("unsupported month day %q", day)
+				}
+				if !slices.Contains(rule.ByMonthDay, monthDay) {
+					rule.ByMonthDay = append(rule.ByMonthDay, monthDay)
+				}
+			}
+		case "WKST":
+			if strings.ToUpper(val) != "MO" {
+				return Rule{}, fmt.Errorf("unsupported week start %q", val)
+			}
+		default:
+			return Rule{}, fmt.Errorf("unsupported rule part %q", name)
+		}
+	}
+	if rule.Freq == "" {
+		return Rule{}, fmt.Errorf("missing frequency")
+	}
+	if rule.Count > 0 && !rule.Until.IsZero() {
+		return Rule{}, fmt.Errorf("count and until are mutually exclusive")
+	}
+	if len(rule.ByMonthDay) > 0 && rule.Freq != Monthly {
+		return Rule{}, fmt.Errorf("month days are supported only for monthly frequency")
+	}
+	if len(rule.ByDay) > 0 && rule.Freq == Monthly {
+		return Rule{}, fmt.Errorf("weekdays are not supported for monthly frequency")
+	}
+	slices.SortFunc(rule.ByDay, func(d1, d2 time.Weekday) int { return mondayIndex(d1) - mondayIndex(d2) })
+	slices.Sort(rule.ByMonthDay)
+	return rule, nil
+}
+
+func parseUntil(value string) (time.Time, error) {
+	if until, err := time.Parse(untilLayout, value); err == nil {
+		return until, nil
+	}
+	//date only form includes whole day
+	until, err := time.Parse(untilDateLayout, value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return until.Add(24*time.Hour - time.Second), nil
+}
+
+// String formats rule back to its RFC 5545 representation (without "RRULE:" prefix).
+func (r Rule) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		var days []string
+		for _, day := range r.ByDay {
+			days = append(days, strings.ToUpper(day.String()[:2]))
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		var days []string
+		for _, day := range r.ByMonthDay {
+			days = append(days, strconv.Itoa(day))
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(days, ","))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format(untilLayout))
+	}
+	return strings.Join(parts, ";")
+}
+
+// All yields occurrences of the rule starting at given time in chronological order. Start is the first occurrence
+// only when it matches the rule. Sequence is infinite when rule has no COUNT or UNTIL.
+func (r Rule) All(start time.Time) iter.Seq[time.Time] {
+	return func(yield func(time.Time) bool) {
+		count := 0
+		emptyPeriods := 0
+		for period := 0; emptyPeriods < maxEmptyPeriods; period++ {
+			candidates := r.periodCandidates(start, period)
+			if len(candidates) == 0 {
+				emptyPeriods++
+				continue
+			}
+			emptyPeriods = 0
+			for _, candidate := range candidates {
+				if candidate.Before(start) {
+					continue
+				}
+				if !r.Until.IsZero() && candidate.After(r.Until) {
+					return
+				}
+				if !yield(candidate) {
+					return
+				}
+				count++
+				if r.Count > 0 && count >= r.Count {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Between returns occurrences within [from, to] range, excluded dates are skipped.
+func (r Rule) Between(start, from, to time.Time, excluded []time.Time) []time.Time {
+	var occurrences []time.Time
+	for occurrence := range r.All(start) {
+		if occurrence.After(to) {
+			break
+		}
+		if occurrence.Before(from) || containsTime(excluded, occurrence) {
+			continue
+		}
+		occurrences = append(occurrences, occurrence)
+	}
+	return occurrences
+}
+
+// IsOccurrence checks whether given time is an occurrence of the rule, excluded dates are not occurrences.
+func (r Rule) IsOccurrence(start, at time.Time, excluded []time.Time) bool {
+	return r.Index(start, at) >= 0 && !containsTime(excluded, at)
+}
+
+// Index returns zero based position of the occurrence within the rule or -1 when given time is not an occurrence.
+func (r Rule) Index(start, at time.Time) int {
+	index := 0
+	for occurrence := range r.All(start) {
+		if occurrence.Equal(at) {
+			return index
+		}
+		if occurrence.After(at) {
+			break
+		}
+		index++
+	}
+	return -1
+}
+
+// periodCandidates returns sorted occurrence candidates of n-th period (day, week or month) since start.
+func (r Rule) periodCandidates(start time.Time, period int) []time.Time {
+	hour, minute, sec := start.Clock()
+	loc := start.Location()
+	interval := max(r.Interval, 1)
+	switch r.Freq {
+	case Daily:
+		day := time.Date(start.Year(), start.Month(), start.Day()+period*interval, hour, minute, sec, 0, loc)
+		if len(r.ByDay) > 0 && !slices.Contains(r.ByDay, day.Weekday()) {
+			return nil
+		}
+		return []time.Time{day}
+	case Weekly:
+		days := r.ByDay
+		if len(days) == 0 {
+			days = []time.Weekday{start.Weekday()}
+		}
+		monday := start.Day() - mondayIndex(start.Weekday()) + period*interval*7
+		var candidates []time.Time
+		for _, day := range days {
+			candidates = append(candidates, time.Date(start.Year(), start.Month(), monday+mondayIndex(day), hour, minute, sec, 0, loc))
+		}
+		return candidates
+	case Monthly:
+		days := r.ByMonthDay
+		if len(days) == 0 {
+			days = []int{start.Day()}
+		}
+		firstOfMonth := time.Date(start.Year(), start.Month()+time.Month(period*interval), 1, hour, minute, sec, 0, loc)
+		var candidates []time.Time
+		for _, day := range days {
+			candidate := firstOfMonth.AddDate(0, 0, day-1)
+			//days not existing in given month are skipped, as required by RFC 5545
+			if candidate.Month() != firstOfMonth.Month() {
+				continue
+			}
+			candidates = append(candidates, time.Date(candidate.Year(), candidate.Month(), candidate.Day(), hour, minute, sec, 0, loc))
+		}
+		return candidates
+	}
+	return nil
+}
+
+func mondayIndex(day time.Weekday) int {
+	return (int(day) + 6) % 7
+}
+
+func containsTime(times []time.Time, t time.Time) bool {
+	return slices.ContainsFunc(times, func(other time.Time) bool { return other.Equal(t) })
+}
+
+// EndingBefore returns copy of the rule which ends right before given time.
+func (r Rule) EndingBefore(at time.Time) Rule {
+	r.Count = 0
+	r.Until = at.Add(-time.Second).UTC()
+	return r
+}
+
+// From returns copy of the rule that continues series from occurrence of given index.
+func (r Rule) From(index int) Rule {
+	if r.Count > 0 {
+		r.Count = max(r.Count-index, 1)
+	}
+	return r
+}
+
+// ShiftDays returns copy of the rule with weekdays and month days moved by given number of days.
+// Month days falling out of 1-31 range are dropped, so rule falls back to start day when none left.
+func (r Rule) ShiftDays(days int) Rule {
+	if days == 0 {
+		return r
+	}
+	var byDay []time.Weekday
+	for _, day := range r.ByDay {
+		byDay = append(byDay, time.Weekday(((int(day)+days)%7+7)%7))
+	}
+	var byMonthDay []int
+	for _, day := range r.ByMonthDay {
+		if shifted := day + days; shifted >= 1 && shifted <= 31 {
+			byMonthDay = append(byMonthDay, shifted)
+		}
+	}
+	slices.SortFunc(byDay, func(d1, d2 time.Weekday) int { return mondayIndex(d1) - mondayIndex(d2) })
+	r.ByDay = byDay
+	r.ByMonthDay = byMonthDay
+	return r
+}
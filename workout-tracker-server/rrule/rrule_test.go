@@ -0,0 +1,169 @@
+package rrule
+
+import (
+	"github.com/stretchr/testify/require"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestParseInvalid(t *testing.T) {
+	testCases := []struct {
+		name string
+		rule string
+	}{
+		{"Empty", ""},
+		{"MissingFrequency", "COUNT=3"},
+		{"UnsupportedFrequency", "FREQ=SECONDLY"},
+		{"InvalidInterval", "FREQ=DAILY;INTERVAL=0"},
+		{"InvalidCount", "FREQ=DAILY;COUNT=x"},
+		{"CountAndUntil", "FREQ=DAILY;COUNT=3;UNTIL=20250101T000000Z"},
+		{"InvalidWeekday", "FREQ=WEEKLY;BYDAY=XX"},
+		{"OrdinalWeekday", "FREQ=WEEKLY;BYDAY=1MO"},
+		{"MonthDayForWeekly", "FREQ=WEEKLY;BYMONTHDAY=1"},
+		{"UnsupportedPart", "FREQ=DAILY;BYHOUR=7"},
+		{"MalformedPart", "FREQ=DAILY;COUNT"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Parse(tc.rule)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestParseFormatRoundTrip(t *testing.T) {
+	rule, err := Parse("RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=TH,MO;UNTIL=20250101T070000Z")
+
+	require.NoError(t, err)
+	require.Equal(t, Weekly, rule.Freq)
+	require.Equal(t, []time.Weekday{time.Monday, time.Thursday}, rule.ByDay)
+	require.Equal(t, "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH;UNTIL=20250101T070000Z", rule.String())
+}
+
+func TestOccurrences(t *testing.T) {
+	//monday
+	start := time.Date(2024, 1, 1, 7, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		name     string
+		rule     string
+		start    time.Time
+		expected []time.Time
+	}{
+		{
+			name:  "DailyWithCount",
+			rule:  "FREQ=DAILY;COUNT=3",
+			start: start,
+			expected: []time.Time{
+				start, start.AddDate(0, 0, 1), start.AddDate(0, 0, 2),
+			},
+		},
+		{
+			name:  "DailyWithIntervalAndWeekdays",
+			rule:  "FREQ=DAILY;INTERVAL=2;BYDAY=MO,WE,FR;COUNT=3",
+			start: start,
+			expected: []time.Time{
+				start, start.AddDate(0, 0, 2), start.AddDate(0, 0, 4),
+			},
+		},
+		{
+			name:  "WeeklyOnMondayAndThursday",
+			rule:  "FREQ=WEEKLY;BYDAY=MO,TH;COUNT=4",
+			start: start,
+			expected: []time.Time{
+				start, start.AddDate(0, 0, 3), start.AddDate(0, 0, 7), start.AddDate(0, 0, 10),
+			},
+		},
+		{
+			name:  "WeeklyStartingMidWeekSkipsEarlierDays",
+			rule:  "FREQ=WEEKLY;BYDAY=MO,TH;COUNT=2",
+			start: start.AddDate(0, 0, 2),
+			expected: []time.Time{
+				start.AddDate(0, 0, 3), start.AddDate(0, 0, 7),
+			},
+		},
+		{
+			name:  "WeeklyWithUntilInclusive",
+			rule:  "FREQ=WEEKLY;INTERVAL=2;UNTIL=20240115T070000Z",
+			start: start,
+			expected: []time.Time{
+				start, start.AddDate(0, 0, 14),
+			},
+		},
+		{
+			name:  "MonthlySkipsMissingDays",
+			rule:  "FREQ=MONTHLY;BYMONTHDAY=31;COUNT=3",
+			start: time.Date(2024, 1, 31, 7, 0, 0, 0, time.UTC),
+			expected: []time.Time{
+				time.Date(2024, 1, 31, 7, 0, 0, 0, time.UTC),
+				time.Date(2024, 3, 31, 7, 0, 0, 0, time.UTC),
+				time.Date(2024, 5, 31, 7, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:     "NeverMatchingRuleEnds",
+			rule:     "FREQ=MONTHLY;INTERVAL=12;BYMONTHDAY=30",
+			start:    time.Date(2024, 2, 1, 7, 0, 0, 0, time.UTC),
+			expected: nil,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rule, err := Parse(tc.rule)
+			require.NoError(t, err)
+
+			require.Equal(t, tc.expected, slices.Collect(rule.All(tc.start)))
+		})
+	}
+}
+
+func TestOccurrencesKeepWallClockAcrossDST(t *testing.T) {
+	//given weekly rule at 7am in Warsaw, DST starts on 31.03.2024
+	warsaw, err := time.LoadLocation("Europe/Warsaw")
+	require.NoError(t, err)
+	rule, err := Parse("FREQ=WEEKLY;COUNT=2")
+	require.NoError(t, err)
+
+	//when
+	occurrences := slices.Collect(rule.All(time.Date(2024, 3, 25, 7, 0, 0, 0, warsaw)))
+
+	//then both at 7am local, but different UTC hour
+	require.Len(t, occurrences, 2)
+	require.Equal(t, 7, occurrences[1].Hour())
+	require.Equal(t, 6, occurrences[0].UTC().Hour())
+	require.Equal(t, 5, occurrences[1].UTC().Hour())
+}
+
+func TestBetweenSkipsExcludedDates(t *testing.T) {
+	start := time.Date(2024, 1, 1, 7, 0, 0, 0, time.UTC)
+	rule, err := Parse("FREQ=DAILY")
+	require.NoError(t, err)
+
+	occurrences := rule.Between(start, start.AddDate(0, 0, 1), start.AddDate(0, 0, 3), []time.Time{start.AddDate(0, 0, 2)})
+
+	require.Equal(t, []time.Time{start.AddDate(0, 0, 1), start.AddDate(0, 0, 3)}, occurrences)
+}
+
+func TestIndex(t *testing.T) {
+	start := time.Date(2024, 1, 1, 7, 0, 0, 0, time.UTC)
+	rule, err := Parse("FREQ=WEEKLY;BYDAY=MO,TH")
+	require.NoError(t, err)
+
+	require.Equal(t, 0, rule.Index(start, start))
+	require.Equal(t, 3, rule.Index(start, start.AddDate(0, 0, 10)))
+	require.Equal(t, -1, rule.Index(start, start.AddDate(0, 0, 1)))
+	require.False(t, rule.IsOccurrence(start, start, []time.Time{start}))
+}
+
+func TestSplitHelpers(t *testing.T) {
+	start := time.Date(2024, 1, 1, 7, 0, 0, 0, time.UTC)
+	rule, err := Parse("FREQ=WEEKLY;BYDAY=MO,TH;COUNT=10")
+	require.NoError(t, err)
+
+	truncated := rule.EndingBefore(start.AddDate(0, 0, 7))
+	require.Equal(t, 2, len(slices.Collect(truncated.All(start))))
+
+	require.Equal(t, 7, rule.From(3).Count)
+	require.Equal(t, []time.Weekday{time.Tuesday, time.Friday}, rule.ShiftDays(1).ByDay)
+	require.Equal(t, []time.Weekday{time.Wednesday, time.Sunday}, rule.ShiftDays(-1).ByDay)
+}
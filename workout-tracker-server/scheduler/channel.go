@@ -0,0 +1,151 @@
+package scheduler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/smtp"
+	"strings"
+	"syscall"
+	"time"
+	"workout-tracker-server/model"
+)
+
+// Channel delivers reminder to the user.
+type Channel interface {
+	Send(ctx context.Context, reminder model.Reminder) error
+}
+
+// LogChannel only logs reminders, it's meant for local setup and tests.
+type LogChannel struct{}
+
+func (LogChannel) Send(_ context.Context, reminder model.Reminder) error {
+	log.Printf("reminder: workout %q of user %s scheduled at %s", reminder.WorkoutName, reminder.OwnerID, reminder.ScheduledAt.Format(time.RFC3339))
+	return nil
+}
+
+// WebhookChannel posts reminder as JSON to the webhook url from user's profile. Only https urls of public addresses
+// are posted to, the address is checked when connecting so DNS changes after the url was saved can't bypass it.
+// Redirects are not followed.
+type WebhookChannel struct {
+	client *http.Client
+}
+
+func NewWebhookChannel(timeout time.Duration) *WebhookChannel {
+	dialer := &net.Dialer{Timeout: timeout, Control: publicAddressControl}
+	return &WebhookChannel{client: &http.Client{
+		Timeout: timeout,
+		//no proxy, connections through it would skip address check
+		Transport: &http.Transport{DialContext: dialer.DialContext, TLSHandshakeTimeout: timeout},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}}
+}
+
+// publicAddressControl rejects connections to addresses which are not public, it runs for each resolved address.
+func publicAddressControl(_, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if !model.IsPublicAddress(addrPort.Addr()) {
+		return fmt.Errorf("webhook address %s is not public", addrPort.Addr())
+	}
+	return nil
+}
+
+type webhookPayload struct {
+	ScheduleID  string    `json:"schedule_id"`
+	WorkoutName string    `json:"workout_name"`
+	ScheduledAt time.Time `json:"scheduled_at"`
+	Timezone    string    `json:"timezone"`
+}
+
+func (w *WebhookChannel) Send(ctx context.Context, reminder model.Reminder) error {
+	if reminder.WebhookURL == nil || *reminder.WebhookURL == "" {
+		return fmt.Errorf("webhook url not set")
+	}
+	webhookURL, err := model.ParseWebhookURL(*reminder.WebhookURL)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(webhookPayload{
+		ScheduleID:  reminder.ScheduleID,
+		WorkoutName: reminder.WorkoutName,
+		ScheduledAt: reminder.ScheduledAt,
+		Timezone:    model.TimezoneName(reminder.Timezone),
+	})
+	if err != nil {
+		return err
+	}
+	rq, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	rq.Header.Set("Content-Type", "application/json")
+	resp, err := w.client.Do(rq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+type SMTPConf struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+// EmailChannel sends reminder to account's email address through SMTP server.
+type EmailChannel struct {
+	conf SMTPConf
+	send func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+}
+
+func NewEmailChannel(conf SMTPConf) *EmailChannel {
+	return &EmailChannel{conf: conf, send: smtp.SendMail}
+}
+
+func (e *EmailChannel) Send(_ context.Context, reminder model.Reminder) error {
+	if reminder.Email == "" {
+		return fmt.Errorf("email address not known")
+	}
+	var auth smtp.Auth
+	if e.conf.Username != "" {
+		auth = smtp.PlainAuth("", e.conf.Username, e.conf.Password, e.conf.Host)
+	}
+	msg, err := reminderEmail(e.conf.From, reminder)
+	if err != nil {
+		return err
+	}
+	return e.send(e.conf.Host+":"+e.conf.Port, auth, e.conf.From, []string{reminder.Email}, msg)
+}
+
+func reminderEmail(from string, reminder model.Reminder) ([]byte, error) {
+	loc, err := model.Location(reminder.Timezone)
+	if err != nil {
+		return nil, err
+	}
+	headers := []string{
+		"From: " + from,
+		"To: " + reminder.Email,
+		"Subject: Upcoming workout: " + strings.NewReplacer("\r", "", "\n", "").Replace(reminder.WorkoutName),
+		"Date: " + time.Now().UTC().Format(time.RFC1123Z),
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=UTF-8",
+	}
+	body := fmt.Sprintf("Your workout %q is scheduled at %s.", reminder.WorkoutName, reminder.ScheduledAt.In(loc).Format("2006-01-02 15:04 MST"))
+	return []byte(strings.Join(headers, "\r\n") + "\r\n\r\n" + body + "\r\n"), nil
+}
@@ -0,0 +1,39 @@
+package scheduler
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"workout-tracker-server/model"
+)
+
+func TestWebhookChannelRejectsLoopbackAddress(t *testing.T) {
+	//given
+	called := false
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		called = true
+	}))
+	defer server.Close()
+	url := server.URL
+
+	//when
+	err := NewWebhookChannel(time.Second).Send(context.Background(), model.Reminder{ScheduleID: "1", WebhookURL: &url})
+
+	//then
+	require.ErrorContains(t, err, "is not public")
+	require.False(t, called)
+}
+
+func TestWebhookChannelRejectsPlainHttp(t *testing.T) {
+	//given
+	url := "http://example.com/reminders"
+
+	//when
+	err := NewWebhookChannel(time.Second).Send(context.Background(), model.Reminder{ScheduleID: "1", WebhookURL: &url})
+
+	//then
+	require.ErrorContains(t, err, "https")
+}
@@ -0,0 +1,41 @@
+package scheduler
+
+import (
+	"context"
+	"log"
+	"time"
+	"workout-tracker-server/db"
+	"workout-tracker-server/model"
+)
+
+// IdempotencyKeyCleanupWorker periodically deletes idempotency keys older than their TTL.
+type IdempotencyKeyCleanupWorker struct {
+	db       db.IdempotencyDb
+	interval time.Duration
+	now      func() time.Time
+}
+
+func NewIdempotencyKeyCleanupWorker(db db.IdempotencyDb, interval time.Duration) *IdempotencyKeyCleanupWorker {
+	return &IdempotencyKeyCleanupWorker{
+		db:       db,
+		interval: interval,
+		now:      func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// Run deletes expired idempotency keys every interval until context is cancelled.
+func (w *IdempotencyKeyCleanupWorker) Run(ctx context.Context) {
+	runPeriodically(ctx, "expired idempotency keys", w.interval, w.RunOnce)
+}
+
+// RunOnce deletes expired idempotency keys, expired keys are already ignored by requests, so cleanup only frees space.
+func (w *IdempotencyKeyCleanupWorker) RunOnce(_ context.Context) error {
+	deleted, err := w.db.DeleteExpiredIdempotencyKeys(w.now().Add(-model.IdempotencyKeyTTL))
+	if err != nil {
+		return err
+	}
+	if deleted > 0 {
+		log.Printf("deleted %d expired idempotency keys", deleted)
+	}
+	return nil
+}
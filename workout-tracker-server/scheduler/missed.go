@@ -0,0 +1,73 @@
+package scheduler
+
+import (
+	"context"
+	"log"
+	"time"
+	"workout-tracker-server/db"
+	"workout-tracker-server/model"
+)
+
+// missedOccurrenceLookback limits how far back occurrences of recurring series are stored to be marked as missed.
+// Older occurrences, e.g. when the job was not running, still get missed status computed on read, but no event.
+const missedOccurrenceLookback = 24 * time.Hour
+
+// MissedScheduleWorker periodically marks workout schedules not completed within grace period as missed
+// and emits missed event for each of them.
+type MissedScheduleWorker struct {
+	db       db.MissedScheduleDb
+	interval time.Duration
+	now      func() time.Time
+}
+
+func NewMissedScheduleWorker(db db.MissedScheduleDb, interval time.Duration) *MissedScheduleWorker {
+	return &MissedScheduleWorker{
+		db:       db,
+		interval: interval,
+		now:      func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// Run marks missed workout schedules every interval until context is cancelled.
+func (w *MissedScheduleWorker) Run(ctx context.Context) {
+	runPeriodically(ctx, "missed workout schedules", w.interval, w.RunOnce)
+}
+
+// RunOnce stores recently overdue occurrences of recurring series and marks all overdue workout schedules as missed.
+// Marking is a single conditional update, so it's safe to run on multiple replicas.
+func (w *MissedScheduleWorker) RunOnce(_ context.Context) error {
+	now := w.now()
+	missedBefore := now.Add(-model.MissedGracePeriod)
+	if err := w.materializeSeriesOccurrences(missedBefore); err != nil {
+		return err
+	}
+	events, err := w.db.MarkWorkoutSchedulesMissed(missedBefore, now)
+	if err != nil {
+		return err
+	}
+	for _, event := range events {
+		log.Printf("event %s: workout schedule %s of user %s %s", event.ID, event.ScheduleID, event.OwnerID, event.Type)
+	}
+	return nil
+}
+
+func (w *MissedScheduleWorker) materializeSeriesOccurrences(missedBefore time.Time) error {
+	seriesList, err := w.db.GetWorkoutScheduleSeriesBefore(missedBefore)
+	if err != nil {
+		return err
+	}
+	for _, series := range seriesList {
+		occurrences, err := series.OccurrencesBetween(missedBefore.Add(-missedOccurrenceLookback), missedBefore)
+		if err != nil {
+			//series with broken timezone must not block occurrences of other series
+			log.Printf("error expanding workout schedule series %s: %v", series.ID, err)
+			continue
+		}
+		for _, occurrence := range occurrences {
+			if _, err := w.db.MaterializeScheduleOccurrence(series, *occurrence.RecurrenceID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+	"workout-tracker-server/mocks"
+	"workout-tracker-server/model"
+	"workout-tracker-server/rrule"
+)
+
+func newTestMissedScheduleWorker(t *testing.T) (*MissedScheduleWorker, *mocks.MissedScheduleDb) {
+	dbMock := mocks.NewMissedScheduleDb(t)
+	worker := NewMissedScheduleWorker(dbMock, time.Minute)
+	worker.now = func() time.Time { return now }
+	return worker, dbMock
+}
+
+func TestMissedRunOnceMarksOverdueSchedules(t *testing.T) {
+	//given daily series at 5:00, now is 6:30, so occurrence from today is beyond grace period
+	worker, dbMock := newTestMissedScheduleWorker(t)
+	missedBefore := now.Add(-model.MissedGracePeriod)
+	series := model.WorkoutScheduleSeries{
+		ID:         "series",
+		StartsAt:   time.Date(2024, 1, 1, 5, 0, 0, 0, time.UTC),
+		Recurrence: rrule.Rule{Freq: rrule.Daily, Interval: 1},
+	}
+	dbMock.EXPECT().GetWorkoutScheduleSeriesBefore(missedBefore).Return([]model.WorkoutScheduleSeries{series}, nil).Once()
+	dbMock.EXPECT().MaterializeScheduleOccurrence(series, time.Date(2024, 1, 15, 5, 0, 0, 0, time.UTC)).Return("1", nil).Once()
+	dbMock.EXPECT().MarkWorkoutSchedulesMissed(missedBefore, now).
+		Return([]model.WorkoutScheduleEvent{{ID: "event", ScheduleID: "1", Type: model.WorkoutScheduleEventMissed}}, nil).Once()
+
+	//when
+	err := worker.RunOnce(context.Background())
+
+	//then
+	require.NoError(t, err)
+}
+
+func TestMissedRunOnceFailsOnDbError(t *testing.T) {
+	//given
+	worker, dbMock := newTestMissedScheduleWorker(t)
+	missedBefore := now.Add(-model.MissedGracePeriod)
+	dbMock.EXPECT().GetWorkoutScheduleSeriesBefore(missedBefore).Return(nil, nil).Once()
+	dbMock.EXPECT().MarkWorkoutSchedulesMissed(missedBefore, now).Return(nil, fmt.Errorf("some err")).Once()
+
+	//when
+	err := worker.RunOnce(context.Background())
+
+	//then
+	require.Error(t, err)
+}
@@ -0,0 +1,44 @@
+package scheduler
+
+import (
+	"context"
+	"log"
+	"time"
+	"workout-tracker-server/db"
+)
+
+// WorkoutPurgeWorker periodically purges workouts which are in trash longer than retention period.
+type WorkoutPurgeWorker struct {
+	db        db.WorkoutPurgeDb
+	interval  time.Duration
+	retention time.Duration
+	now       func() time.Time
+}
+
+func NewWorkoutPurgeWorker(db db.WorkoutPurgeDb, interval, retention time.Duration) *WorkoutPurgeWorker {
+	return &WorkoutPurgeWorker{
+		db:        db,
+		interval:  interval,
+		retention: retention,
+		now:       func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// Run purges deleted workouts every interval until context is cancelled.
+func (w *WorkoutPurgeWorker) Run(ctx context.Context) {
+	runPeriodically(ctx, "deleted workouts", w.interval, w.RunOnce)
+}
+
+// RunOnce purges workouts deleted before retention period. Purge is a single transaction of conditional statements,
+// so it's safe to run on multiple replicas.
+func (w *WorkoutPurgeWorker) RunOnce(_ context.Context) error {
+	now := w.now()
+	purged, err := w.db.PurgeDeletedWorkouts(now.Add(-w.retention), now)
+	if err != nil {
+		return err
+	}
+	if purged > 0 {
+		log.Printf("purged %d deleted workouts", purged)
+	}
+	return nil
+}
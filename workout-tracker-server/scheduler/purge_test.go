@@ -0,0 +1,41 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+	"workout-tracker-server/mocks"
+)
+
+func newTestWorkoutPurgeWorker(t *testing.T) (*WorkoutPurgeWorker, *mocks.WorkoutPurgeDb) {
+	dbMock := mocks.NewWorkoutPurgeDb(t)
+	worker := NewWorkoutPurgeWorker(dbMock, time.Hour, 30*24*time.Hour)
+	worker.now = func() time.Time { return now }
+	return worker, dbMock
+}
+
+func TestPurgeRunOncePurgesWorkoutsDeletedBeforeRetention(t *testing.T) {
+	//given
+	worker, dbMock := newTestWorkoutPurgeWorker(t)
+	dbMock.EXPECT().PurgeDeletedWorkouts(time.Date(2023, 12, 16, 6, 30, 0, 0, time.UTC), now).Return(2, nil).Once()
+
+	//when
+	err := worker.RunOnce(context.Background())
+
+	//then
+	require.NoError(t, err)
+}
+
+func TestPurgeRunOnceFailsOnDbError(t *testing.T) {
+	//given
+	worker, dbMock := newTestWorkoutPurgeWorker(t)
+	dbMock.EXPECT().PurgeDeletedWorkouts(now.Add(-30*24*time.Hour), now).Return(0, fmt.Errorf("some err")).Once()
+
+	//when
+	err := worker.RunOnce(context.Background())
+
+	//then
+	require.Error(t, err)
+}
@@ -0,0 +1,98 @@
+package scheduler
+
+import (
+	"context"
+	"log"
+	"time"
+	"workout-tracker-server/db"
+	"workout-tracker-server/model"
+)
+
+// ReminderWorker periodically sends reminders of workout schedules due within owner's reminder lead time.
+// Every delivery is claimed in database first, so it's safe to run worker on multiple replicas.
+type ReminderWorker struct {
+	db       db.ReminderDb
+	channels map[model.ReminderChannel]Channel
+	interval time.Duration
+	now      func() time.Time
+}
+
+func NewReminderWorker(db db.ReminderDb, channels map[model.ReminderChannel]Channel, interval time.Duration) *ReminderWorker {
+	return &ReminderWorker{
+		db:       db,
+		channels: channels,
+		interval: interval,
+		now:      func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// Run processes reminders every interval until context is cancelled.
+func (w *ReminderWorker) Run(ctx context.Context) {
+	runPeriodically(ctx, "reminders", w.interval, w.RunOnce)
+}
+
+// RunOnce stores upcoming occurrences of recurring series, so their delivery can be tracked, and sends all due
+// reminders. Failure of single delivery doesn't stop processing of others.
+func (w *ReminderWorker) RunOnce(ctx context.Context) error {
+	now := w.now()
+	if err := w.materializeSeriesOccurrences(now); err != nil {
+		return err
+	}
+	reminders, err := w.db.GetDueReminders(now)
+	if err != nil {
+		return err
+	}
+	for _, reminder := range reminders {
+		for _, channelName := range reminder.Channels {
+			w.deliver(ctx, reminder, channelName, now)
+		}
+	}
+	return nil
+}
+
+func (w *ReminderWorker) materializeSeriesOccurrences(now time.Time) error {
+	seriesList, err := w.db.GetReminderSeries(now)
+	if err != nil {
+		return err
+	}
+	for _, rs := range seriesList {
+		occurrences, err := rs.Series.OccurrencesBetween(now, now.Add(rs.LeadTime))
+		if err != nil {
+			//series with broken timezone must not block occurrences of other series
+			log.Printf("error expanding workout schedule series %s: %v", rs.Series.ID, err)
+			continue
+		}
+		for _, occurrence := range occurrences {
+			if _, err := w.db.MaterializeScheduleOccurrence(rs.Series, *occurrence.RecurrenceID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (w *ReminderWorker) deliver(ctx context.Context, reminder model.Reminder, channelName model.ReminderChannel, now time.Time) {
+	channel, ok := w.channels[channelName]
+	if !ok {
+		log.Printf("reminder channel %s not configured, skipping reminder of schedule %s", channelName, reminder.ScheduleID)
+		return
+	}
+	claimed, err := w.db.ClaimReminderDelivery(reminder.ScheduleID, channelName, now)
+	if err != nil {
+		log.Printf("error claiming reminder delivery of schedule %s: %v", reminder.ScheduleID, err)
+		return
+	}
+	if !claimed {
+		return
+	}
+	if err := channel.Send(ctx, reminder); err != nil {
+		log.Printf("error sending %s reminder of schedule %s: %v", channelName, reminder.ScheduleID, err)
+		if err := w.db.MarkReminderFailed(reminder.ScheduleID, channelName, err); err != nil {
+			log.Printf("error marking reminder delivery of schedule %s failed: %v", reminder.ScheduleID, err)
+		}
+		return
+	}
+	if err := w.db.MarkReminderDelivered(reminder.ScheduleID, channelName); err != nil {
+		log.Printf("error marking reminder delivery of schedule %s delivered: %v", reminder.ScheduleID, err)
+	}
+}
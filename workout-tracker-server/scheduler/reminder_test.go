@@ -0,0 +1,97 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+	"workout-tracker-server/mocks"
+	"workout-tracker-server/model"
+	"workout-tracker-server/rrule"
+)
+
+var now = time.Date(2024, 1, 15, 6, 30, 0, 0, time.UTC)
+
+func newTestWorker(t *testing.T, channels map[model.ReminderChannel]Channel) (*ReminderWorker, *mocks.ReminderDb) {
+	dbMock := mocks.NewReminderDb(t)
+	worker := NewReminderWorker(dbMock, channels, time.Minute)
+	worker.now = func() time.Time { return now }
+	return worker, dbMock
+}
+
+func TestRunOnceSendsClaimedReminders(t *testing.T) {
+	//given
+	logChannel := mocks.NewChannel(t)
+	worker, dbMock := newTestWorker(t, map[model.ReminderChannel]Channel{model.ReminderChannelLog: logChannel})
+	reminder := model.Reminder{ScheduleID: "1", Channels: []model.ReminderChannel{model.ReminderChannelLog}}
+	alreadySent := model.Reminder{ScheduleID: "2", Channels: []model.ReminderChannel{model.ReminderChannelLog}}
+
+	dbMock.EXPECT().GetReminderSeries(now).Return(nil, nil).Once()
+	dbMock.EXPECT().GetDueReminders(now).Return([]model.Reminder{reminder, alreadySent}, nil).Once()
+	dbMock.EXPECT().ClaimReminderDelivery("1", model.ReminderChannelLog, now).Return(true, nil).Once()
+	dbMock.EXPECT().ClaimReminderDelivery("2", model.ReminderChannelLog, now).Return(false, nil).Once()
+	logChannel.EXPECT().Send(mock.Anything, reminder).Return(nil).Once()
+	dbMock.EXPECT().MarkReminderDelivered("1", model.ReminderChannelLog).Return(nil).Once()
+
+	//when
+	err := worker.RunOnce(context.Background())
+
+	//then
+	require.NoError(t, err)
+}
+
+func TestRunOnceMarksFailedDelivery(t *testing.T) {
+	//given
+	webhookChannel := mocks.NewChannel(t)
+	worker, dbMock := newTestWorker(t, map[model.ReminderChannel]Channel{model.ReminderChannelWebhook: webhookChannel})
+	//email channel is not configured, so it's skipped without claiming
+	reminder := model.Reminder{ScheduleID: "1", Channels: []model.ReminderChannel{model.ReminderChannelEmail, model.ReminderChannelWebhook}}
+	sendErr := fmt.Errorf("connection refused")
+
+	dbMock.EXPECT().GetReminderSeries(now).Return(nil, nil).Once()
+	dbMock.EXPECT().GetDueReminders(now).Return([]model.Reminder{reminder}, nil).Once()
+	dbMock.EXPECT().ClaimReminderDelivery("1", model.ReminderChannelWebhook, now).Return(true, nil).Once()
+	webhookChannel.EXPECT().Send(mock.Anything, reminder).Return(sendErr).Once()
+	dbMock.EXPECT().MarkReminderFailed("1", model.ReminderChannelWebhook, sendErr).Return(nil).Once()
+
+	//when
+	err := worker.RunOnce(context.Background())
+
+	//then
+	require.NoError(t, err)
+}
+
+func TestRunOnceMaterializesUpcomingSeriesOccurrences(t *testing.T) {
+	//given daily series at 7:00 and 1 hour lead time
+	worker, dbMock := newTestWorker(t, nil)
+	series := model.WorkoutScheduleSeries{
+		ID:         "series",
+		StartsAt:   time.Date(2024, 1, 1, 7, 0, 0, 0, time.UTC),
+		Recurrence: rrule.Rule{Freq: rrule.Daily, Interval: 1},
+	}
+
+	dbMock.EXPECT().GetReminderSeries(now).Return([]model.ReminderSeries{{Series: series, LeadTime: time.Hour}}, nil).Once()
+	dbMock.EXPECT().MaterializeScheduleOccurrence(series, time.Date(2024, 1, 15, 7, 0, 0, 0, time.UTC)).Return("1", nil).Once()
+	dbMock.EXPECT().GetDueReminders(now).Return(nil, nil).Once()
+
+	//when
+	err := worker.RunOnce(context.Background())
+
+	//then
+	require.NoError(t, err)
+}
+
+func TestRunOnceFailsOnDbError(t *testing.T) {
+	//given
+	worker, dbMock := newTestWorker(t, nil)
+	dbMock.EXPECT().GetReminderSeries(now).Return(nil, nil).Once()
+	dbMock.EXPECT().GetDueReminders(now).Return(nil, fmt.Errorf("some err")).Once()
+
+	//when
+	err := worker.RunOnce(context.Background())
+
+	//then
+	require.Error(t, err)
+}